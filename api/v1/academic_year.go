@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type GetAcademicYearReq struct {
+	g.Meta `path:"/academic-year" method:"get" tags:"Academic Years" summary:"Get the current user's academic year"`
+}
+
+type GetAcademicYearRes struct {
+	AcademicYear string `json:"academicYear" dc:"Empty if none was chosen"`
+}
+
+type SetAcademicYearReq struct {
+	g.Meta       `path:"/academic-year" method:"post" tags:"Academic Years" summary:"Choose the current user's academic year"`
+	AcademicYear string `json:"academicYear" v:"required#Academic year is required"`
+}
+
+type SetAcademicYearRes struct {
+	Message string `json:"message"`
+}
+
+type GetAcademicYearsReq struct {
+	g.Meta `path:"/academic-years" method:"get" tags:"Academic Years" summary:"List the academic years that have classrooms"`
+}
+
+type GetAcademicYearsRes struct {
+	AcademicYears []string `json:"academicYears"`
+}
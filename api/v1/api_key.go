@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/model"
+	"tzlev/internal/service"
+)
+
+type GetMyAPIKeysReq struct {
+	g.Meta `path:"/me/api-keys" method:"get" tags:"API Keys" summary:"List the current user's API keys"`
+}
+
+type GetMyAPIKeysRes struct {
+	APIKeys []service.APIKeyInfo `json:"api_keys"`
+}
+
+type CreateMyAPIKeyReq struct {
+	g.Meta    `path:"/me/api-keys" method:"post" tags:"API Keys" summary:"Create an API key for the current user" dc:"Not available to API keys. Scopes cannot exceed the user's own permissions."`
+	Name      string              `json:"name" v:"required#Name is required"`
+	ExpiresAt *time.Time          `json:"expires_at" dc:"Defaults to the configured lifetime"`
+	Scopes    []model.APIKeyScope `json:"scopes"`
+}
+
+type CreateMyAPIKeyRes = CreateAPIKeyRes
+
+type RevokeMyAPIKeyReq struct {
+	g.Meta `path:"/me/api-keys/{id}" method:"delete" tags:"API Keys" summary:"Revoke one of the current user's API keys"`
+	Id     int64 `json:"id" in:"path" v:"required#API key not found"`
+}
+
+type RevokeMyAPIKeyRes = RevokeAPIKeyRes
+
+type GetAPIKeysReq struct {
+	g.Meta `path:"/api-keys" method:"get" tags:"API Keys" summary:"List every API key, including service account keys"`
+}
+
+type GetAPIKeysRes struct {
+	APIKeys []service.APIKeyInfo `json:"api_keys"`
+}
+
+type CreateAPIKeyReq struct {
+	g.Meta         `path:"/api-keys" method:"post" tags:"API Keys" summary:"Create an API key for a user or a service account" dc:"Set exactly one of zehut and service_account."`
+	Name           string              `json:"name" v:"required#Name is required"`
+	Zehut          string              `json:"zehut"`
+	ServiceAccount string              `json:"service_account"`
+	ExpiresAt      *time.Time          `json:"expires_at" dc:"Defaults to the configured lifetime"`
+	Scopes         []model.APIKeyScope `json:"scopes"`
+}
+
+type CreateAPIKeyRes struct {
+	Message string              `json:"message"`
+	Key     string              `json:"key" dc:"The plaintext key; it is returned only once"`
+	APIKey  *service.APIKeyInfo `json:"api_key"`
+}
+
+type RevokeAPIKeyReq struct {
+	g.Meta `path:"/api-keys/{id}" method:"delete" tags:"API Keys" summary:"Revoke any API key"`
+	Id     int64 `json:"id" in:"path" v:"required#API key not found"`
+}
+
+type RevokeAPIKeyRes struct {
+	Message string `json:"message"`
+}
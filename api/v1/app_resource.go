@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/model"
+)
+
+type GetAppResourcesReq struct {
+	g.Meta `path:"/app-resources" method:"get" tags:"App Resources" summary:"List app resources"`
+}
+
+type GetAppResourcesRes struct {
+	Resources []model.AppResource `json:"resources"`
+}
+
+type GetAppResourceReq struct {
+	g.Meta `path:"/app-resources/{id}" method:"get" tags:"App Resources" summary:"Get an app resource"`
+	Id     string `json:"id" in:"path" v:"required#Resource ID is required"`
+}
+
+type GetAppResourceRes struct {
+	Resource *model.AppResource `json:"resource"`
+}
+
+type CreateAppResourceReq struct {
+	g.Meta      `path:"/app-resources" method:"post" tags:"App Resources" summary:"Create an app resource"`
+	Id          string `json:"id" dc:"Generated when empty"`
+	Name        string `json:"name" v:"required#Name is required" dc:"Used in permission checks; must be unique"`
+	Description string `json:"description"`
+}
+
+type CreateAppResourceRes struct {
+	Message  string             `json:"message"`
+	Resource *model.AppResource `json:"resource"`
+}
+
+type UpdateAppResourceReq struct {
+	g.Meta      `path:"/app-resources/{id}" method:"put" tags:"App Resources" summary:"Update an app resource"`
+	Id          string `json:"id" in:"path" v:"required#Resource ID is required"`
+	Name        string `json:"name" v:"required#Name is required"`
+	Description string `json:"description"`
+}
+
+type UpdateAppResourceRes struct {
+	Message  string             `json:"message"`
+	Resource *model.AppResource `json:"resource"`
+}
+
+type DeleteAppResourceReq struct {
+	g.Meta `path:"/app-resources/{id}" method:"delete" tags:"App Resources" summary:"Delete an app resource and the permissions on it"`
+	Id     string `json:"id" in:"path" v:"required#Resource ID is required"`
+}
+
+type DeleteAppResourceRes struct {
+	Message string `json:"message"`
+}
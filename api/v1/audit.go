@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/model"
+)
+
+// AuditLogFilter narrows the audit entries that are listed or exported
+type AuditLogFilter struct {
+	Actor        string `json:"actor" in:"query" dc:"Zehut, api_key:<id>, anonymous or system"`
+	Impersonator string `json:"impersonator" in:"query" dc:"Zehut of the staff member who impersonated the actor"`
+	Action       string `json:"action" in:"query" dc:"Exact action, or a prefix ending in *, e.g. login.*"`
+	Entity       string `json:"entity" in:"query"`
+	EntityID     string `json:"entity_id" in:"query"`
+	IP           string `json:"ip" in:"query"`
+	RequestID    string `json:"request_id" in:"query"`
+	From         string `json:"from" in:"query" dc:"Date or RFC 3339 time"`
+	To           string `json:"to" in:"query" dc:"Date or RFC 3339 time; a date includes the whole day"`
+}
+
+type GetAuditLogReq struct {
+	g.Meta `path:"/audit" method:"get" tags:"Audit" summary:"List audit entries, newest first"`
+	AuditLogFilter
+	Offset int `json:"offset" in:"query" d:"0" v:"min:0"`
+	Limit  int `json:"limit" in:"query" d:"50" dc:"At most 200"`
+}
+
+type GetAuditLogRes struct {
+	Entries []model.AuditLogEntry `json:"entries"`
+	Total   int                   `json:"total"`
+	Offset  int                   `json:"offset"`
+	Limit   int                   `json:"limit"`
+}
+
+type ExportAuditLogReq struct {
+	g.Meta `path:"/audit/export" method:"get" tags:"Audit" summary:"Download audit entries as CSV" dc:"At most 100,000 entries can be exported at once. Exports are audited themselves."`
+	AuditLogFilter
+}
+
+type ExportAuditLogRes struct {
+	g.Meta `mime:"text/csv"`
+}
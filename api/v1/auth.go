@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/auth"
+)
+
+type LoginReq struct {
+	g.Meta   `path:"/login" method:"post" tags:"Auth" summary:"Log in with zehut and password" dc:"Starts a browser session. Users with two-factor authentication get status mfa_required and finish the login with /auth/2fa/verify."`
+	Zehut    string `json:"zehut" v:"required#Zehut is required" dc:"Israeli ID number"`
+	Password string `json:"password" v:"required#Password is required"`
+}
+
+type LoginRes struct {
+	Status  string `json:"status" dc:"ok, or mfa_required when the login waits for a second factor"`
+	Message string `json:"message"`
+}
+
+type VerifyTwoFactorReq struct {
+	g.Meta `path:"/2fa/verify" method:"post" tags:"Auth" summary:"Complete a login with a second factor"`
+	Code   string `json:"code" v:"required#Code is required" dc:"TOTP code or recovery code"`
+}
+
+type VerifyTwoFactorRes struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type LogoutReq struct {
+	g.Meta `path:"/logout" method:"post" tags:"Auth" summary:"End the browser session"`
+}
+
+type LogoutRes struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type GetProvidersReq struct {
+	g.Meta `path:"/providers" method:"get" tags:"Auth" summary:"List the external login providers"`
+}
+
+type GetProvidersRes struct {
+	Providers []LoginProvider `json:"providers"`
+}
+
+// LoginProvider is an OpenID Connect provider shown on the login page
+type LoginProvider struct {
+	Name        string `json:"name" dc:"Used in /auth/{provider}/login"`
+	DisplayName string `json:"display_name"`
+}
+
+type GetCurrentUserReq struct {
+	g.Meta `path:"/me" method:"get" tags:"Account" summary:"Get the current user"`
+}
+
+type GetCurrentUserRes struct {
+	User                  CurrentUser        `json:"user"`
+	MFAEnrollmentRequired bool               `json:"mfa_enrollment_required" dc:"The user must set up two-factor authentication before using the app"`
+	CanImpersonate        bool               `json:"can_impersonate"`
+	Impersonator          *auth.Impersonator `json:"impersonator" dc:"Set while support staff impersonate the user"`
+}
+
+// CurrentUser is the profile of the logged-in user
+type CurrentUser struct {
+	Zehut     string `json:"zehut"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Avatar    string `json:"avatar"`
+	Role      string `json:"role"`
+	IsAdmin   bool   `json:"is_admin"`
+}
+
+type IssueTokenReq struct {
+	g.Meta       `path:"/token" method:"post" tags:"Auth" summary:"Issue bearer tokens" dc:"The password grant logs in with zehut and password. Users with two-factor authentication get status mfa_required and an mfa_token, which the mfa grant exchanges for tokens together with a code. The refresh_token grant exchanges a refresh token for a new pair."`
+	GrantType    string `json:"grant_type" v:"required#Grant type is required" dc:"password, mfa or refresh_token"`
+	Zehut        string `json:"zehut" v:"required-if:GrantType,password#Zehut is required" dc:"password grant"`
+	Password     string `json:"password" v:"required-if:GrantType,password#Password is required" dc:"password grant"`
+	MFAToken     string `json:"mfa_token" v:"required-if:GrantType,mfa#MFA token is required" dc:"mfa grant"`
+	Code         string `json:"code" v:"required-if:GrantType,mfa#Code is required" dc:"mfa grant: TOTP code or recovery code"`
+	RefreshToken string `json:"refresh_token" v:"required-if:GrantType,refresh_token#Refresh token is required" dc:"refresh_token grant"`
+}
+
+type IssueTokenRes struct {
+	Status       string `json:"status,omitempty" dc:"mfa_required when the login waits for a second factor"`
+	MFAToken     string `json:"mfa_token,omitempty" dc:"Set with status mfa_required"`
+	AccessToken  string `json:"access_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty" dc:"Seconds until the access token expires"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type RevokeTokenReq struct {
+	g.Meta       `path:"/token/revoke" method:"post" tags:"Auth" summary:"Revoke a refresh token" dc:"Also revokes every token issued from the same login. Unknown tokens count as already revoked."`
+	RefreshToken string `json:"refresh_token" v:"required#Refresh token is required"`
+}
+
+type RevokeTokenRes struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/model"
+)
+
+type GetClassroomsReq struct {
+	g.Meta       `path:"/classrooms" method:"get" tags:"Classrooms" summary:"List classrooms" dc:"The filters can be combined."`
+	AcademicYear string `json:"academic_year" in:"query"`
+	SchoolID     *int64 `json:"school_id" in:"query" v:"integer#Invalid school_id"`
+	TeacherID    *int64 `json:"teacher_id" in:"query" v:"integer#Invalid teacher_id"`
+	Offset       int    `json:"offset" in:"query" d:"0"`
+	Limit        int    `json:"limit" in:"query" d:"50" dc:"At most 500"`
+}
+
+type GetClassroomsRes struct {
+	Classrooms []model.Classroom `json:"classrooms"`
+}
+
+type GetClassroomReq struct {
+	g.Meta `path:"/classrooms/{id}" method:"get" tags:"Classrooms" summary:"Get a classroom"`
+	Id     int64 `json:"id" in:"path" v:"required|min:1#Classroom ID is required|Invalid classroom ID"`
+}
+
+type GetClassroomRes struct {
+	Classroom *model.Classroom `json:"classroom"`
+}
+
+// ClassroomFields are the writable fields of a classroom
+type ClassroomFields struct {
+	Code           string           `json:"code"`
+	ClassroomName  string           `json:"classroom_name"`
+	SchoolID       int64            `json:"school_id"`
+	TeacherID      int64            `json:"teacher_id" dc:"Users who are not admins may only write their own classrooms"`
+	AcademicYear   string           `json:"academic_year"`
+	ClassroomType  model.ClassType  `json:"classroom_type" v:"in:classroom,group#Invalid classroom type"`
+	ClassroomSemel string           `json:"classroom_semel"`
+	SymbolType     model.SymbolType `json:"symbol_type" v:"in:classroom#Invalid symbol type"`
+	OrderID        int              `json:"order_id"`
+	StartFrom      []string         `json:"start_from"`
+	EndTo          []string         `json:"end_to"`
+	ManualStart    []bool           `json:"manual_start"`
+}
+
+type CreateClassroomReq struct {
+	g.Meta `path:"/classrooms" method:"post" tags:"Classrooms" summary:"Create a classroom"`
+	ClassroomFields
+}
+
+type CreateClassroomRes struct {
+	Message   string           `json:"message"`
+	Classroom *model.Classroom `json:"classroom"`
+}
+
+type UpdateClassroomReq struct {
+	g.Meta `path:"/classrooms/{id}" method:"put" tags:"Classrooms" summary:"Update a classroom" dc:"Reassigning it to another teacher requires the right to write their classrooms too."`
+	Id     int64 `json:"id" in:"path" v:"required|min:1#Classroom ID is required|Invalid classroom ID"`
+	ClassroomFields
+}
+
+type UpdateClassroomRes struct {
+	Message   string           `json:"message"`
+	Classroom *model.Classroom `json:"classroom"`
+}
+
+type DeleteClassroomReq struct {
+	g.Meta `path:"/classrooms/{id}" method:"delete" tags:"Classrooms" summary:"Delete a classroom"`
+	Id     int64 `json:"id" in:"path" v:"required|min:1#Classroom ID is required|Invalid classroom ID"`
+}
+
+type DeleteClassroomRes struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/model"
+)
+
+type GetGroupsReq struct {
+	g.Meta `path:"/groups" method:"get" tags:"Groups" summary:"List groups"`
+}
+
+type GetGroupsRes struct {
+	Groups []model.Group `json:"groups"`
+}
+
+type GetGroupReq struct {
+	g.Meta `path:"/groups/{id}" method:"get" tags:"Groups" summary:"Get a group"`
+	Id     int64 `json:"id" in:"path" v:"required|min:1#Invalid group ID|Invalid group ID"`
+}
+
+type GetGroupRes struct {
+	Group *model.Group `json:"group"`
+}
+
+type CreateGroupReq struct {
+	g.Meta      `path:"/groups" method:"post" tags:"Groups" summary:"Create a group"`
+	Name        string `json:"name" v:"required#Group name is required"`
+	Description string `json:"description"`
+}
+
+type CreateGroupRes struct {
+	Message string       `json:"message"`
+	Group   *model.Group `json:"group"`
+}
+
+type UpdateGroupReq struct {
+	g.Meta      `path:"/groups/{id}" method:"put" tags:"Groups" summary:"Update a group's name and description"`
+	Id          int64  `json:"id" in:"path" v:"required|min:1#Invalid group ID|Invalid group ID"`
+	Name        string `json:"name" v:"required#Group name is required"`
+	Description string `json:"description"`
+}
+
+type UpdateGroupRes struct {
+	Message string       `json:"message"`
+	Group   *model.Group `json:"group"`
+}
+
+type DeleteGroupReq struct {
+	g.Meta `path:"/groups/{id}" method:"delete" tags:"Groups" summary:"Delete a group with its members and permissions"`
+	Id     int64 `json:"id" in:"path" v:"required|min:1#Invalid group ID|Invalid group ID"`
+}
+
+type DeleteGroupRes struct {
+	Message string `json:"message"`
+}
+
+type GetGroupMembersReq struct {
+	g.Meta `path:"/groups/{id}/members" method:"get" tags:"Groups" summary:"List the users in a group"`
+	Id     int64 `json:"id" in:"path" v:"required|min:1#Invalid group ID|Invalid group ID"`
+}
+
+type GetGroupMembersRes struct {
+	Members []model.GroupMemberUser `json:"members"`
+}
+
+type AddGroupMemberReq struct {
+	g.Meta `path:"/groups/{id}/members" method:"post" tags:"Groups" summary:"Add a user to a group"`
+	Id     int64  `json:"id" in:"path" v:"required|min:1#Invalid group ID|Invalid group ID"`
+	Zehut  string `json:"zehut" v:"required#Zehut is required"`
+}
+
+type AddGroupMemberRes struct {
+	Message string `json:"message"`
+}
+
+type RemoveGroupMemberReq struct {
+	g.Meta `path:"/groups/{id}/members/{zehut}" method:"delete" tags:"Groups" summary:"Remove a user from a group"`
+	Id     int64  `json:"id" in:"path" v:"required|min:1#Invalid group ID|Invalid group ID"`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type RemoveGroupMemberRes struct {
+	Message string `json:"message"`
+}
+
+type GetGroupPermissionsReq struct {
+	g.Meta `path:"/groups/{id}/permissions" method:"get" tags:"Groups" summary:"List a group's permissions per resource"`
+	Id     int64 `json:"id" in:"path" v:"required|min:1#Invalid group ID|Invalid group ID"`
+}
+
+type GetGroupPermissionsRes struct {
+	Permissions []model.GroupPermission `json:"permissions"`
+}
+
+type SetGroupPermissionsReq struct {
+	g.Meta      `path:"/groups/{id}/permissions" method:"put" tags:"Groups" summary:"Replace a group's permissions"`
+	Id          int64                   `json:"id" in:"path" v:"required|min:1#Invalid group ID|Invalid group ID"`
+	Permissions []model.GroupPermission `json:"permissions"`
+}
+
+type SetGroupPermissionsRes struct {
+	Message string `json:"message"`
+}
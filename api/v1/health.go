@@ -0,0 +1,14 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type HealthCheckReq struct {
+	g.Meta `path:"/health" method:"get" tags:"Health" summary:"Check that the server is running"`
+}
+
+type HealthCheckRes struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
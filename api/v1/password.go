@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type ForgotPasswordReq struct {
+	g.Meta `path:"/password/forgot" method:"post" tags:"Auth" summary:"Email a password reset link" dc:"Give a zehut or an email address. The response is the same whether or not the account exists."`
+	Zehut  string `json:"zehut"`
+	Email  string `json:"email"`
+}
+
+type ForgotPasswordRes struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type ResetPasswordReq struct {
+	g.Meta   `path:"/password/reset" method:"post" tags:"Auth" summary:"Set a new password with a reset token" dc:"Passwords that break the password policy are refused with a list of violations."`
+	Token    string `json:"token" v:"required#The reset link is invalid or has expired" dc:"Token from the reset email"`
+	Password string `json:"password" v:"required#Password is required"`
+}
+
+type ResetPasswordRes struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
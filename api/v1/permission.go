@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/model"
+)
+
+type GetUserPermissionsReq struct {
+	g.Meta `path:"/users/{zehut}/permissions" method:"get" tags:"Permissions" summary:"List the permissions granted directly to a user"`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type GetUserPermissionsRes struct {
+	Permissions []model.UserPermission `json:"permissions"`
+}
+
+type SetUserPermissionsReq struct {
+	g.Meta      `path:"/users/{zehut}/permissions" method:"put" tags:"Permissions" summary:"Replace the permissions granted directly to a user"`
+	Zehut       string                 `json:"zehut" in:"path" v:"required#Zehut is required"`
+	Permissions []model.UserPermission `json:"permissions"`
+}
+
+type SetUserPermissionsRes struct {
+	Message string `json:"message"`
+}
+
+type GetMyPermissionsReq struct {
+	g.Meta `path:"/me/permissions" method:"get" tags:"Account" summary:"Get the current user's effective permissions"`
+}
+
+type GetMyPermissionsRes struct {
+	Permissions map[string]model.PermissionFlags `json:"permissions" dc:"Keyed by resource name"`
+}
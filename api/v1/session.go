@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/service"
+)
+
+type GetMySessionsReq struct {
+	g.Meta `path:"/me/sessions" method:"get" tags:"Account" summary:"List the current user's active sessions"`
+}
+
+type GetMySessionsRes struct {
+	Sessions []service.SessionInfo `json:"sessions"`
+}
+
+type RevokeMySessionReq struct {
+	g.Meta `path:"/me/sessions/{id}" method:"delete" tags:"Account" summary:"End one of the current user's sessions"`
+	Id     string `json:"id" in:"path" v:"required#Session not found"`
+}
+
+type RevokeMySessionRes struct {
+	Message string `json:"message"`
+}
+
+type RevokeMyOtherSessionsReq struct {
+	g.Meta `path:"/me/sessions" method:"delete" tags:"Account" summary:"End every session of the current user except this one"`
+}
+
+type RevokeMyOtherSessionsRes struct {
+	Message string `json:"message"`
+	Revoked int    `json:"revoked"`
+}
+
+type GetUserSessionsReq struct {
+	g.Meta `path:"/users/{zehut}/sessions" method:"get" tags:"Users" summary:"List a user's active sessions"`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type GetUserSessionsRes struct {
+	Sessions []service.SessionInfo `json:"sessions"`
+}
+
+type ForceLogoutReq struct {
+	g.Meta `path:"/users/{zehut}/sessions" method:"delete" tags:"Users" summary:"Log a user out everywhere" dc:"Ends the user's sessions and revokes their refresh tokens."`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type ForceLogoutRes struct {
+	Message string `json:"message"`
+}
+
+type StartImpersonationReq struct {
+	g.Meta `path:"/users/{zehut}/impersonate" method:"post" tags:"Impersonation" summary:"Act as another user" dc:"Admins and technical staff switch their browser session to the user. Admins and frozen users cannot be impersonated."`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type StartImpersonationRes struct {
+	Message string `json:"message"`
+}
+
+type StopImpersonationReq struct {
+	g.Meta `path:"/me/impersonation" method:"delete" tags:"Impersonation" summary:"Return to your own account"`
+}
+
+type StopImpersonationRes struct {
+	Message string `json:"message"`
+}
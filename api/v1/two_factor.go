@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/service"
+)
+
+type GetMyTwoFactorReq struct {
+	g.Meta `path:"/me/2fa" method:"get" tags:"Two-Factor" summary:"Get the current user's two-factor status"`
+}
+
+type GetMyTwoFactorRes struct {
+	TwoFactor *service.TwoFactorStatus `json:"two_factor"`
+}
+
+type EnrollMyTwoFactorReq struct {
+	g.Meta `path:"/me/2fa/enroll" method:"post" tags:"Two-Factor" summary:"Start setting up an authenticator app" dc:"Returns the TOTP secret and provisioning URI. Enrollment completes with /api/me/2fa/confirm."`
+}
+
+type EnrollMyTwoFactorRes struct {
+	Enrollment *service.TOTPEnrollment `json:"enrollment"`
+}
+
+type ConfirmMyTwoFactorReq struct {
+	g.Meta `path:"/me/2fa/confirm" method:"post" tags:"Two-Factor" summary:"Enable two-factor authentication with a first code"`
+	Code   string `json:"code" v:"required#Code is required" dc:"TOTP code from the authenticator app"`
+}
+
+type ConfirmMyTwoFactorRes struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes" dc:"Shown only this once"`
+}
+
+type DisableMyTwoFactorReq struct {
+	g.Meta `path:"/me/2fa" method:"delete" tags:"Two-Factor" summary:"Disable two-factor authentication" dc:"Not allowed for users who must use two-factor authentication."`
+	Code   string `json:"code" v:"required#Code is required" dc:"TOTP code or recovery code"`
+}
+
+type DisableMyTwoFactorRes struct {
+	Message string `json:"message"`
+}
+
+type RegenerateMyRecoveryCodesReq struct {
+	g.Meta `path:"/me/2fa/recovery-codes" method:"post" tags:"Two-Factor" summary:"Replace the current user's recovery codes"`
+	Code   string `json:"code" v:"required#Code is required" dc:"TOTP code or recovery code"`
+}
+
+type RegenerateMyRecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recovery_codes" dc:"Shown only this once"`
+}
+
+type ResetUserTwoFactorReq struct {
+	g.Meta `path:"/users/{zehut}/2fa" method:"delete" tags:"Users" summary:"Remove a user's two-factor setup" dc:"For users who lost both their authenticator and their recovery codes. Only admins can reset admins and users the 2FA policy applies to. Your own 2FA cannot be reset here."`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type ResetUserTwoFactorRes struct {
+	Message string `json:"message"`
+}
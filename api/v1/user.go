@@ -0,0 +1,126 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/model"
+)
+
+type GetUsersReq struct {
+	g.Meta `path:"/users" method:"get" tags:"Users" summary:"List users"`
+	Search string `json:"search" in:"query" dc:"Matches zehut, name or email"`
+	Offset int    `json:"offset" in:"query" d:"0" v:"min:0"`
+	Limit  int    `json:"limit" in:"query" d:"50" dc:"At most 200"`
+}
+
+type GetUsersRes struct {
+	Users  []model.User `json:"users"`
+	Total  int          `json:"total"`
+	Offset int          `json:"offset"`
+	Limit  int          `json:"limit"`
+}
+
+type GetUserReq struct {
+	g.Meta `path:"/users/{zehut}" method:"get" tags:"Users" summary:"Get a user"`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type GetUserRes struct {
+	User *model.User `json:"user"`
+}
+
+// UserProfile holds the editable profile and payroll fields of a user.
+// Fields left out of the request body are not changed.
+type UserProfile struct {
+	FirstName         *string `json:"first_name"`
+	LastName          *string `json:"last_name"`
+	Email             *string `json:"email" v:"email#Invalid email address"`
+	Mobile            *string `json:"mobile"`
+	Phone             *string `json:"phone"`
+	Address           *string `json:"address"`
+	City              *string `json:"city"`
+	Zipcode           *string `json:"zipcode"`
+	Role              *string `json:"role"`
+	RoleDescription   *string `json:"role_description"`
+	Remarks           *string `json:"remarks"`
+	ManagerId         *string `json:"manager_id"`
+	UnitCode          *string `json:"unit_code"`
+	PaymentPerHour    *int    `json:"payment_per_hour" v:"min:0"`
+	Allowance         *int    `json:"allowance" v:"min:0"`
+	MonthlyTicket     *int    `json:"monthly_ticket" v:"min:0"`
+	HoursCount        *bool   `json:"hours_count"`
+	DailyAllowance    *bool   `json:"daily_allowance"`
+	MonthlyAllowance  *bool   `json:"monthly_allowance"`
+	TravelConsiderate *bool   `json:"travel_considerate"`
+}
+
+type CreateUserReq struct {
+	g.Meta   `path:"/users" method:"post" tags:"Users" summary:"Create a user" dc:"Only admins can create admin users. The password must satisfy the password policy."`
+	Zehut    string `json:"zehut" v:"required#Zehut, first name and last name are required"`
+	Password string `json:"password"`
+	IsAdmin  bool   `json:"is_admin"`
+	UserProfile
+}
+
+type CreateUserRes struct {
+	Message string      `json:"message"`
+	User    *model.User `json:"user"`
+}
+
+type UpdateUserReq struct {
+	g.Meta `path:"/users/{zehut}" method:"put" tags:"Users" summary:"Update a user's profile and payroll fields" dc:"Only admins can change admin accounts."`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+	UserProfile
+}
+
+type UpdateUserRes struct {
+	Message string      `json:"message"`
+	User    *model.User `json:"user"`
+}
+
+type SetFreezedReq struct {
+	g.Meta    `path:"/users/{zehut}/freeze" method:"put" tags:"Users" summary:"Freeze or unfreeze a user account" dc:"Only admins can freeze admins."`
+	Zehut     string `json:"zehut" in:"path" v:"required#Zehut is required"`
+	IsFreezed bool   `json:"is_freezed"`
+}
+
+type SetFreezedRes struct {
+	Message string `json:"message"`
+}
+
+type SetAdminReq struct {
+	g.Meta  `path:"/users/{zehut}/admin" method:"put" tags:"Users" summary:"Grant or revoke admin rights" dc:"Only admins may call it."`
+	Zehut   string `json:"zehut" in:"path" v:"required#Zehut is required"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+type SetAdminRes struct {
+	Message string `json:"message"`
+}
+
+type GetLoginLockoutReq struct {
+	g.Meta `path:"/users/{zehut}/lockout" method:"get" tags:"Users" summary:"Get recent failed logins and any lockout of a user"`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type GetLoginLockoutRes struct {
+	FailedAttempts int  `json:"failed_attempts"`
+	Locked         bool `json:"locked"`
+	RetryAfter     int  `json:"retry_after" dc:"Seconds until the lockout ends"`
+}
+
+type ClearLoginLockoutReq struct {
+	g.Meta `path:"/users/{zehut}/lockout" method:"delete" tags:"Users" summary:"Lift a user's login lockout"`
+	Zehut  string `json:"zehut" in:"path" v:"required#Invalid request format"`
+}
+
+type ClearLoginLockoutRes struct {
+	Message string `json:"message"`
+}
+
+type ClearIPLockoutReq struct {
+	g.Meta `path:"/login-lockouts/{ip}" method:"delete" tags:"Users" summary:"Lift the login lockout of a client IP"`
+	IP     string `json:"ip" in:"path" v:"required|ip#Invalid request format|Invalid IP address"`
+}
+
+type ClearIPLockoutRes = ClearLoginLockoutRes
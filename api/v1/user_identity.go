@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/model"
+)
+
+type GetMyIdentitiesReq struct {
+	g.Meta `path:"/me/identities" method:"get" tags:"Account" summary:"List the external accounts linked to the current user"`
+}
+
+type GetMyIdentitiesRes struct {
+	Identities []model.UserIdentity `json:"identities"`
+}
+
+type UnlinkMyIdentityReq struct {
+	g.Meta `path:"/me/identities/{id}" method:"delete" tags:"Account" summary:"Unlink one of the current user's external accounts"`
+	Id     int64 `json:"id" in:"path" v:"required#Linked account not found"`
+}
+
+type UnlinkMyIdentityRes struct {
+	Message string `json:"message"`
+}
+
+type GetUserIdentitiesReq struct {
+	g.Meta `path:"/users/{zehut}/identities" method:"get" tags:"Users" summary:"List the external accounts linked to a user"`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+}
+
+type GetUserIdentitiesRes struct {
+	Identities []model.UserIdentity `json:"identities"`
+}
+
+type UnlinkUserIdentityReq struct {
+	g.Meta `path:"/users/{zehut}/identities/{id}" method:"delete" tags:"Users" summary:"Unlink an external account from a user"`
+	Zehut  string `json:"zehut" in:"path" v:"required#Zehut is required"`
+	Id     int64  `json:"id" in:"path" v:"required#Linked account not found"`
+}
+
+type UnlinkUserIdentityRes struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,8 @@
+// Package v1 declares the request and response types of the JSON API. Routes,
+// validation rules and the OpenAPI document served at /api/docs all come from
+// the g.Meta tags and field tags here.
+//
+// Every response is wrapped by middleware.HandlerResponse: the fields of the
+// response type sit next to "success", and failed requests get RFC 7807
+// problem details instead.
+package v1
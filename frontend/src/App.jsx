@@ -4,11 +4,15 @@ import { AuthProvider } from './context/AuthContext'
 import Sidebar from './components/Sidebar'
 import Navbar from './components/Navbar'
 import Footer from './components/Footer'
+import ImpersonationBanner from './components/ImpersonationBanner'
 import ThemeCustomization from './components/ThemeCustomization'
 import Home from './pages/Home'
 import Dashboard from './pages/Dashboard'
 import Login from './pages/Login'
+import ForgotPassword from './pages/ForgotPassword'
+import ResetPassword from './pages/ResetPassword'
 import Permissions from './pages/Permissions'
+import Security from './pages/Security'
 import NotFound from './pages/NotFound'
 
 // Layout wrapper for all pages
@@ -104,6 +108,7 @@ function MainLayout({ children }) {
       <ThemeCustomization />
       <Sidebar />
       <main className="dashboard-main">
+        <ImpersonationBanner />
         <Navbar />
         <div className="dashboard-main-body">
           {children}
@@ -118,6 +123,8 @@ function AppRoutes() {
   return (
     <Routes>
       <Route path="/login" element={<Login />} />
+      <Route path="/forgot-password" element={<ForgotPassword />} />
+      <Route path="/reset-password" element={<ResetPassword />} />
       <Route
         path="/"
         element={
@@ -142,6 +149,14 @@ function AppRoutes() {
           </MainLayout>
         }
       />
+      <Route
+        path="/security"
+        element={
+          <MainLayout>
+            <Security />
+          </MainLayout>
+        }
+      />
       {/* Catch-all route for 404 - must be last */}
       <Route
         path="*"
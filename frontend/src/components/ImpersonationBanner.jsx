@@ -0,0 +1,45 @@
+import React, { useState } from 'react'
+import { useTranslation } from 'react-i18next'
+import { useAuth } from '../context/AuthContext'
+
+// Shown on every page while support staff are logged in as another user
+function ImpersonationBanner() {
+  const { t } = useTranslation()
+  const { user, impersonator, stopImpersonation } = useAuth()
+  const [error, setError] = useState(null)
+  const [returning, setReturning] = useState(false)
+
+  if (!impersonator || !user) {
+    return null
+  }
+
+  const handleReturn = async () => {
+    setError(null)
+    setReturning(true)
+    try {
+      await stopImpersonation()
+    } catch (err) {
+      console.error('Error ending impersonation:', err)
+      setError(t('auth.impersonation.returnError'))
+      setReturning(false)
+    }
+  }
+
+  return (
+    <div className="alert alert-warning d-flex flex-wrap align-items-center justify-content-between gap-2 mb-0 rounded-0" role="alert">
+      <span>
+        <iconify-icon icon="mdi:account-switch" className="me-1"></iconify-icon>
+        {t('auth.impersonation.banner', {
+          name: `${user.first_name} ${user.last_name}`,
+          zehut: user.zehut,
+        })}
+        {error && <span className="text-danger ms-2">{error}</span>}
+      </span>
+      <button type="button" className="btn btn-sm btn-warning" onClick={handleReturn} disabled={returning}>
+        {t('auth.impersonation.return')}
+      </button>
+    </div>
+  )
+}
+
+export default ImpersonationBanner
@@ -101,6 +101,12 @@ function Navbar() {
                         {t('common.profile')}
                       </Link>
                     </li>
+                    <li>
+                      <Link className="dropdown-item text-black px-16 py-8 rounded text-sm" to="/security">
+                        <iconify-icon icon="solar:shield-keyhole-linear" className="icon text-xl"></iconify-icon>
+                        {t('common.security')}
+                      </Link>
+                    </li>
                     <li>
                       <a className="dropdown-item text-black px-16 py-8 rounded text-sm" href="#" onClick={handleLogout}>
                         <iconify-icon icon="lucide:power" className="icon text-xl"></iconify-icon>
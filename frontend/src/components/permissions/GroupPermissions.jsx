@@ -1,15 +1,106 @@
-import React from 'react'
-import { useTranslation } from 'react-i18next'
+import React, {useEffect, useState} from 'react'
+import {useTranslation} from 'react-i18next'
+import PermissionMatrix, {toPermissionList, toPermissionMap} from './PermissionMatrix'
 
 function GroupPermissions() {
-  const { t } = useTranslation()
-
-  return (
-    <div className="tab-pane active">
-      <h5>{t('permissions.groupPermissions.title')}</h5>
-      <p>{t('permissions.groupPermissions.description')}</p>
-    </div>
-  )
+    const {t} = useTranslation()
+    const [groups, setGroups] = useState([])
+    const [resources, setResources] = useState([])
+    const [groupId, setGroupId] = useState('')
+    const [permissions, setPermissions] = useState({})
+    const [error, setError] = useState(null)
+    const [message, setMessage] = useState(null)
+
+    useEffect(() => {
+        const load = async () => {
+            try {
+                const [groupsResponse, resourcesResponse] = await Promise.all([
+                    fetch('/api/groups', {credentials: 'include'}),
+                    fetch('/api/app-resources', {credentials: 'include'})
+                ])
+                const groupsData = await groupsResponse.json()
+                const resourcesData = await resourcesResponse.json()
+                setGroups(groupsData.groups || [])
+                setResources(resourcesData.resources || [])
+            } catch (err) {
+                setError(t('permissions.groupPermissions.loadError'))
+                console.error('Error loading groups or resources:', err)
+            }
+        }
+        load()
+    }, [])
+
+    const handleSelectGroup = async (id) => {
+        setGroupId(id)
+        setMessage(null)
+        setPermissions({})
+        if (!id) {
+            return
+        }
+
+        try {
+            const response = await fetch(`/api/groups/${id}/permissions`, {credentials: 'include'})
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                setPermissions(toPermissionMap(data.permissions))
+            } else {
+                setError(data.detail || t('permissions.groupPermissions.loadError'))
+            }
+        } catch (err) {
+            setError(t('permissions.groupPermissions.loadError'))
+            console.error('Error loading group permissions:', err)
+        }
+    }
+
+    const handleSave = async () => {
+        setError(null)
+        setMessage(null)
+        try {
+            const response = await fetch(`/api/groups/${groupId}/permissions`, {
+                method: 'PUT',
+                headers: {'Content-Type': 'application/json'},
+                credentials: 'include',
+                body: JSON.stringify({permissions: toPermissionList(permissions)})
+            })
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                setMessage(t('permissions.matrix.saved'))
+            } else {
+                setError(data.detail || t('permissions.matrix.saveError'))
+            }
+        } catch (err) {
+            setError(t('permissions.matrix.saveError'))
+            console.error('Error saving group permissions:', err)
+        }
+    }
+
+    return (
+        <div className="tab-pane active">
+            <h5>{t('permissions.groupPermissions.title')}</h5>
+
+            {error && <div className="alert alert-danger" role="alert">{error}</div>}
+            {message && <div className="alert alert-success" role="alert">{message}</div>}
+
+            <div className="mb-3" style={{maxWidth: '320px'}}>
+                <label className="form-label">{t('permissions.groupPermissions.selectGroup')}</label>
+                <select className="form-select" value={groupId} onChange={(e) => handleSelectGroup(e.target.value)}>
+                    <option value="">-</option>
+                    {groups.map((group) => (
+                        <option key={group.id} value={group.id}>{group.name}</option>
+                    ))}
+                </select>
+            </div>
+
+            {groupId && (
+                <>
+                    <PermissionMatrix resources={resources} permissions={permissions} onChange={setPermissions}/>
+                    <button className="btn btn-primary btn-sm" onClick={handleSave}>
+                        {t('permissions.matrix.save')}
+                    </button>
+                </>
+            )}
+        </div>
+    )
 }
 
 export default GroupPermissions
@@ -1,15 +1,324 @@
-import React from 'react'
-import { useTranslation } from 'react-i18next'
+import React, {useEffect, useState} from 'react'
+import {useTranslation} from 'react-i18next'
 
 function Groups() {
-  const { t } = useTranslation()
-
-  return (
-    <div className="tab-pane active">
-      <h5>{t('permissions.groups.title')}</h5>
-      <p>{t('permissions.groups.description')}</p>
-    </div>
-  )
+    const {t} = useTranslation()
+    const [groups, setGroups] = useState([])
+    const [loading, setLoading] = useState(true)
+    const [error, setError] = useState(null)
+    const [showModal, setShowModal] = useState(false)
+    const [editingGroup, setEditingGroup] = useState(null)
+    const [formData, setFormData] = useState({name: '', description: ''})
+    const [selectedGroup, setSelectedGroup] = useState(null)
+    const [members, setMembers] = useState([])
+    const [newMember, setNewMember] = useState('')
+
+    useEffect(() => {
+        loadGroups()
+    }, [])
+
+    const loadGroups = async () => {
+        try {
+            setLoading(true)
+            setError(null)
+
+            const response = await fetch('/api/groups', {credentials: 'include'})
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                setGroups(data.groups || [])
+            } else {
+                setError(data.detail || t('permissions.groups.loadError'))
+            }
+        } catch (err) {
+            setError(t('permissions.groups.loadError'))
+            console.error('Error loading groups:', err)
+        } finally {
+            setLoading(false)
+        }
+    }
+
+    const loadMembers = async (group) => {
+        setSelectedGroup(group)
+        try {
+            const response = await fetch(`/api/groups/${group.id}/members`, {credentials: 'include'})
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                setMembers(data.members || [])
+            } else {
+                setError(data.detail || t('permissions.groups.loadError'))
+            }
+        } catch (err) {
+            setError(t('permissions.groups.loadError'))
+            console.error('Error loading members:', err)
+        }
+    }
+
+    const handleCreate = () => {
+        setEditingGroup(null)
+        setFormData({name: '', description: ''})
+        setShowModal(true)
+    }
+
+    const handleEdit = (group) => {
+        setEditingGroup(group)
+        setFormData({name: group.name, description: group.description || ''})
+        setShowModal(true)
+    }
+
+    const handleDelete = async (group) => {
+        if (!window.confirm(t('permissions.groups.confirmDelete'))) {
+            return
+        }
+
+        try {
+            const response = await fetch(`/api/groups/${group.id}`, {
+                method: 'DELETE',
+                credentials: 'include'
+            })
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                if (selectedGroup && selectedGroup.id === group.id) {
+                    setSelectedGroup(null)
+                    setMembers([])
+                }
+                await loadGroups()
+            } else {
+                setError(data.detail || t('permissions.groups.deleteError'))
+            }
+        } catch (err) {
+            setError(t('permissions.groups.deleteError'))
+            console.error('Error deleting group:', err)
+        }
+    }
+
+    const handleSubmit = async (e) => {
+        e.preventDefault()
+
+        if (!formData.name.trim()) {
+            setError(t('permissions.groups.nameRequired'))
+            return
+        }
+
+        try {
+            const url = editingGroup ? `/api/groups/${editingGroup.id}` : '/api/groups'
+            const response = await fetch(url, {
+                method: editingGroup ? 'PUT' : 'POST',
+                headers: {'Content-Type': 'application/json'},
+                credentials: 'include',
+                body: JSON.stringify(formData)
+            })
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                setShowModal(false)
+                await loadGroups()
+            } else {
+                setError(data.detail || t('permissions.groups.saveError'))
+            }
+        } catch (err) {
+            setError(t('permissions.groups.saveError'))
+            console.error('Error saving group:', err)
+        }
+    }
+
+    const handleAddMember = async (e) => {
+        e.preventDefault()
+        if (!newMember.trim() || !selectedGroup) {
+            return
+        }
+
+        try {
+            const response = await fetch(`/api/groups/${selectedGroup.id}/members`, {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                credentials: 'include',
+                body: JSON.stringify({zehut: newMember.trim()})
+            })
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                setNewMember('')
+                await loadMembers(selectedGroup)
+            } else {
+                setError(data.detail || t('permissions.groups.saveError'))
+            }
+        } catch (err) {
+            setError(t('permissions.groups.saveError'))
+            console.error('Error adding member:', err)
+        }
+    }
+
+    const handleRemoveMember = async (zehut) => {
+        try {
+            const response = await fetch(`/api/groups/${selectedGroup.id}/members/${encodeURIComponent(zehut)}`, {
+                method: 'DELETE',
+                credentials: 'include'
+            })
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                await loadMembers(selectedGroup)
+            } else {
+                setError(data.detail || t('permissions.groups.deleteError'))
+            }
+        } catch (err) {
+            setError(t('permissions.groups.deleteError'))
+            console.error('Error removing member:', err)
+        }
+    }
+
+    if (loading) {
+        return (
+            <div className="tab-pane active">
+                <h5>{t('permissions.groups.title')}</h5>
+                <div className="d-flex justify-content-center">
+                    <div className="spinner-border" role="status">
+                        <span className="visually-hidden">Loading...</span>
+                    </div>
+                </div>
+            </div>
+        )
+    }
+
+    return (
+        <div className="tab-pane active">
+            <div className="d-flex justify-content-between align-items-center mb-3">
+                <h5>{t('permissions.groups.title')}</h5>
+                <button className="btn btn-primary btn-sm" onClick={handleCreate}>
+                    {t('permissions.groups.addNew')}
+                </button>
+            </div>
+
+            {error && (
+                <div className="alert alert-danger" role="alert">
+                    {error}
+                </div>
+            )}
+
+            <div className="row">
+                <div className="col-md-6">
+                    <table className="table table-striped table-sm">
+                        <thead>
+                        <tr>
+                            <th className="text-start">{t('permissions.groups.name')}</th>
+                            <th className="text-start">{t('permissions.groups.groupDescription')}</th>
+                            <th className="text-center">{t('permissions.modules.actions')}</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {groups.length === 0 ? (
+                            <tr>
+                                <td colSpan="3" className="text-center text-muted">
+                                    {t('permissions.groups.noGroups')}
+                                </td>
+                            </tr>
+                        ) : (
+                            groups.map((group) => (
+                                <tr key={group.id} className={selectedGroup && selectedGroup.id === group.id ? 'table-active' : ''}>
+                                    <td>{group.name}</td>
+                                    <td>{group.description || '-'}</td>
+                                    <td className="text-center">
+                                        <button className="btn btn-sm btn-outline-secondary me-1" onClick={() => loadMembers(group)}>
+                                            {t('permissions.groups.members')}
+                                        </button>
+                                        <button className="btn btn-sm btn-outline-primary me-1" onClick={() => handleEdit(group)}>
+                                            {t('permissions.modules.edit')}
+                                        </button>
+                                        <button className="btn btn-sm btn-outline-danger" onClick={() => handleDelete(group)}>
+                                            {t('permissions.modules.delete')}
+                                        </button>
+                                    </td>
+                                </tr>
+                            ))
+                        )}
+                        </tbody>
+                    </table>
+                </div>
+
+                {selectedGroup && (
+                    <div className="col-md-6">
+                        <h6>{t('permissions.groups.membersOf', {name: selectedGroup.name})}</h6>
+                        <form className="d-flex gap-2 mb-3" onSubmit={handleAddMember}>
+                            <input
+                                type="text"
+                                className="form-control form-control-sm"
+                                placeholder={t('permissions.groups.zehutPlaceholder')}
+                                value={newMember}
+                                onChange={(e) => setNewMember(e.target.value)}
+                            />
+                            <button type="submit" className="btn btn-primary btn-sm">
+                                {t('permissions.groups.addMember')}
+                            </button>
+                        </form>
+                        <table className="table table-striped table-sm">
+                            <tbody>
+                            {members.length === 0 ? (
+                                <tr>
+                                    <td className="text-center text-muted">{t('permissions.groups.noMembers')}</td>
+                                </tr>
+                            ) : (
+                                members.map((member) => (
+                                    <tr key={member.zehut}>
+                                        <td>{member.zehut}</td>
+                                        <td>{member.first_name} {member.last_name}</td>
+                                        <td className="text-center">
+                                            <button className="btn btn-sm btn-outline-danger" onClick={() => handleRemoveMember(member.zehut)}>
+                                                {t('permissions.groups.removeMember')}
+                                            </button>
+                                        </td>
+                                    </tr>
+                                ))
+                            )}
+                            </tbody>
+                        </table>
+                    </div>
+                )}
+            </div>
+
+            {showModal && (
+                <div className="modal show d-block" style={{backgroundColor: 'rgba(0,0,0,0.5)'}}>
+                    <div className="modal-dialog">
+                        <div className="modal-content">
+                            <div className="modal-header">
+                                <h5 className="modal-title">
+                                    {editingGroup ? t('permissions.groups.editGroup') : t('permissions.groups.addGroup')}
+                                </h5>
+                                <button type="button" className="btn-close" onClick={() => setShowModal(false)}></button>
+                            </div>
+                            <form onSubmit={handleSubmit}>
+                                <div className="modal-body">
+                                    <div className="mb-3">
+                                        <label className="form-label">{t('permissions.groups.name')}</label>
+                                        <input
+                                            type="text"
+                                            className="form-control"
+                                            value={formData.name}
+                                            onChange={(e) => setFormData({...formData, name: e.target.value})}
+                                            required
+                                        />
+                                    </div>
+                                    <div className="mb-3">
+                                        <label className="form-label">{t('permissions.groups.groupDescription')}</label>
+                                        <textarea
+                                            className="form-control"
+                                            rows="3"
+                                            value={formData.description}
+                                            onChange={(e) => setFormData({...formData, description: e.target.value})}
+                                        />
+                                    </div>
+                                </div>
+                                <div className="modal-footer">
+                                    <button type="button" className="btn btn-secondary" onClick={() => setShowModal(false)}>
+                                        {t('permissions.modules.cancel')}
+                                    </button>
+                                    <button type="submit" className="btn btn-primary">
+                                        {editingGroup ? t('permissions.modules.update') : t('permissions.modules.create')}
+                                    </button>
+                                </div>
+                            </form>
+                        </div>
+                    </div>
+                </div>
+            )}
+        </div>
+    )
 }
 
 export default Groups
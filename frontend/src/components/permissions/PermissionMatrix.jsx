@@ -0,0 +1,82 @@
+import React from 'react'
+import {useTranslation} from 'react-i18next'
+
+export const PERMISSION_FLAGS = ['can_view', 'can_create', 'can_edit', 'can_delete']
+
+// Renders one row per app resource with a checkbox for each action.
+// `permissions` is keyed by resource id.
+function PermissionMatrix({resources, permissions, onChange}) {
+    const {t} = useTranslation()
+
+    const toggle = (resourceId, flag) => {
+        const current = permissions[resourceId] || {}
+        onChange({
+            ...permissions,
+            [resourceId]: {...current, [flag]: !current[flag]}
+        })
+    }
+
+    return (
+        <div className="table-responsive">
+            <table className="table table-striped table-sm">
+                <thead>
+                <tr>
+                    <th className="text-start">{t('permissions.matrix.resource')}</th>
+                    {PERMISSION_FLAGS.map((flag) => (
+                        <th key={flag} className="text-center">{t(`permissions.matrix.${flag}`)}</th>
+                    ))}
+                </tr>
+                </thead>
+                <tbody>
+                {resources.length === 0 ? (
+                    <tr>
+                        <td colSpan={PERMISSION_FLAGS.length + 1} className="text-center text-muted">
+                            {t('permissions.modules.noResources')}
+                        </td>
+                    </tr>
+                ) : (
+                    resources.map((resource) => (
+                        <tr key={resource.id}>
+                            <td>{resource.name}</td>
+                            {PERMISSION_FLAGS.map((flag) => (
+                                <td key={flag} className="text-center">
+                                    <input
+                                        type="checkbox"
+                                        className="form-check-input"
+                                        checked={!!(permissions[resource.id] || {})[flag]}
+                                        onChange={() => toggle(resource.id, flag)}
+                                    />
+                                </td>
+                            ))}
+                        </tr>
+                    ))
+                )}
+                </tbody>
+            </table>
+        </div>
+    )
+}
+
+// Converts an API permission list into a map keyed by resource id
+export const toPermissionMap = (list) => {
+    const map = {}
+    ;(list || []).forEach((p) => {
+        map[p.resource_id] = p
+    })
+    return map
+}
+
+// Converts a permission map back into the list the API expects, dropping empty rows
+export const toPermissionList = (map) => {
+    return Object.entries(map)
+        .map(([resourceId, p]) => ({
+            resource_id: resourceId,
+            can_view: !!p.can_view,
+            can_create: !!p.can_create,
+            can_edit: !!p.can_edit,
+            can_delete: !!p.can_delete
+        }))
+        .filter((p) => PERMISSION_FLAGS.some((flag) => p[flag]))
+}
+
+export default PermissionMatrix
@@ -1,15 +1,126 @@
-import React from 'react'
-import { useTranslation } from 'react-i18next'
+import React, {useEffect, useState} from 'react'
+import {useTranslation} from 'react-i18next'
+import {useAuth} from '../../context/AuthContext'
+import PermissionMatrix, {toPermissionList, toPermissionMap} from './PermissionMatrix'
 
 function UserPermissions() {
-  const { t } = useTranslation()
-
-  return (
-    <div className="tab-pane active">
-      <h5>{t('permissions.userPermissions.title')}</h5>
-      <p>{t('permissions.userPermissions.description')}</p>
-    </div>
-  )
+    const {t} = useTranslation()
+    const {canImpersonate, startImpersonation} = useAuth()
+    const [resources, setResources] = useState([])
+    const [zehut, setZehut] = useState('')
+    const [loadedZehut, setLoadedZehut] = useState('')
+    const [permissions, setPermissions] = useState({})
+    const [error, setError] = useState(null)
+    const [message, setMessage] = useState(null)
+
+    useEffect(() => {
+        const load = async () => {
+            try {
+                const response = await fetch('/api/app-resources', {credentials: 'include'})
+                const data = await response.json()
+                setResources(data.resources || [])
+            } catch (err) {
+                setError(t('permissions.userPermissions.loadError'))
+                console.error('Error loading resources:', err)
+            }
+        }
+        load()
+    }, [])
+
+    const handleLoad = async (e) => {
+        e.preventDefault()
+        setError(null)
+        setMessage(null)
+        if (!zehut.trim()) {
+            return
+        }
+
+        try {
+            const response = await fetch(`/api/users/${encodeURIComponent(zehut.trim())}/permissions`, {credentials: 'include'})
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                setPermissions(toPermissionMap(data.permissions))
+                setLoadedZehut(zehut.trim())
+            } else {
+                setError(data.detail || t('permissions.userPermissions.loadError'))
+            }
+        } catch (err) {
+            setError(t('permissions.userPermissions.loadError'))
+            console.error('Error loading user permissions:', err)
+        }
+    }
+
+    const handleSave = async () => {
+        setError(null)
+        setMessage(null)
+        try {
+            const response = await fetch(`/api/users/${encodeURIComponent(loadedZehut)}/permissions`, {
+                method: 'PUT',
+                headers: {'Content-Type': 'application/json'},
+                credentials: 'include',
+                body: JSON.stringify({permissions: toPermissionList(permissions)})
+            })
+            const data = await response.json()
+            if (response.ok && data.success !== false) {
+                setMessage(t('permissions.matrix.saved'))
+            } else {
+                setError(data.detail || t('permissions.matrix.saveError'))
+            }
+        } catch (err) {
+            setError(t('permissions.matrix.saveError'))
+            console.error('Error saving user permissions:', err)
+        }
+    }
+
+    const handleImpersonate = async () => {
+        setError(null)
+        setMessage(null)
+        try {
+            await startImpersonation(loadedZehut)
+        } catch (err) {
+            setError(err.message || t('auth.impersonation.startError'))
+            console.error('Error starting impersonation:', err)
+        }
+    }
+
+    return (
+        <div className="tab-pane active">
+            <h5>{t('permissions.userPermissions.title')}</h5>
+            <p className="text-muted">{t('permissions.userPermissions.description')}</p>
+
+            {error && <div className="alert alert-danger" role="alert">{error}</div>}
+            {message && <div className="alert alert-success" role="alert">{message}</div>}
+
+            <form className="d-flex gap-2 mb-3" style={{maxWidth: '400px'}} onSubmit={handleLoad}>
+                <input
+                    type="text"
+                    className="form-control"
+                    placeholder={t('permissions.groups.zehutPlaceholder')}
+                    value={zehut}
+                    onChange={(e) => setZehut(e.target.value)}
+                />
+                <button type="submit" className="btn btn-outline-primary">
+                    {t('permissions.userPermissions.load')}
+                </button>
+            </form>
+
+            {loadedZehut && (
+                <>
+                    <PermissionMatrix resources={resources} permissions={permissions} onChange={setPermissions}/>
+                    <div className="d-flex gap-2">
+                        <button className="btn btn-primary btn-sm" onClick={handleSave}>
+                            {t('permissions.matrix.save')}
+                        </button>
+                        {canImpersonate && (
+                            <button className="btn btn-outline-warning btn-sm" onClick={handleImpersonate}>
+                                {t('auth.impersonation.impersonate')}
+                            </button>
+                        )}
+                    </div>
+                </>
+            )}
+        </div>
+    )
 }
 
 export default UserPermissions
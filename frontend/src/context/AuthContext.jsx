@@ -12,6 +12,9 @@ export const useAuth = () => {
 
 export const AuthProvider = ({ children }) => {
   const [user, setUser] = useState(null)
+  // Set while support staff are logged in as this user
+  const [impersonator, setImpersonator] = useState(null)
+  const [canImpersonate, setCanImpersonate] = useState(false)
   const [loading, setLoading] = useState(true)
 
   useEffect(() => {
@@ -27,6 +30,12 @@ export const AuthProvider = ({ children }) => {
       if (response.ok) {
         const data = await response.json()
         setUser(data.user)
+        setImpersonator(data.impersonator || null)
+        setCanImpersonate(!!data.can_impersonate)
+        // Until a required 2FA enrollment is done the API refuses everything else
+        if (data.mfa_enrollment_required && window.location.pathname !== '/security') {
+          window.location.href = '/security'
+        }
       } else {
         setUser(null)
       }
@@ -55,11 +64,44 @@ export const AuthProvider = ({ children }) => {
     }
   }
 
+  const startImpersonation = async (zehut) => {
+    const response = await fetch(`/api/users/${encodeURIComponent(zehut)}/impersonate`, {
+      method: 'POST',
+      credentials: 'include',
+    })
+    const data = await response.json()
+    if (!response.ok || data.success === false) {
+      throw new Error(data.detail)
+    }
+    window.location.href = '/'
+  }
+
+  const stopImpersonation = async () => {
+    const response = await fetch('/api/me/impersonation', {
+      method: 'DELETE',
+      credentials: 'include',
+    })
+    // A 401 means the staff member's own session ended meanwhile
+    if (response.status === 401) {
+      window.location.href = '/login'
+      return
+    }
+    const data = await response.json()
+    if (!response.ok || data.success === false) {
+      throw new Error(data.detail)
+    }
+    window.location.href = '/'
+  }
+
   const value = {
     user,
     loading,
     login,
     logout,
+    impersonator,
+    canImpersonate,
+    startImpersonation,
+    stopImpersonation,
     isAuthenticated: !!user,
   }
 
@@ -0,0 +1,93 @@
+import React, { useState } from 'react'
+import { Link } from 'react-router-dom'
+
+function ForgotPassword() {
+  const [zehut, setZehut] = useState('')
+  const [error, setError] = useState('')
+  const [sent, setSent] = useState(false)
+  const [loading, setLoading] = useState(false)
+
+  const handleSubmit = async (e) => {
+    e.preventDefault()
+    setError('')
+    setLoading(true)
+
+    try {
+      const response = await fetch('/auth/password/forgot', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ zehut }),
+      })
+
+      const data = await response.json()
+
+      if (response.ok && data.success !== false) {
+        setSent(true)
+      } else {
+        setError(data.detail || 'Request failed')
+      }
+    } catch (err) {
+      setError('Network error. Please try again.')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div className="min-vh-100 d-flex align-items-center justify-content-center bg-neutral-50">
+      <div className="card shadow-lg" style={{ maxWidth: '450px', width: '100%' }}>
+        <div className="card-body p-40">
+          <div className="text-center mb-32">
+            <h3 className="mb-8">שכחתי סיסמה</h3>
+            <p className="text-secondary-light">הכנס את תעודת הזהות שלך ונשלח קישור לאיפוס הסיסמה לכתובת המייל הרשומה</p>
+          </div>
+
+          {error && (
+            <div className="alert alert-danger mb-24" role="alert">
+              {error}
+            </div>
+          )}
+
+          {sent ? (
+            <div className="alert alert-success mb-24" role="alert">
+              אם החשבון קיים, נשלח אליו קישור לאיפוס הסיסמה.
+            </div>
+          ) : (
+            <form onSubmit={handleSubmit}>
+              <div className="mb-20">
+                <label className="form-label fw-semibold text-primary-light text-sm mb-8">
+                  תעודת זהות
+                </label>
+                <input
+                  type="text"
+                  className="form-control radius-8"
+                  placeholder="הכנס תעודת זהות"
+                  value={zehut}
+                  onChange={(e) => setZehut(e.target.value)}
+                  required
+                  maxLength="9"
+                />
+              </div>
+
+              <button
+                type="submit"
+                className="btn btn-primary text-sm btn-sm px-12 py-16 w-100 radius-8 mt-32"
+                disabled={loading}
+              >
+                {loading ? 'שולח...' : 'שלח קישור'}
+              </button>
+            </form>
+          )}
+
+          <div className="mt-24 text-center">
+            <Link to="/login" className="text-primary-600 text-sm">חזרה להתחברות</Link>
+          </div>
+        </div>
+      </div>
+    </div>
+  )
+}
+
+export default ForgotPassword
@@ -1,15 +1,52 @@
-import React, { useState } from 'react'
-import { useNavigate } from 'react-router-dom'
+import React, { useEffect, useState } from 'react'
+import { Link, useNavigate, useSearchParams } from 'react-router-dom'
 import { useTranslation } from 'react-i18next'
 
+// safeReturnTo accepts only paths on this site, mirroring auth.SafeReturnTo.
+// Browsers drop tabs and newlines from URLs, turning "/\t/host" into
+// "//host", so control characters are refused and the result must resolve to
+// this origin.
+const safeReturnTo = (value) => {
+  if (!value || value.length > 2048 || !value.startsWith('/') || value.startsWith('//') || value.includes('\\')) {
+    return null
+  }
+  if ([...value].some((c) => c < ' ' || c === '\x7f')) {
+    return null
+  }
+
+  try {
+    const url = new URL(value, window.location.origin)
+    return url.origin === window.location.origin ? url.pathname + url.search + url.hash : null
+  } catch {
+    return null
+  }
+}
+
 function Login() {
   const { t } = useTranslation()
   const navigate = useNavigate()
+  const [searchParams] = useSearchParams()
+  const returnTo = safeReturnTo(searchParams.get('return_to'))
   const [zehut, setZehut] = useState('')
   const [password, setPassword] = useState('')
-  const [error, setError] = useState('')
+  // Provider callbacks redirect here with ?mfa=1 when a code is needed
+  const [mfaRequired, setMfaRequired] = useState(searchParams.get('mfa') === '1')
+  const [code, setCode] = useState('')
+  const [providers, setProviders] = useState([])
+  // Provider callbacks redirect here with ?error=<code> when the login failed
+  const errorCode = searchParams.get('error')
+  const [error, setError] = useState(
+    errorCode ? t(`auth.errors.${errorCode}`, { defaultValue: t('auth.errors.unknown') }) : ''
+  )
   const [loading, setLoading] = useState(false)
 
+  useEffect(() => {
+    fetch('/auth/providers')
+      .then((response) => response.json())
+      .then((data) => setProviders(data.providers || []))
+      .catch(() => setProviders([]))
+  }, [])
+
   const handleLogin = async (e) => {
     e.preventDefault()
     setError('')
@@ -27,11 +64,46 @@ function Login() {
 
       const data = await response.json()
 
-      if (response.ok) {
-        // Redirect to dashboard
-        window.location.href = '/dashboard'
+      if (response.ok && data.status === 'mfa_required') {
+        setMfaRequired(true)
+      } else if (response.ok && data.success !== false) {
+        window.location.href = returnTo || '/dashboard'
+      } else {
+        setError(data.detail || 'Login failed')
+      }
+    } catch (err) {
+      setError('Network error. Please try again.')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const handleVerify = async (e) => {
+    e.preventDefault()
+    setError('')
+    setLoading(true)
+
+    try {
+      const response = await fetch('/auth/2fa/verify', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ code }),
+        credentials: 'include',
+      })
+
+      const data = await response.json()
+
+      if (response.ok && data.success !== false) {
+        window.location.href = returnTo || '/dashboard'
       } else {
-        setError(data.error || 'Login failed')
+        setError(data.detail || 'Verification failed')
+        // The pending login is gone, so start over with the password
+        if (response.status === 401 && data.detail !== 'Invalid verification code') {
+          setMfaRequired(false)
+          setCode('')
+        }
       }
     } catch (err) {
       setError('Network error. Please try again.')
@@ -40,8 +112,9 @@ function Login() {
     }
   }
 
-  const handleGoogleLogin = () => {
-    window.location.href = '/auth/google/login'
+  const handleProviderLogin = (name) => {
+    const query = returnTo ? `?return_to=${encodeURIComponent(returnTo)}` : ''
+    window.location.href = `/auth/${name}/login${query}`
   }
 
   return (
@@ -59,57 +132,101 @@ function Login() {
             </div>
           )}
 
-          <form onSubmit={handleLogin}>
-            <div className="mb-20">
-              <label className="form-label fw-semibold text-primary-light text-sm mb-8">
-                תעודת זהות
-              </label>
-              <input
-                type="text"
-                className="form-control radius-8"
-                placeholder="הכנס תעודת זהות"
-                value={zehut}
-                onChange={(e) => setZehut(e.target.value)}
-                required
-                maxLength="9"
-              />
-            </div>
+          {mfaRequired ? (
+            <form onSubmit={handleVerify}>
+              <div className="mb-20">
+                <label className="form-label fw-semibold text-primary-light text-sm mb-8">
+                  קוד אימות
+                </label>
+                <input
+                  type="text"
+                  className="form-control radius-8"
+                  placeholder="הכנס את הקוד מאפליקציית האימות או קוד שחזור"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  autoComplete="one-time-code"
+                  autoFocus
+                  required
+                />
+              </div>
 
-            <div className="mb-20">
-              <label className="form-label fw-semibold text-primary-light text-sm mb-8">
-                סיסמה
-              </label>
-              <input
-                type="password"
-                className="form-control radius-8"
-                placeholder="הכנס סיסמה"
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                required
-              />
-            </div>
+              <button
+                type="submit"
+                className="btn btn-primary text-sm btn-sm px-12 py-16 w-100 radius-8 mt-32"
+                disabled={loading}
+              >
+                {loading ? 'מאמת...' : 'אמת'}
+              </button>
+            </form>
+          ) : (
+            <>
+              <form onSubmit={handleLogin}>
+                <div className="mb-20">
+                  <label className="form-label fw-semibold text-primary-light text-sm mb-8">
+                    תעודת זהות
+                  </label>
+                  <input
+                    type="text"
+                    className="form-control radius-8"
+                    placeholder="הכנס תעודת זהות"
+                    value={zehut}
+                    onChange={(e) => setZehut(e.target.value)}
+                    required
+                    maxLength="9"
+                  />
+                </div>
 
-            <button
-              type="submit"
-              className="btn btn-primary text-sm btn-sm px-12 py-16 w-100 radius-8 mt-32"
-              disabled={loading}
-            >
-              {loading ? 'מתחבר...' : 'התחבר'}
-            </button>
-          </form>
-
-          <div className="my-24 text-center">
-            <span className="text-secondary-light text-sm">או</span>
-          </div>
+                <div className="mb-20">
+                  <label className="form-label fw-semibold text-primary-light text-sm mb-8">
+                    סיסמה
+                  </label>
+                  <input
+                    type="password"
+                    className="form-control radius-8"
+                    placeholder="הכנס סיסמה"
+                    value={password}
+                    onChange={(e) => setPassword(e.target.value)}
+                    required
+                  />
+                </div>
 
-          <button
-            type="button"
-            onClick={handleGoogleLogin}
-            className="btn btn-outline-primary text-sm btn-sm px-12 py-16 w-100 radius-8 d-flex align-items-center justify-content-center gap-2"
-          >
-            <iconify-icon icon="logos:google-icon" className="text-xl"></iconify-icon>
-            התחבר עם Google
-          </button>
+                <div className="text-end">
+                  <Link to="/forgot-password" className="text-primary-600 text-sm">
+                    שכחתי סיסמה
+                  </Link>
+                </div>
+
+                <button
+                  type="submit"
+                  className="btn btn-primary text-sm btn-sm px-12 py-16 w-100 radius-8 mt-32"
+                  disabled={loading}
+                >
+                  {loading ? 'מתחבר...' : 'התחבר'}
+                </button>
+              </form>
+
+              {providers.length > 0 && (
+                <div className="my-24 text-center">
+                  <span className="text-secondary-light text-sm">או</span>
+                </div>
+              )}
+
+              {providers.map((provider) => (
+                <button
+                  key={provider.name}
+                  type="button"
+                  onClick={() => handleProviderLogin(provider.name)}
+                  className="btn btn-outline-primary text-sm btn-sm px-12 py-16 w-100 radius-8 d-flex align-items-center justify-content-center gap-2 mb-12"
+                >
+                  <iconify-icon
+                    icon={provider.name === 'google' ? 'logos:google-icon' : 'solar:login-2-outline'}
+                    className="text-xl"
+                  ></iconify-icon>
+                  התחבר עם {provider.display_name}
+                </button>
+              ))}
+            </>
+          )}
         </div>
       </div>
     </div>
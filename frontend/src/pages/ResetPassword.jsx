@@ -0,0 +1,119 @@
+import React, { useState } from 'react'
+import { Link, useSearchParams } from 'react-router-dom'
+
+function ResetPassword() {
+  const [searchParams] = useSearchParams()
+  const token = searchParams.get('token') || ''
+  const [password, setPassword] = useState('')
+  const [confirm, setConfirm] = useState('')
+  const [error, setError] = useState('')
+  const [done, setDone] = useState(false)
+  const [loading, setLoading] = useState(false)
+
+  const handleSubmit = async (e) => {
+    e.preventDefault()
+    setError('')
+
+    if (password !== confirm) {
+      setError('הסיסמאות אינן תואמות')
+      return
+    }
+
+    setLoading(true)
+
+    try {
+      const response = await fetch('/auth/password/reset', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ token, password }),
+      })
+
+      const data = await response.json()
+
+      if (response.ok && data.success !== false) {
+        setDone(true)
+      } else {
+        setError(data.detail || 'Reset failed')
+      }
+    } catch (err) {
+      setError('Network error. Please try again.')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div className="min-vh-100 d-flex align-items-center justify-content-center bg-neutral-50">
+      <div className="card shadow-lg" style={{ maxWidth: '450px', width: '100%' }}>
+        <div className="card-body p-40">
+          <div className="text-center mb-32">
+            <h3 className="mb-8">איפוס סיסמה</h3>
+            <p className="text-secondary-light">בחר סיסמה חדשה לחשבון שלך</p>
+          </div>
+
+          {error && (
+            <div className="alert alert-danger mb-24" role="alert">
+              {error}
+            </div>
+          )}
+
+          {!token ? (
+            <div className="alert alert-danger mb-24" role="alert">
+              הקישור אינו תקין. בקש קישור חדש.
+            </div>
+          ) : done ? (
+            <div className="alert alert-success mb-24" role="alert">
+              הסיסמה עודכנה. ניתן להתחבר עם הסיסמה החדשה.
+            </div>
+          ) : (
+            <form onSubmit={handleSubmit}>
+              <div className="mb-20">
+                <label className="form-label fw-semibold text-primary-light text-sm mb-8">
+                  סיסמה חדשה
+                </label>
+                <input
+                  type="password"
+                  className="form-control radius-8"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                  minLength="8"
+                />
+              </div>
+
+              <div className="mb-20">
+                <label className="form-label fw-semibold text-primary-light text-sm mb-8">
+                  אימות סיסמה
+                </label>
+                <input
+                  type="password"
+                  className="form-control radius-8"
+                  value={confirm}
+                  onChange={(e) => setConfirm(e.target.value)}
+                  required
+                  minLength="8"
+                />
+              </div>
+
+              <button
+                type="submit"
+                className="btn btn-primary text-sm btn-sm px-12 py-16 w-100 radius-8 mt-32"
+                disabled={loading}
+              >
+                {loading ? 'שומר...' : 'עדכן סיסמה'}
+              </button>
+            </form>
+          )}
+
+          <div className="mt-24 text-center">
+            <Link to="/login" className="text-primary-600 text-sm">חזרה להתחברות</Link>
+          </div>
+        </div>
+      </div>
+    </div>
+  )
+}
+
+export default ResetPassword
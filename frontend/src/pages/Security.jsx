@@ -0,0 +1,282 @@
+import React, { useEffect, useState } from 'react'
+import { useSearchParams } from 'react-router-dom'
+import { useTranslation } from 'react-i18next'
+
+function LinkedAccounts() {
+  const { t } = useTranslation()
+  const [searchParams] = useSearchParams()
+  const [identities, setIdentities] = useState([])
+  const [providers, setProviders] = useState([])
+  // Failed links redirect back here with ?error=<code>
+  const errorCode = searchParams.get('error')
+  const [error, setError] = useState(
+    errorCode ? t(`auth.errors.${errorCode}`, { defaultValue: t('auth.errors.unknown') }) : ''
+  )
+
+  useEffect(() => {
+    loadIdentities()
+    fetch('/auth/providers')
+      .then((response) => response.json())
+      .then((data) => setProviders(data.providers || []))
+      .catch(() => setProviders([]))
+  }, [])
+
+  const loadIdentities = async () => {
+    try {
+      const response = await fetch('/api/me/identities', { credentials: 'include' })
+      const data = await response.json()
+      if (data.success) {
+        setIdentities(data.identities || [])
+      } else {
+        setError(data.detail)
+      }
+    } catch (err) {
+      setError('Network error. Please try again.')
+    }
+  }
+
+  const handleUnlink = async (id) => {
+    setError('')
+
+    try {
+      const response = await fetch(`/api/me/identities/${id}`, {
+        method: 'DELETE',
+        credentials: 'include',
+      })
+      const data = await response.json()
+      if (data.success) {
+        await loadIdentities()
+      } else {
+        setError(data.detail)
+      }
+    } catch (err) {
+      setError('Network error. Please try again.')
+    }
+  }
+
+  const displayName = (name) => providers.find((provider) => provider.name === name)?.display_name || name
+
+  return (
+    <div className="card mt-24" style={{ maxWidth: '600px' }}>
+      <div className="card-body p-24">
+        <h5 className="mb-16">חשבונות מקושרים</h5>
+
+        {error && (
+          <div className="alert alert-danger mb-24" role="alert">
+            {error}
+          </div>
+        )}
+
+        {identities.length === 0 && (
+          <p className="text-secondary-light mb-16">אין חשבונות מקושרים.</p>
+        )}
+
+        <ul className="list-unstyled mb-16">
+          {identities.map((identity) => (
+            <li key={identity.id} className="d-flex align-items-center justify-content-between mb-8">
+              <span>
+                {displayName(identity.provider)} <span className="text-secondary-light" dir="ltr">{identity.email}</span>
+              </span>
+              <button type="button" className="btn btn-outline-danger text-sm btn-sm radius-8" onClick={() => handleUnlink(identity.id)}>
+                נתק
+              </button>
+            </li>
+          ))}
+        </ul>
+
+        <div className="d-flex gap-2 flex-wrap">
+          {providers.map((provider) => (
+            <a key={provider.name} className="btn btn-outline-primary text-sm btn-sm radius-8" href={`/api/me/identities/${provider.name}/link`}>
+              קשר חשבון {provider.display_name}
+            </a>
+          ))}
+        </div>
+      </div>
+    </div>
+  )
+}
+
+function Security() {
+  const [status, setStatus] = useState(null)
+  const [enrollment, setEnrollment] = useState(null)
+  const [recoveryCodes, setRecoveryCodes] = useState(null)
+  const [code, setCode] = useState('')
+  const [error, setError] = useState('')
+  const [loading, setLoading] = useState(false)
+
+  useEffect(() => {
+    loadStatus()
+  }, [])
+
+  const request = async (method, url, body) => {
+    const response = await fetch(url, {
+      method,
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: body ? JSON.stringify(body) : undefined,
+      credentials: 'include',
+    })
+    return response.json()
+  }
+
+  const loadStatus = async () => {
+    try {
+      const data = await request('GET', '/api/me/2fa')
+      if (data.success) {
+        setStatus(data.two_factor)
+      } else {
+        setError(data.detail)
+      }
+    } catch (err) {
+      setError('Network error. Please try again.')
+    }
+  }
+
+  // run sends the current code to url and handles the common response shape
+  const run = async (method, url, onSuccess) => {
+    setError('')
+    setLoading(true)
+
+    try {
+      const data = await request(method, url, { code })
+      if (data.success) {
+        setCode('')
+        onSuccess(data)
+        await loadStatus()
+      } else {
+        setError(data.detail)
+      }
+    } catch (err) {
+      setError('Network error. Please try again.')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const handleEnroll = async () => {
+    setError('')
+    setLoading(true)
+
+    try {
+      const data = await request('POST', '/api/me/2fa/enroll')
+      if (data.success) {
+        setEnrollment(data.enrollment)
+        setRecoveryCodes(null)
+      } else {
+        setError(data.detail)
+      }
+    } catch (err) {
+      setError('Network error. Please try again.')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const handleConfirm = (e) => {
+    e.preventDefault()
+    run('POST', '/api/me/2fa/confirm', (data) => {
+      setEnrollment(null)
+      setRecoveryCodes(data.recovery_codes)
+    })
+  }
+
+  const handleRegenerate = (e) => {
+    e.preventDefault()
+    run('POST', '/api/me/2fa/recovery-codes', (data) => setRecoveryCodes(data.recovery_codes))
+  }
+
+  const handleDisable = (e) => {
+    e.preventDefault()
+    run('DELETE', '/api/me/2fa', () => setRecoveryCodes(null))
+  }
+
+  const codeInput = (
+    <input
+      type="text"
+      className="form-control radius-8 mb-16"
+      placeholder="קוד מאפליקציית האימות"
+      value={code}
+      onChange={(e) => setCode(e.target.value)}
+      autoComplete="one-time-code"
+      required
+    />
+  )
+
+  return (
+    <>
+      <div className="card" style={{ maxWidth: '600px' }}>
+        <div className="card-body p-24">
+          <h5 className="mb-16">אימות דו-שלבי</h5>
+
+          {error && (
+            <div className="alert alert-danger mb-24" role="alert">
+              {error}
+            </div>
+          )}
+
+          {status?.required && !status?.enabled && (
+            <div className="alert alert-warning mb-24" role="alert">
+              החשבון שלך מחייב אימות דו-שלבי. יש להגדיר אותו לפני שניתן להמשיך.
+            </div>
+          )}
+
+          {recoveryCodes && (
+            <div className="alert alert-success mb-24" role="alert">
+              <p className="mb-8">שמור את קודי השחזור במקום בטוח. כל קוד ניתן לשימוש פעם אחת והם לא יוצגו שוב.</p>
+              <ul className="mb-0" dir="ltr">
+                {recoveryCodes.map((recoveryCode) => (
+                  <li key={recoveryCode}><code>{recoveryCode}</code></li>
+                ))}
+              </ul>
+            </div>
+          )}
+
+          {!status ? null : status.enabled ? (
+            <>
+              <p className="text-secondary-light mb-16">
+                אימות דו-שלבי פעיל. נותרו {status.recovery_codes_remaining} קודי שחזור.
+              </p>
+              <form onSubmit={handleRegenerate}>
+                {codeInput}
+                <div className="d-flex gap-2">
+                  <button type="submit" className="btn btn-primary text-sm btn-sm radius-8" disabled={loading}>
+                    צור קודי שחזור חדשים
+                  </button>
+                  {!status.required && (
+                    <button type="button" className="btn btn-outline-danger text-sm btn-sm radius-8" disabled={loading} onClick={handleDisable}>
+                      בטל אימות דו-שלבי
+                    </button>
+                  )}
+                </div>
+              </form>
+            </>
+          ) : enrollment ? (
+            <form onSubmit={handleConfirm}>
+              <p className="text-secondary-light mb-8">
+                הוסף את החשבון לאפליקציית האימות באמצעות הקישור או המפתח, ואז הכנס את הקוד שמוצג בה.
+              </p>
+              <p className="mb-8" dir="ltr">
+                <a href={enrollment.uri}>{enrollment.uri}</a>
+              </p>
+              <p className="mb-16" dir="ltr">
+                <code>{enrollment.secret}</code>
+              </p>
+              {codeInput}
+              <button type="submit" className="btn btn-primary text-sm btn-sm radius-8" disabled={loading}>
+                הפעל
+              </button>
+            </form>
+          ) : (
+            <button type="button" className="btn btn-primary text-sm btn-sm radius-8" disabled={loading} onClick={handleEnroll}>
+              הגדר אימות דו-שלבי
+            </button>
+          )}
+        </div>
+      </div>
+      {status && !(status.required && !status.enabled) && <LinkedAccounts />}
+    </>
+  )
+}
+
+export default Security
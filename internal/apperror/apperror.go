@@ -0,0 +1,124 @@
+// Package apperror defines the errors that handlers and middleware report to
+// API clients. Each constructor sets the HTTP status of the error, and
+// middleware.HandlerResponse writes it as an RFC 7807 problem details body.
+//
+// Only Detail, Fields and Extensions reach the client. The cause of an
+// Internal error, and any error that is not an *Error, is only logged.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gvalid"
+)
+
+// Error is an error with a message meant for the API client
+type Error struct {
+	Status     int
+	Detail     string
+	Fields     []FieldError
+	Extensions map[string]interface{}
+
+	cause error
+}
+
+// FieldError is a request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Detail + ": " + e.cause.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// With adds a member to the problem details, e.g. the seconds until a
+// lockout ends
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]interface{})
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+// BadRequest is a request that cannot be carried out as sent
+func BadRequest(detail string) *Error {
+	return &Error{Status: http.StatusBadRequest, Detail: detail}
+}
+
+// Validation is a request with invalid fields
+func Validation(detail string, fields ...FieldError) *Error {
+	return &Error{Status: http.StatusBadRequest, Detail: detail, Fields: fields}
+}
+
+// Unauthorized is a request without valid credentials
+func Unauthorized(detail string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Detail: detail}
+}
+
+// Forbidden is a request the caller is not allowed to make
+func Forbidden(detail string) *Error {
+	return &Error{Status: http.StatusForbidden, Detail: detail}
+}
+
+// NotFound is a request for a record that does not exist
+func NotFound(detail string) *Error {
+	return &Error{Status: http.StatusNotFound, Detail: detail}
+}
+
+// Conflict is a request that clashes with the current state of a record
+func Conflict(detail string) *Error {
+	return &Error{Status: http.StatusConflict, Detail: detail}
+}
+
+// TooManyRequests is a request refused by a rate limit or lockout
+func TooManyRequests(detail string) *Error {
+	return &Error{Status: http.StatusTooManyRequests, Detail: detail}
+}
+
+// Internal is a failure on our side. The client only sees detail; cause is
+// logged.
+func Internal(cause error, detail string) *Error {
+	return &Error{Status: http.StatusInternalServerError, Detail: detail, cause: cause}
+}
+
+// From returns the *Error in err's chain. Failed request validation becomes a
+// Validation error and any other error an Internal one.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var validationErr gvalid.Error
+	if errors.As(err, &validationErr) {
+		var fields []FieldError
+		for _, item := range validationErr.Items() {
+			for field, rules := range item {
+				for _, ruleErr := range rules {
+					fields = append(fields, FieldError{Field: field, Message: ruleErr.Error()})
+				}
+			}
+		}
+		return Validation(validationErr.FirstError().Error(), fields...)
+	}
+
+	switch gerror.Code(err) {
+	case gcode.CodeInvalidParameter, gcode.CodeMissingParameter, gcode.CodeInvalidRequest:
+		// Decoding errors name Go types, which clients have no use for
+		return &Error{Status: http.StatusBadRequest, Detail: "Invalid request format", cause: err}
+	}
+
+	return Internal(err, "Internal server error")
+}
@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+
+	"tzlev/internal/auth"
+	"tzlev/internal/logging"
+)
+
+// Actors recorded for entries without an authenticated user: changes made
+// outside any request, e.g. by the seeder, and requests before login
+const (
+	ActorSystem    = "system"
+	ActorAnonymous = "anonymous"
+)
+
+// Entry is a single security-relevant event or data change. Impersonator is
+// the staff member acting as Actor in an impersonation session.
+type Entry struct {
+	Actor        string                 `json:"actor"`
+	Impersonator string                 `json:"impersonator,omitempty"`
+	Action       string                 `json:"action"`
+	Entity       string                 `json:"entity"`
+	EntityID     string                 `json:"entity_id"`
+	IP           string                 `json:"ip"`
+	RequestID    string                 `json:"request_id,omitempty"`
+	Changes      map[string]Change      `json:"changes,omitempty"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+}
+
+// Change is the value of one field before and after a change. Before is nil
+// for created records and After is nil for deleted ones.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Record writes an entry to the audit_log table and the "audit" logger. Actor,
+// impersonator, IP and request ID are taken from the request in ctx when not
+// set. Failing to store an entry is logged but does not fail the audited action.
+func Record(ctx context.Context, entry Entry) {
+	if entry.Actor == "" {
+		entry.Actor = actorFromContext(ctx)
+	}
+	if entry.Impersonator == "" {
+		if identity := auth.IdentityFromContext(ctx); identity != nil && identity.IsImpersonated() {
+			entry.Impersonator = identity.Impersonator.Zehut
+		}
+	}
+	if entry.IP == "" {
+		if r := ghttp.RequestFromCtx(ctx); r != nil {
+			entry.IP = auth.ClientIP(r)
+		}
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = logging.RequestID(ctx)
+	}
+
+	g.Log("audit").Info(ctx, entry)
+
+	data := g.Map{
+		"actor":       entry.Actor,
+		"action":      entry.Action,
+		"entity":      entry.Entity,
+		"entity_id":   entry.EntityID,
+		"ip":          entry.IP,
+		"request_id":  entry.RequestID,
+		"inserted_at": time.Now(),
+	}
+	if entry.Impersonator != "" {
+		data["impersonator"] = entry.Impersonator
+	}
+	if entry.Changes != nil {
+		data["changes"] = marshal(entry.Changes)
+	}
+	if entry.Details != nil {
+		data["details"] = marshal(entry.Details)
+	}
+
+	if _, err := g.DB().Model("audit_log").Ctx(ctx).Insert(data); err != nil {
+		g.Log().Error(ctx, "Failed to store audit entry:", err)
+	}
+}
+
+// RecordChange records a create, update or delete of a record. Pass nil as
+// before for a create and nil as after for a delete. Updates that changed
+// nothing visible are not recorded.
+func RecordChange(ctx context.Context, action, entity, entityID string, before, after interface{}) {
+	changes := Diff(before, after)
+	if len(changes) == 0 {
+		return
+	}
+
+	Record(ctx, Entry{
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityID,
+		Changes:  changes,
+	})
+}
+
+// ignoredFields change on every write and would only add noise to diffs
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// Diff returns the fields that differ between two versions of a record, keyed
+// by their JSON names. Fields hidden from JSON, like password hashes, are
+// never included.
+func Diff(before, after interface{}) map[string]Change {
+	beforeFields := fields(before)
+	afterFields := fields(after)
+
+	changes := make(map[string]Change)
+	for name, value := range beforeFields {
+		if ignoredFields[name] {
+			continue
+		}
+		if other, ok := afterFields[name]; !ok || !reflect.DeepEqual(value, other) {
+			changes[name] = Change{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok && !ignoredFields[name] {
+			changes[name] = Change{After: value}
+		}
+	}
+
+	return changes
+}
+
+// fields turns a record into a map through its JSON encoding, so the diff uses
+// the same names and visibility as the API
+func fields(record interface{}) map[string]interface{} {
+	if record == nil || (reflect.ValueOf(record).Kind() == reflect.Ptr && reflect.ValueOf(record).IsNil()) {
+		return nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return map[string]interface{}{"error": fmt.Sprintf("cannot encode record: %v", err)}
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return map[string]interface{}{"error": fmt.Sprintf("cannot decode record: %v", err)}
+	}
+	return m
+}
+
+func actorFromContext(ctx context.Context) string {
+	identity := auth.IdentityFromContext(ctx)
+	switch {
+	case identity == nil && ghttp.RequestFromCtx(ctx) != nil:
+		return ActorAnonymous
+	case identity == nil:
+		return ActorSystem
+	case identity.Zehut != "":
+		return identity.Zehut
+	default:
+		// Service account API keys have no user behind them
+		return fmt.Sprintf("api_key:%d", identity.APIKeyID)
+	}
+}
+
+func marshal(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+type record struct {
+	Name      string `json:"name"`
+	Email     string `json:"email,omitempty"`
+	Count     int    `json:"count"`
+	Secret    string `json:"-"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+func TestDiff(t *testing.T) {
+	base := record{Name: "Dana", Email: "dana@example.com", Count: 1, Secret: "a", UpdatedAt: "monday"}
+
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		want   map[string]Change
+	}{
+		{
+			name:   "unchanged",
+			before: base,
+			after:  base,
+			want:   map[string]Change{},
+		},
+		{
+			name:   "changed field",
+			before: base,
+			after:  record{Name: "Dana", Email: "dana@example.com", Count: 2, Secret: "a", UpdatedAt: "monday"},
+			want:   map[string]Change{"count": {Before: float64(1), After: float64(2)}},
+		},
+		{
+			name:   "hidden and ignored fields",
+			before: base,
+			after:  record{Name: "Dana", Email: "dana@example.com", Count: 1, Secret: "b", UpdatedAt: "tuesday"},
+			want:   map[string]Change{},
+		},
+		{
+			name:   "omitted field removed",
+			before: base,
+			after:  record{Name: "Dana", Count: 1, UpdatedAt: "monday"},
+			want:   map[string]Change{"email": {Before: "dana@example.com"}},
+		},
+		{
+			name:   "omitted field added",
+			before: record{Name: "Dana", Count: 1, UpdatedAt: "monday"},
+			after:  base,
+			want:   map[string]Change{"email": {After: "dana@example.com"}},
+		},
+		{
+			name:   "created",
+			before: (*record)(nil),
+			after:  &record{Name: "Dana", UpdatedAt: "monday"},
+			want: map[string]Change{
+				"name":  {After: "Dana"},
+				"count": {After: float64(0)},
+			},
+		},
+		{
+			name:   "deleted",
+			before: &record{Name: "Dana", UpdatedAt: "monday"},
+			after:  nil,
+			want: map[string]Change{
+				"name":  {Before: "Dana"},
+				"count": {Before: float64(0)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gctx"
+)
+
+// trustedProxies are the networks of the reverse proxies in front of the
+// server, read once from security.trustedProxies
+var trustedProxies = sync.OnceValue(func() []netip.Prefix {
+	ctx := gctx.New()
+
+	var prefixes []netip.Prefix
+	for _, entry := range g.Cfg().MustGet(ctx, "security.trustedProxies").Strings() {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				g.Log().Warningf(ctx, "Ignoring invalid trusted proxy %q: %v", entry, err)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+})
+
+// ClientIP returns the address of the client that sent r, for login
+// throttling, sessions and the audit log. Clients can put anything into
+// X-Forwarded-For, so it is only read when the connection comes from one of
+// security.trustedProxies, and then from the right up to the first address
+// that is not a trusted proxy itself.
+func ClientIP(r *ghttp.Request) string {
+	ip := r.GetRemoteIp()
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trustedProxies() {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
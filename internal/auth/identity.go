@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+
+	"tzlev/internal/model"
+)
+
+// Authentication methods recorded on sessions. Logins through an external
+// identity provider record the provider's name, e.g. "google".
+const (
+	MethodPassword = "password"
+	MethodToken    = "token"
+	MethodAPIKey   = "api_key"
+)
+
+// Identity is the authenticated user behind a request. middleware.Auth puts it
+// into the request context; controllers should not read the user from anywhere else.
+type Identity struct {
+	Zehut     string `json:"zehut"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	IsAdmin   bool   `json:"is_admin"`
+	Method    string `json:"method"`
+	SessionID string `json:"-"`
+
+	// Set for sessions of users who must enroll in two-factor authentication
+	// before they can do anything else
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
+
+	// Set while support staff impersonate the user. The request acts as Zehut
+	// but is audited under both identities.
+	Impersonator *Impersonator `json:"impersonator,omitempty"`
+
+	// Set for requests made with an API key. Zehut is empty for service accounts.
+	APIKeyID       int64                            `json:"-"`
+	ServiceAccount string                           `json:"service_account,omitempty"`
+	Scopes         map[string]model.PermissionFlags `json:"-"`
+}
+
+// Impersonator is the staff member behind an impersonation session
+type Impersonator struct {
+	Zehut string `json:"zehut"`
+	Name  string `json:"name"`
+}
+
+// IsImpersonated reports whether the request comes from an impersonation session
+func (i *Identity) IsImpersonated() bool {
+	return i.Impersonator != nil
+}
+
+// IsAPIKey reports whether the request was authenticated with an API key
+func (i *Identity) IsAPIKey() bool {
+	return i.APIKeyID != 0
+}
+
+// ScopeAllows reports whether an API key's scopes permit action on resource.
+// Identities that did not authenticate with a key are not limited by scopes.
+func (i *Identity) ScopeAllows(resource string, action model.PermissionAction) bool {
+	if !i.IsAPIKey() {
+		return true
+	}
+	return i.Scopes[resource].Allows(action)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity stored in ctx, or nil for anonymous requests
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// ZehutFromContext returns the zehut of the authenticated user, or "" for anonymous requests
+func ZehutFromContext(ctx context.Context) string {
+	if identity := IdentityFromContext(ctx); identity != nil {
+		return identity.Zehut
+	}
+	return ""
+}
@@ -1,17 +1,150 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword generates a bcrypt hash from a plaintext password
+// Password hashing algorithms
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+const (
+	argon2idPrefix  = "$argon2id$"
+	argon2SaltSize  = 16
+	argon2KeyLength = 32
+)
+
+// hashParams are the settings new password hashes are made with
+type hashParams struct {
+	Algorithm   string
+	BcryptCost  int
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// currentHashParams reads security.passwordHash and security.bcryptCost
+func currentHashParams() hashParams {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	return hashParams{
+		Algorithm:   cfg.MustGet(ctx, "security.passwordHash.algorithm", AlgorithmBcrypt).String(),
+		BcryptCost:  cfg.MustGet(ctx, "security.bcryptCost", 12).Int(),
+		Memory:      cfg.MustGet(ctx, "security.passwordHash.argon2id.memory", 64*1024).Uint32(),
+		Iterations:  cfg.MustGet(ctx, "security.passwordHash.argon2id.iterations", 3).Uint32(),
+		Parallelism: cfg.MustGet(ctx, "security.passwordHash.argon2id.parallelism", 2).Uint8(),
+	}
+}
+
+// HashPassword hashes a plaintext password with the configured algorithm
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 12)
-	return string(bytes), err
+	params := currentHashParams()
+
+	switch params.Algorithm {
+	case AlgorithmBcrypt:
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), params.BcryptCost)
+		return string(bytes), err
+	case AlgorithmArgon2id:
+		return hashArgon2id(password, params)
+	default:
+		return "", fmt.Errorf("unknown password hash algorithm %q", params.Algorithm)
+	}
 }
 
-// CheckPassword compares a plaintext password with a hashed password
+// CheckPassword compares a plaintext password with a bcrypt or argon2id hash
 func CheckPassword(password, hash string) bool {
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(computed, key) == 1
+	}
+
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// NeedsRehash reports whether a hash was made with another algorithm or other
+// parameters than the configured ones. Callers rehash the password after it
+// was checked, while they still have the plaintext.
+func NeedsRehash(hash string) bool {
+	params := currentHashParams()
+
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		if params.Algorithm != AlgorithmArgon2id {
+			return true
+		}
+		current, _, _, err := decodeArgon2id(hash)
+		return err != nil ||
+			current.Memory != params.Memory ||
+			current.Iterations != params.Iterations ||
+			current.Parallelism != params.Parallelism
+	}
+
+	if params.Algorithm != AlgorithmBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != params.BcryptCost
+}
+
+// hashArgon2id encodes the hash in the PHC string format used by the reference
+// implementation: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func hashArgon2id(password string, params hashParams) (string, error) {
+	salt := make([]byte, argon2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version,
+		params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func decodeArgon2id(hash string) (hashParams, []byte, []byte, error) {
+	var params hashParams
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id key")
+	}
+
+	params.Algorithm = AlgorithmArgon2id
+	return params, salt, key, nil
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+)
+
+// bcryptMaxBytes is the longest password bcrypt can hash
+const bcryptMaxBytes = 72
+
+// PasswordPolicyError lists every rule a new password broke
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+// PasswordPolicy decides which new passwords are accepted. It is configured
+// under security.passwordPolicy.
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+
+	// Lowercased passwords from the breached-password list
+	breached map[string]struct{}
+}
+
+// configuredPolicy is loaded on first use and shared, so that the breached
+// password list is read from disk only once
+var configuredPolicy = sync.OnceValue(NewPasswordPolicy)
+
+// ConfiguredPasswordPolicy returns the policy from security.passwordPolicy
+func ConfiguredPasswordPolicy() *PasswordPolicy {
+	return configuredPolicy()
+}
+
+// NewPasswordPolicy reads security.passwordPolicy and the breached password
+// list. Use ConfiguredPasswordPolicy unless a fresh copy is needed.
+func NewPasswordPolicy() *PasswordPolicy {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	policy := &PasswordPolicy{
+		MinLength:        cfg.MustGet(ctx, "security.passwordPolicy.minLength", 8).Int(),
+		MaxLength:        cfg.MustGet(ctx, "security.passwordPolicy.maxLength", 128).Int(),
+		RequireLowercase: cfg.MustGet(ctx, "security.passwordPolicy.requireLowercase", false).Bool(),
+		RequireUppercase: cfg.MustGet(ctx, "security.passwordPolicy.requireUppercase", false).Bool(),
+		RequireDigit:     cfg.MustGet(ctx, "security.passwordPolicy.requireDigit", false).Bool(),
+		RequireSymbol:    cfg.MustGet(ctx, "security.passwordPolicy.requireSymbol", false).Bool(),
+	}
+
+	if cfg.MustGet(ctx, "security.passwordPolicy.rejectBreached", true).Bool() {
+		path := cfg.MustGet(ctx, "security.passwordPolicy.breachedPasswordsFile", "config/breached-passwords.txt").String()
+		breached, err := loadBreachedPasswords(path)
+		if err != nil {
+			g.Log().Warningf(ctx, "Breached password list not loaded, passwords are not checked against it: %v", err)
+		}
+		policy.breached = breached
+	}
+
+	return policy
+}
+
+// Validate checks a new password against the policy. It returns a
+// *PasswordPolicyError listing the rules the password broke.
+func (p *PasswordPolicy) Validate(password string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("Password must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("Password must be at most %d characters", p.MaxLength))
+	} else if len(password) > bcryptMaxBytes && currentHashParams().Algorithm == AlgorithmBcrypt {
+		violations = append(violations, fmt.Sprintf("Password must be at most %d bytes", bcryptMaxBytes))
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			hasSymbol = true
+		}
+	}
+	if p.RequireLowercase && !hasLower {
+		violations = append(violations, "Password must contain a lowercase letter")
+	}
+	if p.RequireUppercase && !hasUpper {
+		violations = append(violations, "Password must contain an uppercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "Password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "Password must contain a symbol")
+	}
+
+	if _, found := p.breached[strings.ToLower(password)]; found {
+		violations = append(violations, "Password appears in a list of breached passwords")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// loadBreachedPasswords reads a list with one password per line. Empty lines
+// and lines starting with # are skipped.
+func loadBreachedPasswords(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+
+	return breached, scanner.Err()
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// useConfig replaces the configuration for the duration of a test
+func useConfig(t *testing.T, content string) {
+	t.Helper()
+
+	adapter, err := gcfg.NewAdapterContent(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	t.Cleanup(func() {
+		g.Cfg().SetAdapter(previous)
+	})
+}
+
+// Cheap parameters keep the tests fast
+const (
+	bcryptConfig = `
+security:
+  bcryptCost: 4
+  passwordHash:
+    algorithm: bcrypt
+`
+	argon2idConfig = `
+security:
+  bcryptCost: 4
+  passwordHash:
+    algorithm: argon2id
+    argon2id:
+      memory: 1024
+      iterations: 1
+      parallelism: 1
+`
+)
+
+func TestHashAndCheckPassword(t *testing.T) {
+	for _, config := range []string{bcryptConfig, argon2idConfig} {
+		useConfig(t, config)
+
+		hash, err := HashPassword("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !CheckPassword("correct horse", hash) {
+			t.Errorf("%s: right password rejected", hash)
+		}
+		if CheckPassword("correct horsE", hash) {
+			t.Errorf("%s: wrong password accepted", hash)
+		}
+		if NeedsRehash(hash) {
+			t.Errorf("%s: fresh hash needs rehash", hash)
+		}
+	}
+}
+
+func TestDecodeArgon2id(t *testing.T) {
+	// salt "somesalt" and an arbitrary key, base64 without padding
+	const salt = "c29tZXNhbHQ"
+	const key = "a2V5a2V5a2V5a2V5"
+
+	tests := []struct {
+		name    string
+		hash    string
+		want    hashParams
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			hash: "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$" + key,
+			want: hashParams{Algorithm: AlgorithmArgon2id, Memory: 65536, Iterations: 3, Parallelism: 2},
+		},
+		{name: "old version", hash: "$argon2id$v=16$m=65536,t=3,p=2$" + salt + "$" + key, wantErr: true},
+		{name: "missing part", hash: "$argon2id$v=19$m=65536,t=3,p=2$" + salt, wantErr: true},
+		{name: "extra part", hash: "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$" + key + "$x", wantErr: true},
+		{name: "bad parameters", hash: "$argon2id$v=19$m=lots,t=3,p=2$" + salt + "$" + key, wantErr: true},
+		{name: "bad salt", hash: "$argon2id$v=19$m=65536,t=3,p=2$!!$" + key, wantErr: true},
+		{name: "empty key", hash: "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, gotSalt, gotKey, err := decodeArgon2id(tt.hash)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decodeArgon2id(%q) succeeded, want an error", tt.hash)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if params != tt.want {
+				t.Errorf("params = %+v, want %+v", params, tt.want)
+			}
+			if string(gotSalt) != "somesalt" || string(gotKey) != "keykeykeykey" {
+				t.Errorf("salt %q, key %q", gotSalt, gotKey)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordMalformedHash(t *testing.T) {
+	for _, hash := range []string{"", "plaintext", "$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ", "$2a$04$short"} {
+		if CheckPassword("", hash) {
+			t.Errorf("CheckPassword accepted malformed hash %q", hash)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	useConfig(t, bcryptConfig)
+	bcrypt4, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useConfig(t, argon2idConfig)
+	argon, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonOtherMemory := strings.Replace(argon, "m=1024", "m=2048", 1)
+
+	tests := []struct {
+		name   string
+		config string
+		hash   string
+		want   bool
+	}{
+		{"bcrypt, same cost", bcryptConfig, bcrypt4, false},
+		{"bcrypt, other cost", strings.Replace(bcryptConfig, "bcryptCost: 4", "bcryptCost: 5", 1), bcrypt4, true},
+		{"bcrypt to argon2id", argon2idConfig, bcrypt4, true},
+		{"argon2id, same parameters", argon2idConfig, argon, false},
+		{"argon2id, other memory", argon2idConfig, argonOtherMemory, true},
+		{"argon2id to bcrypt", bcryptConfig, argon, true},
+		{"malformed argon2id", argon2idConfig, "$argon2id$v=19$broken", true},
+		{"malformed bcrypt", bcryptConfig, "not a hash", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.config)
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash(%q) = %v, want %v", tt.hash, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+)
+
+// maxReturnToLength bounds return_to values, which are carried through the
+// session and the login URL
+const maxReturnToLength = 2048
+
+// SafeReturnTo validates a return_to value from a login request. Only paths on
+// this site are accepted, so a crafted login link cannot send the user to
+// another origin after logging in.
+func SafeReturnTo(raw string) (string, bool) {
+	if raw == "" || len(raw) > maxReturnToLength {
+		return "", false
+	}
+
+	// "//host" and "/\host" are protocol-relative URLs to browsers
+	if raw[0] != '/' || strings.HasPrefix(raw, "//") || strings.ContainsAny(raw, "\\") {
+		return "", false
+	}
+	for _, c := range raw {
+		if c < 0x20 || c == 0x7f {
+			return "", false
+		}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "", false
+	}
+
+	return raw, true
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSafeReturnTo(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want bool
+	}{
+		{"path", "/classrooms", true},
+		{"path with query and fragment", "/users?search=cohen#top", true},
+		{"root", "/", true},
+		{"empty", "", false},
+		{"relative path", "classrooms", false},
+		{"absolute URL", "https://evil.example/", false},
+		{"protocol-relative", "//evil.example", false},
+		{"backslash", "/\\evil.example", false},
+		{"tab", "/\t/evil.example", false},
+		{"newline", "/\n/evil.example", false},
+		{"delete", "/\x7f/evil.example", false},
+		{"javascript", "javascript:alert(1)", false},
+		{"too long", "/" + strings.Repeat("a", maxReturnToLength), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := SafeReturnTo(tt.raw)
+			if ok != tt.want {
+				t.Fatalf("SafeReturnTo(%q) ok = %v, want %v", tt.raw, ok, tt.want)
+			}
+			if ok && got != tt.raw {
+				t.Errorf("SafeReturnTo(%q) = %q, want it unchanged", tt.raw, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		at       int64
+		wantStep int64
+		wantOK   bool
+	}{
+		// The last six digits of the RFC 6238 SHA-1 vectors
+		{"vector 59", "287082", 59, 1, true},
+		{"vector 1111111109", "081804", 1111111109, 37037036, true},
+		{"vector 1234567890", "005924", 1234567890, 41152263, true},
+		{"vector 2000000000", "279037", 2000000000, 66666666, true},
+		{"previous step", "287082", 89, 1, true},
+		{"next step", "287082", 29, 1, true},
+		{"two steps late", "287082", 119, 0, false},
+		{"wrong code", "287083", 59, 0, false},
+		{"too short", "28708", 59, 0, false},
+		{"too long", "2870820", 59, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.at, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q, %d) = %d, %v; want %d, %v", tt.code, tt.at, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+// A code keeps its step while it is accepted, which is what replay protection
+// records, so it cannot be used again in the next period
+func TestValidateTOTPStepIsStable(t *testing.T) {
+	for _, at := range []int64{30, 45, 59, 60, 89} {
+		step, ok := ValidateTOTP(rfc6238Secret, "287082", time.Unix(at, 0))
+		if !ok || step != 1 {
+			t.Errorf("at %d: step %d, ok %v; want step 1", at, step, ok)
+		}
+	}
+}
+
+func TestValidateTOTPLowercaseSecret(t *testing.T) {
+	if _, ok := ValidateTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", time.Unix(59, 0)); !ok {
+		t.Error("lowercase secret was not accepted")
+	}
+}
+
+func TestValidateTOTPInvalidSecret(t *testing.T) {
+	if _, ok := ValidateTOTP("not base32!", "287082", time.Unix(59, 0)); ok {
+		t.Error("invalid secret was accepted")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != totpSecretSize {
+		t.Errorf("secret has %d bytes, want %d", len(key), totpSecretSize)
+	}
+
+	code := totpCode(key, time.Now().Unix()/int64(totpPeriod.Seconds()))
+	if _, ok := ValidateTOTP(secret, code, time.Now()); !ok {
+		t.Error("current code of a generated secret was not accepted")
+	}
+}
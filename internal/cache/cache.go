@@ -3,9 +3,13 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	goredis "github.com/redis/go-redis/v9"
+
+	"tzlev/internal/metrics"
 	"tzlev/internal/redis"
 )
 
@@ -37,6 +41,9 @@ func (cm *CacheManager) Get(ctx context.Context, cacheKey string, dest interface
 	key := cm.key(cacheKey)
 
 	data, err := redis.Client.Get(ctx, key).Bytes()
+	if err == nil || errors.Is(err, goredis.Nil) {
+		metrics.RecordCacheLookup(err == nil)
+	}
 	if err != nil {
 		return err
 	}
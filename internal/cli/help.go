@@ -16,15 +16,41 @@ USAGE:
 COMMANDS:
     migrate              Run database migrations
     seed                 Seed database with test data
+    jwtkey               Manage access token signing keys
     version              Show version information
     help                 Show this help message
 
+MIGRATE OPTIONS:
+    --action=up          Apply pending migrations (default)
+    --action=down        Roll back the latest migration
+    --action=status      List applied and pending migrations
+    --action=redo        Roll back and re-apply the latest migration
+    --action=goto        Migrate up or down to --version (0 rolls back everything)
+    --steps=N            Limit up/down to N migrations
+    --version=N          Target version for goto
+
+SEED OPTIONS:
+    --table=NAME         all (default), users, classrooms or app_resources
+    --env=NAME           Fixture set to load (defaults to app.environment)
+    --truncate           Empty the table, and with CASCADE every table referencing
+                         it, before seeding (needs --force in production)
+    --dry-run            Show what would be created or updated without writing
+
+JWTKEY OPTIONS:
+    --action=list        List keys in security.jwt.keysPath, newest first (default)
+    --action=generate    Generate a new signing key
+    --alg=NAME           EdDSA or RS256 (defaults to security.jwt.algorithm)
+
 WEB MODE:
     ./tzlev              Start web server (no arguments)
 
 EXAMPLES:
     ./tzlev migrate --action=up
+    ./tzlev migrate --action=down --steps=2
+    ./tzlev migrate --action=goto --version=20240101120000
     ./tzlev seed --table=users
+    ./tzlev seed --env=development --dry-run
+    ./tzlev jwtkey --action=generate
     ./tzlev version
 
 For web server mode, simply run without arguments:
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcmd"
+
+	"tzlev/internal/jwt"
+)
+
+func RunJWTKey(ctx context.Context, parser *gcmd.Parser) {
+	cfg := g.Cfg()
+
+	action := parser.GetOpt("action", "list").String()
+	dir := cfg.MustGet(ctx, "security.jwt.keysPath", "keys/jwt").String()
+
+	switch action {
+	case "generate":
+		algorithm := parser.GetOpt("alg", cfg.MustGet(ctx, "security.jwt.algorithm", jwt.AlgorithmEdDSA).String()).String()
+		key, err := jwt.GenerateKey(dir, algorithm)
+		if err != nil {
+			g.Log().Error(ctx, "Key generation failed:", err)
+			os.Exit(1)
+		}
+		g.Log().Infof(ctx, "Generated %s key %s in %s", key.Algorithm, key.ID, dir)
+
+	case "list":
+		keys, err := jwt.LoadKeys(dir)
+		if err != nil {
+			g.Log().Error(ctx, "Failed to load keys:", err)
+			os.Exit(1)
+		}
+		if len(keys) == 0 {
+			fmt.Printf("No keys in %s\n", dir)
+			break
+		}
+		fmt.Printf("%-30s %-6s %s\n", "KID", "ALG", "CREATED")
+		for i, key := range keys {
+			signing := ""
+			if i == 0 {
+				signing = " (signing)"
+			}
+			fmt.Printf("%-30s %-6s %s%s\n", key.ID, key.Algorithm, key.CreatedAt.Format("2006-01-02 15:04:05"), signing)
+		}
+
+	default:
+		g.Log().Errorf(ctx, "Unknown jwtkey action: %s (expected generate or list)", action)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
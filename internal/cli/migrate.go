@@ -2,20 +2,99 @@ package cli
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gcmd"
+
+	"tzlev/internal/migration"
 )
 
 func RunMigrate(ctx context.Context, parser *gcmd.Parser) {
 	action := parser.GetOpt("action", "up").String()
+	steps := parser.GetOpt("steps", 0).Int()
+	dir := g.Cfg().MustGet(ctx, "migrations.path", "migrations").String()
 
 	g.Log().Infof(ctx, "Running database migrations (%s)...", action)
 
-	// TODO: Implement migration logic using golang-migrate or custom system
-	// For now, just a placeholder
+	migrator := migration.NewMigrator(dir)
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx, steps)
+		if err != nil {
+			g.Log().Error(ctx, "Migration failed:", err)
+			os.Exit(1)
+		}
+		g.Log().Infof(ctx, "Applied %d migration(s)", len(applied))
+
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			g.Log().Error(ctx, "Rollback failed:", err)
+			os.Exit(1)
+		}
+		g.Log().Infof(ctx, "Rolled back %d migration(s)", len(reverted))
+
+	case "redo":
+		redone, err := migrator.Redo(ctx)
+		if err != nil {
+			g.Log().Error(ctx, "Redo failed:", err)
+			os.Exit(1)
+		}
+		g.Log().Infof(ctx, "Redid migration %d_%s", redone.Version, redone.Name)
+
+	case "goto":
+		if parser.GetOpt("version") == nil {
+			g.Log().Error(ctx, "The goto action requires --version")
+			os.Exit(1)
+		}
+		version := parser.GetOpt("version").Int64()
+		if err := migrator.Goto(ctx, version); err != nil {
+			g.Log().Error(ctx, "Migration failed:", err)
+			os.Exit(1)
+		}
+		g.Log().Infof(ctx, "Database is now at version %d", version)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			g.Log().Error(ctx, "Failed to read migration status:", err)
+			os.Exit(1)
+		}
+		printMigrationStatus(statuses)
+		os.Exit(0)
+
+	default:
+		g.Log().Errorf(ctx, "Unknown migrate action: %s (expected up, down, status, redo or goto)", action)
+		os.Exit(1)
+	}
 
 	g.Log().Info(ctx, "Migrations completed successfully")
 	os.Exit(0)
 }
+
+func printMigrationStatus(statuses []migration.Status) {
+	if len(statuses) == 0 {
+		fmt.Println("No migrations found")
+		return
+	}
+
+	fmt.Printf("%-16s %-40s %-10s %s\n", "VERSION", "NAME", "STATE", "APPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		appliedAt := ""
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.Modified {
+			state = "modified"
+		}
+		if s.Missing {
+			state = "missing"
+		}
+		fmt.Printf("%-16d %-40s %-10s %s\n", s.Version, s.Name, state, appliedAt)
+	}
+}
@@ -6,14 +6,51 @@ import (
 
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gcmd"
+
+	"tzlev/internal/seed"
 )
 
 func RunSeed(ctx context.Context, parser *gcmd.Parser) {
+	cfg := g.Cfg()
+
 	table := parser.GetOpt("table", "all").String()
+	env := parser.GetOpt("env", cfg.MustGet(ctx, "app.environment").String()).String()
+	dir := cfg.MustGet(ctx, "seeds.path", "seeds").String()
+
+	opts := seed.Options{
+		Env:      env,
+		Truncate: parser.GetOpt("truncate") != nil,
+		DryRun:   parser.GetOpt("dry-run") != nil,
+	}
+
+	if table == "all" {
+		opts.Tables = seed.Tables
+	} else if seed.IsTable(table) {
+		opts.Tables = []string{table}
+	} else {
+		g.Log().Errorf(ctx, "Unknown seed table: %s (expected all or one of %v)", table, seed.Tables)
+		os.Exit(1)
+	}
+
+	// Truncating real data by accident is not recoverable
+	if opts.Truncate && env == "production" && parser.GetOpt("force") == nil {
+		g.Log().Error(ctx, "Refusing to truncate in production without --force")
+		os.Exit(1)
+	}
 
-	g.Log().Infof(ctx, "Seeding database (table: %s)...", table)
+	g.Log().Infof(ctx, "Seeding database (table: %s, env: %s)...", table, env)
+	if opts.DryRun {
+		g.Log().Info(ctx, "Dry run: no changes will be written")
+	}
 
-	// TODO: Implement seeding logic
+	results, err := seed.NewSeeder(dir).Run(ctx, opts)
+	for _, result := range results {
+		g.Log().Infof(ctx, "%s: %d created, %d updated", result.Table, result.Created, result.Updated)
+	}
+	if err != nil {
+		g.Log().Error(ctx, "Seeding failed:", err)
+		os.Exit(1)
+	}
 
 	g.Log().Info(ctx, "Database seeded successfully")
 	os.Exit(0)
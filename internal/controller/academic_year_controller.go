@@ -1,13 +1,15 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"time"
 
-	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
-	"github.com/gogf/gf/v2/os/gctx"
 
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/auth"
 	"tzlev/internal/redis"
 	"tzlev/internal/repository"
 )
@@ -23,125 +25,65 @@ func NewAcademicYearController() *AcademicYearController {
 }
 
 // GetAcademicYear retrieves the user's saved academic year from Redis
-func (c *AcademicYearController) GetAcademicYear(r *ghttp.Request) {
-	ctx := gctx.New()
-
-	// Get user zehut from session
-	userZehut, _ := r.Session.Get("user_zehut")
-	if userZehut.String() == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "User not authenticated",
-		})
-		return
+func (c *AcademicYearController) GetAcademicYear(ctx context.Context, req *v1.GetAcademicYearReq) (*v1.GetAcademicYearRes, error) {
+	// Get user zehut from the authenticated identity
+	userZehut := auth.ZehutFromContext(ctx)
+	if userZehut == "" {
+		return nil, apperror.Unauthorized("User not authenticated")
 	}
 
 	// Get academic year from Redis
-	key := "tzlev:user:" + userZehut.String() + ":academic_year"
+	key := "tzlev:user:" + userZehut + ":academic_year"
 	academicYear, err := redis.Client.Get(ctx, key).Result()
 	if err != nil {
 		// If key doesn't exist, return empty
 		if err.Error() == "redis: nil" {
-			r.Response.WriteJson(g.Map{
-				"success":      true,
-				"academicYear": "",
-			})
-			return
+			return &v1.GetAcademicYearRes{}, nil
 		}
 
-		g.Log().Error(ctx, "Error getting academic year from Redis:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to retrieve academic year",
-		})
-		return
+		return nil, apperror.Internal(err, "Failed to retrieve academic year")
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success":      true,
-		"academicYear": academicYear,
-	})
+	return &v1.GetAcademicYearRes{
+		AcademicYear: academicYear,
+	}, nil
 }
 
 // SetAcademicYear saves the user's academic year to Redis
-func (c *AcademicYearController) SetAcademicYear(r *ghttp.Request) {
-	ctx := gctx.New()
-
-	// Get user zehut from session
-	userZehut, _ := r.Session.Get("user_zehut")
-	if userZehut.String() == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "User not authenticated",
-		})
-		return
-	}
-
-	// Parse request body
-	var request struct {
-		AcademicYear string `json:"academicYear"`
-	}
-
-	if err := r.Parse(&request); err != nil {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Invalid request format",
-		})
-		return
-	}
-
-	// Validate academic year
-	if request.AcademicYear == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Academic year is required",
-		})
-		return
+func (c *AcademicYearController) SetAcademicYear(ctx context.Context, req *v1.SetAcademicYearReq) (*v1.SetAcademicYearRes, error) {
+	// Get user zehut from the authenticated identity
+	userZehut := auth.ZehutFromContext(ctx)
+	if userZehut == "" {
+		return nil, apperror.Unauthorized("User not authenticated")
 	}
 
 	// Save academic year to Redis with 1 year expiration
-	key := "tzlev:user:" + userZehut.String() + ":academic_year"
-	err := redis.Client.Set(ctx, key, request.AcademicYear, 365*24*time.Hour).Err()
+	key := "tzlev:user:" + userZehut + ":academic_year"
+	err := redis.Client.Set(ctx, key, req.AcademicYear, 365*24*time.Hour).Err()
 	if err != nil {
-		g.Log().Error(ctx, "Error saving academic year to Redis:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to save academic year",
-		})
-		return
+		return nil, apperror.Internal(err, "Failed to save academic year")
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success": true,
-		"message": "Academic year saved successfully",
-	})
+	return &v1.SetAcademicYearRes{
+		Message: "Academic year saved successfully",
+	}, nil
 }
 
 // GetAcademicYearsList retrieves all available academic years from classrooms table
-func (c *AcademicYearController) GetAcademicYearsList(r *ghttp.Request) {
-	ctx := gctx.New()
-
+func (c *AcademicYearController) GetAcademicYearsList(ctx context.Context, req *v1.GetAcademicYearsReq) (*v1.GetAcademicYearsRes, error) {
 	academicYears, err := c.classroomRepo.GetDistinctAcademicYears(ctx)
 	if err != nil {
-		g.Log().Error(ctx, "Error getting academic years list:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to retrieve academic years",
-		})
-		return
+		return nil, apperror.Internal(err, "Failed to retrieve academic years")
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success":       true,
-		"academicYears": academicYears,
-	})
+	return &v1.GetAcademicYearsRes{
+		AcademicYears: academicYears,
+	}, nil
 }
 
 // GetCurrentAcademicYear retrieves the user's current academic year from Redis
 // This is a general function that can be used by other parts of the application
-func GetCurrentAcademicYear(userZehut string) (string, error) {
-	ctx := gctx.New()
-
+func GetCurrentAcademicYear(ctx context.Context, userZehut string) (string, error) {
 	if userZehut == "" {
 		return "", fmt.Errorf("user zehut is required")
 	}
@@ -160,14 +102,14 @@ func GetCurrentAcademicYear(userZehut string) (string, error) {
 	return academicYear, nil
 }
 
-// GetCurrentAcademicYearFromRequest retrieves the current academic year from the request session
+// GetCurrentAcademicYearFromRequest retrieves the current academic year of the authenticated user
 // This is a convenience function for controllers that have access to the request
 func (c *AcademicYearController) GetCurrentAcademicYearFromRequest(r *ghttp.Request) (string, error) {
-	// Get user zehut from session
-	userZehut, _ := r.Session.Get("user_zehut")
-	if userZehut.String() == "" {
+	// Get user zehut from the authenticated identity
+	userZehut := auth.ZehutFromContext(r.Context())
+	if userZehut == "" {
 		return "", fmt.Errorf("user not authenticated")
 	}
 
-	return GetCurrentAcademicYear(userZehut.String())
+	return GetCurrentAcademicYear(r.Context(), userZehut)
 }
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gogf/gf/v2/frame/g"
+
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/audit"
+	"tzlev/internal/auth"
+	"tzlev/internal/service"
+)
+
+type APIKeyController struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyController() *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: service.NewAPIKeyService(),
+	}
+}
+
+// rejectAPIKey stops API keys from managing API keys
+func rejectAPIKey(ctx context.Context) error {
+	if identity := auth.IdentityFromContext(ctx); identity != nil && identity.IsAPIKey() {
+		return apperror.Forbidden("API keys cannot manage API keys")
+	}
+	return nil
+}
+
+// GetMyAPIKeys lists the current user's API keys
+func (c *APIKeyController) GetMyAPIKeys(ctx context.Context, req *v1.GetMyAPIKeysReq) (*v1.GetMyAPIKeysRes, error) {
+	keys, err := c.apiKeyService.ListForUser(ctx, auth.ZehutFromContext(ctx))
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve API keys")
+	}
+
+	return &v1.GetMyAPIKeysRes{
+		APIKeys: keys,
+	}, nil
+}
+
+// CreateMyAPIKey creates an API key owned by the current user
+func (c *APIKeyController) CreateMyAPIKey(ctx context.Context, req *v1.CreateMyAPIKeyReq) (*v1.CreateMyAPIKeyRes, error) {
+	if err := rejectAPIKey(ctx); err != nil {
+		return nil, err
+	}
+
+	zehut := auth.ZehutFromContext(ctx)
+	return c.create(ctx, service.APIKeyRequest{
+		Name:      req.Name,
+		Zehut:     zehut,
+		ExpiresAt: req.ExpiresAt,
+		Scopes:    req.Scopes,
+		CreatedBy: zehut,
+	})
+}
+
+// RevokeMyAPIKey revokes one of the current user's API keys
+func (c *APIKeyController) RevokeMyAPIKey(ctx context.Context, req *v1.RevokeMyAPIKeyReq) (*v1.RevokeMyAPIKeyRes, error) {
+	if err := rejectAPIKey(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.revoke(ctx, req.Id, auth.ZehutFromContext(ctx))
+}
+
+// GetAPIKeys lists every API key, including service account keys
+func (c *APIKeyController) GetAPIKeys(ctx context.Context, req *v1.GetAPIKeysReq) (*v1.GetAPIKeysRes, error) {
+	keys, err := c.apiKeyService.ListAll(ctx)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve API keys")
+	}
+
+	return &v1.GetAPIKeysRes{
+		APIKeys: keys,
+	}, nil
+}
+
+// CreateAPIKey creates a key for any user or for a service account
+func (c *APIKeyController) CreateAPIKey(ctx context.Context, req *v1.CreateAPIKeyReq) (*v1.CreateAPIKeyRes, error) {
+	if err := rejectAPIKey(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.create(ctx, service.APIKeyRequest{
+		Name:           req.Name,
+		Zehut:          req.Zehut,
+		ServiceAccount: req.ServiceAccount,
+		ExpiresAt:      req.ExpiresAt,
+		Scopes:         req.Scopes,
+		CreatedBy:      auth.ZehutFromContext(ctx),
+	})
+}
+
+// RevokeAPIKey revokes any API key
+func (c *APIKeyController) RevokeAPIKey(ctx context.Context, req *v1.RevokeAPIKeyReq) (*v1.RevokeAPIKeyRes, error) {
+	if err := rejectAPIKey(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.revoke(ctx, req.Id, "")
+}
+
+func (c *APIKeyController) create(ctx context.Context, req service.APIKeyRequest) (*v1.CreateAPIKeyRes, error) {
+	plaintext, key, err := c.apiKeyService.Create(ctx, req)
+	if err != nil {
+		if errors.Is(err, service.ErrScopeNotAllowed) {
+			return nil, apperror.Forbidden("Requested scopes exceed your or the owner's permissions")
+		}
+		if errors.Is(err, service.ErrAdminKeyRequired) {
+			return nil, apperror.Forbidden("Only admins can create keys for admins or service accounts")
+		}
+		var reqErr *service.APIKeyRequestError
+		if errors.As(err, &reqErr) {
+			return nil, apperror.Validation(reqErr.Message, apperror.FieldError{Field: reqErr.Field, Message: reqErr.Message})
+		}
+		return nil, apperror.Internal(err, "Failed to create API key")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    req.CreatedBy,
+		Action:   "api_key.create",
+		Entity:   "api_key",
+		EntityID: key.Prefix,
+		Details: g.Map{
+			"zehut":           req.Zehut,
+			"service_account": req.ServiceAccount,
+			"expires_at":      key.ExpiresAt,
+		},
+	})
+
+	// The plaintext key is returned only once
+	return &v1.CreateAPIKeyRes{
+		Message: "API key created. Store it now; it cannot be shown again.",
+		Key:     plaintext,
+		APIKey:  key,
+	}, nil
+}
+
+func (c *APIKeyController) revoke(ctx context.Context, id int64, ownerZehut string) (*v1.RevokeAPIKeyRes, error) {
+	if err := c.apiKeyService.Revoke(ctx, id, ownerZehut); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			return nil, apperror.NotFound("API key not found")
+		}
+
+		return nil, apperror.Internal(err, "Failed to revoke API key")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    auth.ZehutFromContext(ctx),
+		Action:   "api_key.revoke",
+		Entity:   "api_key",
+		EntityID: strconv.FormatInt(id, 10),
+	})
+
+	return &v1.RevokeAPIKeyRes{
+		Message: "API key revoked successfully",
+	}, nil
+}
@@ -1,166 +1,111 @@
 package controller
 
 import (
+	"context"
+	"errors"
+
 	"github.com/gogf/gf/v2/frame/g"
-	"github.com/gogf/gf/v2/net/ghttp"
-	"github.com/gogf/gf/v2/os/gctx"
 
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
 	"tzlev/internal/model"
 	"tzlev/internal/repository"
+	"tzlev/internal/service"
 )
 
 type AppResourceController struct {
-	resourceRepo *repository.AppResourceRepository
+	resourceRepo      *repository.AppResourceRepository
+	permissionService *service.PermissionService
 }
 
 func NewAppResourceController() *AppResourceController {
 	return &AppResourceController{
-		resourceRepo: repository.NewAppResourceRepository(),
+		resourceRepo:      repository.NewAppResourceRepository(),
+		permissionService: service.NewPermissionService(),
 	}
 }
 
 // GetAppResources retrieves all app resources
-func (c *AppResourceController) GetAppResources(r *ghttp.Request) {
-	ctx := gctx.New()
-
+func (c *AppResourceController) GetAppResources(ctx context.Context, req *v1.GetAppResourcesReq) (*v1.GetAppResourcesRes, error) {
 	resources, err := c.resourceRepo.ListAll(ctx)
 	if err != nil {
-		g.Log().Error(ctx, "Error getting app resources:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to retrieve app resources",
-		})
-		return
+		return nil, apperror.Internal(err, "Failed to retrieve app resources")
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success":   true,
-		"resources": resources,
-	})
+	return &v1.GetAppResourcesRes{
+		Resources: resources,
+	}, nil
 }
 
 // GetAppResource retrieves a specific app resource by ID
-func (c *AppResourceController) GetAppResource(r *ghttp.Request) {
-	ctx := gctx.New()
-
-	id := r.Get("id").String()
-	if id == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Resource ID is required",
-		})
-		return
-	}
-
-	resource, err := c.resourceRepo.FindByID(ctx, id)
+func (c *AppResourceController) GetAppResource(ctx context.Context, req *v1.GetAppResourceReq) (*v1.GetAppResourceRes, error) {
+	resource, err := c.resourceRepo.FindByID(ctx, req.Id)
 	if err != nil {
 		g.Log().Error(ctx, "Error getting app resource:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Resource not found",
-		})
-		return
+		return nil, apperror.NotFound("Resource not found")
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success":  true,
-		"resource": resource,
-	})
+	return &v1.GetAppResourceRes{
+		Resource: resource,
+	}, nil
 }
 
 // CreateAppResource creates a new app resource
-func (c *AppResourceController) CreateAppResource(r *ghttp.Request) {
-	ctx := gctx.New()
-
-	var resource model.AppResource
-	if err := r.Parse(&resource); err != nil {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Invalid request format",
-		})
-		return
+func (c *AppResourceController) CreateAppResource(ctx context.Context, req *v1.CreateAppResourceReq) (*v1.CreateAppResourceRes, error) {
+	resource := model.AppResource{
+		Id:          req.Id,
+		Name:        req.Name,
+		Description: req.Description,
 	}
 
 	if err := c.resourceRepo.Create(ctx, &resource); err != nil {
-		g.Log().Error(ctx, "Error creating app resource:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"error":   err.Error(),
-		})
-		return
+		if errors.Is(err, repository.ErrAppResourceNameTaken) {
+			return nil, apperror.Conflict("A resource with this name already exists")
+		}
+		return nil, apperror.Internal(err, "Failed to create app resource")
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success":  true,
-		"message":  "App resource created successfully",
-		"resource": resource,
-	})
+	return &v1.CreateAppResourceRes{
+		Message:  "App resource created successfully",
+		Resource: &resource,
+	}, nil
 }
 
 // UpdateAppResource updates an existing app resource
-func (c *AppResourceController) UpdateAppResource(r *ghttp.Request) {
-	ctx := gctx.New()
-
-	id := r.Get("id").String()
-	if id == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Resource ID is required",
-		})
-		return
+func (c *AppResourceController) UpdateAppResource(ctx context.Context, req *v1.UpdateAppResourceReq) (*v1.UpdateAppResourceRes, error) {
+	resource := model.AppResource{
+		Id:          req.Id,
+		Name:        req.Name,
+		Description: req.Description,
 	}
 
-	var resource model.AppResource
-	if err := r.Parse(&resource); err != nil {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Invalid request format",
-		})
-		return
+	if err := c.resourceRepo.Update(ctx, &resource); err != nil {
+		return nil, apperror.Internal(err, "Failed to update app resource")
 	}
 
-	resource.Id = id
-
-	if err := c.resourceRepo.Update(ctx, &resource); err != nil {
-		g.Log().Error(ctx, "Error updating app resource:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to update app resource",
-		})
-		return
+	// Effective permissions are keyed by resource name, which may have changed
+	if err := c.permissionService.InvalidateAll(ctx); err != nil {
+		g.Log().Warning(ctx, "Failed to invalidate permission cache:", err)
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success":  true,
-		"message":  "App resource updated successfully",
-		"resource": resource,
-	})
+	return &v1.UpdateAppResourceRes{
+		Message:  "App resource updated successfully",
+		Resource: &resource,
+	}, nil
 }
 
 // DeleteAppResource deletes an app resource
-func (c *AppResourceController) DeleteAppResource(r *ghttp.Request) {
-	ctx := gctx.New()
-
-	id := r.Get("id").String()
-	if id == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Resource ID is required",
-		})
-		return
+func (c *AppResourceController) DeleteAppResource(ctx context.Context, req *v1.DeleteAppResourceReq) (*v1.DeleteAppResourceRes, error) {
+	if err := c.resourceRepo.Delete(ctx, req.Id); err != nil {
+		return nil, apperror.Internal(err, "Failed to delete app resource")
 	}
 
-	if err := c.resourceRepo.Delete(ctx, id); err != nil {
-		g.Log().Error(ctx, "Error deleting app resource:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to delete app resource",
-		})
-		return
+	// Permissions on the resource were removed with it
+	if err := c.permissionService.InvalidateAll(ctx); err != nil {
+		g.Log().Warning(ctx, "Failed to invalidate permission cache:", err)
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success": true,
-		"message": "App resource deleted successfully",
-	})
+	return &v1.DeleteAppResourceRes{
+		Message: "App resource deleted successfully",
+	}, nil
 }
@@ -1,245 +1,768 @@
 package controller
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/audit"
 	"tzlev/internal/auth"
+	"tzlev/internal/jwt"
+	"tzlev/internal/metrics"
+	"tzlev/internal/middleware"
+	"tzlev/internal/model"
 	"tzlev/internal/oauth"
+	"tzlev/internal/ratelimit"
 	"tzlev/internal/repository"
+	"tzlev/internal/service"
 	"tzlev/internal/session"
 )
 
+// oauthLoginKey is the cookie session key holding a provider login between
+// the redirect and the callback
+const oauthLoginKey = "oauth_login"
+
+// mfaChallengeKey is the cookie session key holding a browser login that waits
+// for its second factor
+const mfaChallengeKey = "mfa_challenge"
+
+// Error codes provider logins and links redirect back to the SPA with. The
+// login and security pages translate them.
+const (
+	providerErrUnknown          = "unknown_provider"
+	providerErrUnavailable      = "login_unavailable"
+	providerErrInvalidState     = "invalid_state"
+	providerErrCancelled        = "login_cancelled"
+	providerErrVerification     = "verification_failed"
+	providerErrNotAuthorized    = "not_authorized"
+	providerErrAccountFrozen    = "account_frozen"
+	providerErrNotAuthenticated = "not_authenticated"
+	providerErrLinkedToOther    = "identity_linked_to_other"
+	providerErrLinkFailed       = "link_failed"
+)
+
 type AuthController struct {
-	userRepo       *repository.UserRepository
-	sessionManager *session.SessionManager
+	userRepo         *repository.UserRepository
+	userService      *service.UserService
+	sessionService   *service.SessionService
+	tokenService     *service.TokenService
+	twoFactorService *service.TwoFactorService
+	identityService  *service.UserIdentityService
+	loginLimiter     *ratelimit.LoginLimiter
 }
 
 func NewAuthController() *AuthController {
 	return &AuthController{
-		userRepo:       repository.NewUserRepository(),
-		sessionManager: session.NewSessionManager(),
+		userRepo:         repository.NewUserRepository(),
+		userService:      service.NewUserService(),
+		sessionService:   service.NewSessionService(),
+		tokenService:     service.NewTokenService(),
+		twoFactorService: service.NewTwoFactorService(),
+		identityService:  service.NewUserIdentityService(),
+		loginLimiter:     ratelimit.NewLoginLimiter(),
 	}
 }
 
 // Login handles Zehut/Password authentication
-func (c *AuthController) Login(r *ghttp.Request) {
+func (c *AuthController) Login(ctx context.Context, req *v1.LoginReq) (*v1.LoginRes, error) {
+	r := g.RequestFromCtx(ctx)
+
+	user, err := c.authenticatePassword(r, auth.MethodPassword, req.Zehut, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := c.holdForSecondFactor(r, user, auth.MethodPassword)
+	if err != nil {
+		return nil, apperror.Internal(err, "Login is temporarily unavailable")
+	}
+	if pending {
+		return &v1.LoginRes{
+			Status:  "mfa_required",
+			Message: "Enter the code from your authenticator app",
+		}, nil
+	}
+
+	c.resetLoginFailures(ctx, user.Zehut)
+
+	if err := c.establishSession(r, user, auth.MethodPassword); err != nil {
+		return nil, err
+	}
+
+	return &v1.LoginRes{
+		Status:  "ok",
+		Message: "Login successful",
+	}, nil
+}
+
+// VerifyTwoFactor completes a browser login that is waiting for its second
+// factor. The body carries a TOTP code or a recovery code.
+func (c *AuthController) VerifyTwoFactor(ctx context.Context, req *v1.VerifyTwoFactorReq) (*v1.VerifyTwoFactorRes, error) {
+	r := g.RequestFromCtx(ctx)
+
+	token := r.Session.MustGet(mfaChallengeKey).String()
+	if token == "" {
+		return nil, apperror.Unauthorized("No login is waiting for a code. Please sign in again.")
+	}
+
+	result, err := c.completeChallenge(r, token, session.ChallengeSession, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Session.Remove(mfaChallengeKey)
+
+	if err := c.establishSession(r, result.User, result.Method); err != nil {
+		return nil, err
+	}
+
+	return &v1.VerifyTwoFactorRes{
+		Status:  "ok",
+		Message: "Login successful",
+	}, nil
+}
+
+// IssueToken issues bearer tokens to API clients. It supports the "password"
+// grant (zehut and password), the "mfa" grant that completes a password grant
+// for users with 2FA, and the "refresh_token" grant.
+func (c *AuthController) IssueToken(ctx context.Context, req *v1.IssueTokenReq) (*v1.IssueTokenRes, error) {
+	r := g.RequestFromCtx(ctx)
+
+	// Token responses must not be cached
+	r.Response.Header().Set("Cache-Control", "no-store")
+
+	var pair *service.TokenPair
+	var err error
+	// Set for the grants that log a user in, as opposed to refreshing
+	var loggedIn *model.User
+
+	switch req.GrantType {
+	case "password":
+		user, err := c.authenticatePassword(r, auth.MethodToken, req.Zehut, req.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		mfaToken, err := c.tokenSecondFactor(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if mfaToken != "" {
+			return &v1.IssueTokenRes{
+				Status:   "mfa_required",
+				MFAToken: mfaToken,
+			}, nil
+		}
+
+		c.resetLoginFailures(ctx, user.Zehut)
+		loggedIn = user
+
+	case "mfa":
+		result, err := c.completeChallenge(r, req.MFAToken, session.ChallengeToken, req.Code)
+		if err != nil {
+			return nil, err
+		}
+		loggedIn = result.User
+
+	case "refresh_token":
+		pair, err = c.tokenService.Refresh(ctx, req.RefreshToken)
+
+	default:
+		return nil, apperror.BadRequest("Unsupported grant type").With("error", "unsupported_grant_type")
+	}
+
+	if loggedIn != nil {
+		pair, err = c.tokenService.Issue(ctx, loggedIn)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAccountFrozen):
+			return nil, apperror.Forbidden("Account is frozen. Please contact administrator.")
+		case errors.Is(err, session.ErrInvalidRefreshToken), errors.Is(err, session.ErrRefreshTokenReused):
+			return nil, apperror.Unauthorized("Invalid or expired refresh token")
+		default:
+			return nil, apperror.Internal(err, "Failed to issue token")
+		}
+	}
+
+	if loggedIn != nil {
+		recordLogin(r, loggedIn, auth.MethodToken)
+	}
+
+	return &v1.IssueTokenRes{
+		AccessToken:  pair.AccessToken,
+		TokenType:    pair.TokenType,
+		ExpiresIn:    pair.ExpiresIn,
+		RefreshToken: pair.RefreshToken,
+	}, nil
+}
+
+// RevokeToken revokes a refresh token together with every token issued from
+// the same login
+func (c *AuthController) RevokeToken(ctx context.Context, req *v1.RevokeTokenReq) (*v1.RevokeTokenRes, error) {
+	// Unknown tokens are treated as already revoked
+	if err := c.tokenService.Revoke(ctx, req.RefreshToken); err != nil && !errors.Is(err, session.ErrInvalidRefreshToken) {
+		return nil, apperror.Internal(err, "Failed to revoke token")
+	}
+
+	return &v1.RevokeTokenRes{
+		Message: "Token revoked",
+	}, nil
+}
+
+// JWKS publishes the public keys used to sign access tokens
+func (c *AuthController) JWKS(r *ghttp.Request) {
+	r.Response.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(jwt.JWKSMaxAge.Seconds())))
+	r.Response.WriteJson(g.Map{
+		"keys": jwt.JWKS(),
+	})
+}
+
+// authenticatePassword checks a zehut and password subject to login throttling.
+// method is the kind of login the password is for.
+func (c *AuthController) authenticatePassword(r *ghttp.Request, method, zehut, password string) (*model.User, error) {
 	ctx := r.Context()
 
-	type LoginRequest struct {
-		Zehut    string `json:"zehut" v:"required"`
-		Password string `json:"password" v:"required"`
+	ip := auth.ClientIP(r)
+
+	// Count the attempt before checking anything, so that concurrent guesses
+	// cannot get past the limits. It is refused while the zehut or the client IP
+	// is locked out or has used up its attempts.
+	attempt, lockout, err := c.loginLimiter.Begin(ctx, zehut, ip)
+	if err != nil {
+		return nil, apperror.Internal(err, "Login is temporarily unavailable")
+	}
+	if lockout != nil {
+		g.Log().Warningf(ctx, "Login attempt while locked out (%s %s)", lockout.Subject, lockout.Value)
+		return nil, lockedOut(r, lockout.RetryAfter)
 	}
 
-	var req LoginRequest
-	if err := r.Parse(&req); err != nil {
-		r.Response.WriteJson(g.Map{
-			"error": "Invalid request",
-		})
-		return
+	// Slow down repeated failures for the same zehut
+	if delay := c.loginLimiter.Delay(attempt); delay > 0 && !sleepContext(ctx, delay) {
+		c.releaseAttempt(ctx, attempt)
+		return nil, apperror.TooManyRequests("Login attempt cancelled")
 	}
 
 	// Find user by zehut
-	user, err := c.userRepo.FindByZehut(ctx, req.Zehut)
+	user, err := c.userRepo.FindByZehut(ctx, zehut)
 	if err != nil {
-		g.Log().Warning(ctx, "User not found:", req.Zehut)
-		r.Response.WriteJson(g.Map{
-			"error": "Invalid credentials",
-		})
-		return
+		g.Log().Warning(ctx, "User not found:", zehut)
+		c.recordLoginFailure(ctx, attempt, method, zehut, ip)
+		return nil, apperror.Unauthorized("Invalid credentials")
 	}
 
 	// Verify password
-	if !auth.CheckPassword(req.Password, user.HashedPassword) {
-		g.Log().Warning(ctx, "Invalid password for user:", req.Zehut)
-		r.Response.WriteJson(g.Map{
-			"error": "Invalid credentials",
+	if !auth.CheckPassword(password, user.HashedPassword) {
+		g.Log().Warning(ctx, "Invalid password for user:", zehut)
+		c.recordLoginFailure(ctx, attempt, method, zehut, ip)
+		return nil, apperror.Unauthorized("Invalid credentials")
+	}
+
+	c.releaseAttempt(ctx, attempt)
+
+	// Move the hash to the configured algorithm and cost while we have the password
+	if err := c.userService.UpgradePasswordHash(ctx, user, password); err != nil {
+		g.Log().Warning(ctx, "Failed to upgrade password hash:", err)
+	}
+
+	return user, nil
+}
+
+// resetLoginFailures clears the failure count of a zehut once every factor of a
+// login was verified. It is not done after the password alone, so that wrong
+// two-factor codes keep counting towards the lockout.
+func (c *AuthController) resetLoginFailures(ctx context.Context, zehut string) {
+	if err := c.loginLimiter.Reset(ctx, zehut); err != nil {
+		g.Log().Error(ctx, "Failed to reset login failures:", err)
+	}
+}
+
+// holdForSecondFactor starts a two-factor challenge for a browser login if the
+// user has 2FA enabled, remembering it on the cookie session. It reports
+// whether the login now waits for a code.
+func (c *AuthController) holdForSecondFactor(r *ghttp.Request, user *model.User, method string) (bool, error) {
+	ctx := r.Context()
+
+	enabled, err := c.twoFactorService.IsEnabled(ctx, user.Zehut)
+	if err != nil || !enabled {
+		return false, err
+	}
+
+	token, err := c.twoFactorService.StartChallenge(ctx, user, method, session.ChallengeSession)
+	if err != nil {
+		return false, err
+	}
+
+	r.Session.Set(mfaChallengeKey, token)
+	return true, nil
+}
+
+// tokenSecondFactor handles 2FA for the password grant. Users with 2FA get an
+// mfa_token to complete with the "mfa" grant; users who must enroll first are
+// refused. It returns an empty token if no second factor is needed.
+func (c *AuthController) tokenSecondFactor(ctx context.Context, user *model.User) (string, error) {
+	enabled, err := c.twoFactorService.IsEnabled(ctx, user.Zehut)
+	if err != nil {
+		return "", apperror.Internal(err, "Failed to issue token")
+	}
+
+	if enabled {
+		token, err := c.twoFactorService.StartChallenge(ctx, user, auth.MethodToken, session.ChallengeToken)
+		if err != nil {
+			return "", apperror.Internal(err, "Failed to issue token")
+		}
+		return token, nil
+	}
+
+	// Tokens would bypass the enrollment restriction on sessions
+	if c.twoFactorService.IsRequired(user) {
+		return "", apperror.Forbidden("Two-factor authentication must be set up before requesting tokens")
+	}
+
+	return "", nil
+}
+
+// completeChallenge verifies the second factor of a pending login. Wrong codes
+// count as failed logins.
+func (c *AuthController) completeChallenge(r *ghttp.Request, token, purpose, code string) (*service.ChallengeResult, error) {
+	ctx := r.Context()
+
+	ip := auth.ClientIP(r)
+
+	challenge, err := c.twoFactorService.AttemptChallenge(ctx, token, purpose)
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidChallenge) {
+			r.Session.Remove(mfaChallengeKey)
+			return nil, apperror.Unauthorized("The login has expired. Please sign in again.")
+		}
+
+		return nil, apperror.Internal(err, "Login is temporarily unavailable")
+	}
+
+	attempt, lockout, err := c.loginLimiter.Begin(ctx, challenge.Zehut, ip)
+	if err != nil {
+		return nil, apperror.Internal(err, "Login is temporarily unavailable")
+	}
+	if lockout != nil {
+		return nil, lockedOut(r, lockout.RetryAfter)
+	}
+
+	result, err := c.twoFactorService.CompleteChallenge(ctx, token, challenge, code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			g.Log().Warning(ctx, "Invalid two-factor code for user:", challenge.Zehut)
+			c.recordLoginFailure(ctx, attempt, challenge.Method, challenge.Zehut, ip)
+			return nil, apperror.Unauthorized("Invalid verification code")
+		}
+
+		c.releaseAttempt(ctx, attempt)
+		return nil, apperror.Internal(err, "Login is temporarily unavailable")
+	}
+
+	if result.RecoveryCodeUsed {
+		audit.Record(ctx, audit.Entry{
+			Actor:    result.User.Zehut,
+			Action:   "mfa.recovery_code_used",
+			Entity:   "user",
+			EntityID: result.User.Zehut,
+			IP:       ip,
 		})
+	}
+
+	c.resetLoginFailures(ctx, result.User.Zehut)
+
+	return result, nil
+}
+
+// establishSession logs user in through the session service
+func (c *AuthController) establishSession(r *ghttp.Request, user *model.User, method string) error {
+	ctx := r.Context()
+
+	if _, err := c.sessionService.Establish(r, user, method); err != nil {
+		if errors.Is(err, service.ErrAccountFrozen) {
+			g.Log().Warningf(ctx, "%s login attempt for frozen user: %s", method, user.Zehut)
+			return apperror.Forbidden("Account is frozen. Please contact administrator.")
+		}
+
+		return apperror.Internal(err, "Failed to create session")
+	}
+
+	recordLogin(r, user, method)
+	return nil
+}
+
+// recordLogin audits a completed login: who, how and from where
+func recordLogin(r *ghttp.Request, user *model.User, method string) {
+	metrics.RecordLogin(method, true)
+	audit.Record(r.Context(), audit.Entry{
+		Actor:    user.Zehut,
+		Action:   "login.success",
+		Entity:   "user",
+		EntityID: user.Zehut,
+		IP:       auth.ClientIP(r),
+		Details: g.Map{
+			"method":     method,
+			"user_agent": r.UserAgent(),
+		},
+	})
+}
+
+// recordLoginFailure keeps a failed attempt counted and audits it, along with
+// any lockout it triggers
+func (c *AuthController) recordLoginFailure(ctx context.Context, attempt *ratelimit.Attempt, method, zehut, ip string) {
+	metrics.RecordLogin(method, false)
+	audit.Record(ctx, audit.Entry{
+		Action:   "login.failure",
+		Entity:   "user",
+		EntityID: zehut,
+		IP:       ip,
+	})
+
+	lockouts, err := c.loginLimiter.RecordFailure(ctx, attempt)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to record login failure:", err)
 		return
 	}
 
-	// Create session
-	sessionID, _ := r.Session.Id()
-	fullName := user.FirstName + " " + user.LastName
-	sess := &session.Session{
-		UserID:    0, // Legacy field
-		Zehut:     user.Zehut,
-		Email:     user.Email,
-		Name:      fullName,
-		CreatedAt: time.Now(),
+	for _, lockout := range lockouts {
+		g.Log().Warningf(ctx, "Login locked out for %s %s", lockout.Subject, lockout.Value)
+		audit.Record(ctx, audit.Entry{
+			Action:   "login.lockout",
+			Entity:   lockout.Subject,
+			EntityID: lockout.Value,
+			IP:       ip,
+			Details: g.Map{
+				"zehut":    zehut,
+				"duration": lockout.RetryAfter.String(),
+			},
+		})
+	}
+}
+
+// releaseAttempt takes back a login attempt that did not fail
+func (c *AuthController) releaseAttempt(ctx context.Context, attempt *ratelimit.Attempt) {
+	if err := c.loginLimiter.Release(ctx, attempt); err != nil {
+		g.Log().Error(ctx, "Failed to release login attempt:", err)
 	}
+}
 
-	if err := c.sessionManager.Create(ctx, sessionID, sess); err != nil {
-		g.Log().Error(ctx, "Failed to create session:", err)
-		r.Response.WriteJson(g.Map{
-			"error": "Failed to create session",
+// lockedOut sets Retry-After and returns the 429 error for a locked out login
+func lockedOut(r *ghttp.Request, retryAfter time.Duration) error {
+	return tooManyRequests(r, retryAfter, "Too many failed login attempts. Please try again later.")
+}
+
+// tooManyRequests sets Retry-After and returns a 429 error that also carries
+// the seconds to wait
+func tooManyRequests(r *ghttp.Request, retryAfter time.Duration, detail string) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	r.Response.Header().Set("Retry-After", strconv.Itoa(seconds))
+	return apperror.TooManyRequests(detail).With("retry_after", seconds)
+}
+
+// sleepContext waits for d and reports false if the request was cancelled first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// GetProviders lists the external identity providers shown on the login page
+func (c *AuthController) GetProviders(ctx context.Context, req *v1.GetProvidersReq) (*v1.GetProvidersRes, error) {
+	providers := oauth.Providers()
+
+	list := make([]v1.LoginProvider, 0, len(providers))
+	for _, provider := range providers {
+		list = append(list, v1.LoginProvider{
+			Name:        provider.Name,
+			DisplayName: provider.DisplayName,
 		})
-		return
 	}
 
-	// Set session cookie
-	r.Session.Set("user_zehut", user.Zehut)
-	r.Session.Set("user_email", user.Email)
-	r.Session.Set("user_name", fullName)
+	return &v1.GetProvidersRes{
+		Providers: list,
+	}, nil
+}
 
-	r.Response.WriteJson(g.Map{
-		"status": "ok",
-		"message": "Login successful",
-	})
+// ProviderLogin redirects to an OpenID Connect provider to log in. A valid
+// return_to path is where the user lands after the login.
+func (c *AuthController) ProviderLogin(r *ghttp.Request) {
+	extra := g.Map{"intent": "login"}
+	if returnTo, ok := auth.SafeReturnTo(r.Get("return_to").String()); ok {
+		extra["return_to"] = returnTo
+	}
+
+	c.startProviderLogin(r, extra)
 }
 
-func (c *AuthController) GoogleLogin(r *ghttp.Request) {
-	// Generate random state for CSRF protection
-	b := make([]byte, 32)
-	rand.Read(b)
-	state := base64.URLEncoding.EncodeToString(b)
+// LinkProvider redirects the logged-in user to an OpenID Connect provider to
+// link their account there. The provider's callback completes the link.
+func (c *AuthController) LinkProvider(r *ghttp.Request) {
+	identity := auth.IdentityFromContext(r.Context())
+	if identity == nil || identity.SessionID == "" {
+		middleware.WriteError(r, apperror.Forbidden("Accounts can only be linked from a browser session"))
+		return
+	}
+
+	c.startProviderLogin(r, g.Map{"intent": "link", "zehut": identity.Zehut})
+}
+
+// startProviderLogin redirects to the provider named in the route. The state,
+// nonce and PKCE verifier are kept in the cookie session with extra until the callback.
+func (c *AuthController) startProviderLogin(r *ghttp.Request, extra g.Map) {
+	ctx := r.Context()
+	intent, _ := extra["intent"].(string)
+
+	provider, ok := oauth.Get(r.Get("provider").String())
+	if !ok {
+		redirectProviderError(r, intent, providerErrUnknown)
+		return
+	}
+
+	login, err := oauth.NewLoginRequest()
+	if err != nil {
+		g.Log().Error(ctx, "Failed to start provider login:", err)
+		redirectProviderError(r, intent, providerErrUnavailable)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, login)
+	if err != nil {
+		g.Log().Errorf(ctx, "Failed to reach login provider %s: %v", provider.Name, err)
+		redirectProviderError(r, intent, providerErrUnavailable)
+		return
+	}
 
-	// Store state in session
-	r.Session.Set("oauth_state", state)
+	stored := g.Map{
+		"provider": provider.Name,
+		"state":    login.State,
+		"nonce":    login.Nonce,
+		"verifier": login.Verifier,
+	}
+	for key, value := range extra {
+		stored[key] = value
+	}
+	r.Session.Set(oauthLoginKey, stored)
 
-	// Redirect to Google OAuth
-	url := oauth.GoogleOAuthConfig.AuthCodeURL(state)
-	r.Response.RedirectTo(url)
+	r.Response.RedirectTo(authURL)
 }
 
-func (c *AuthController) GoogleCallback(r *ghttp.Request) {
+// ProviderCallback completes a login with, or a link to, an OpenID Connect
+// provider. Logins are resolved to users by the provider's subject ID. The
+// browser arrives here by redirect, so failures redirect back to the SPA too.
+func (c *AuthController) ProviderCallback(r *ghttp.Request) {
 	ctx := r.Context()
 
-	// Verify state
+	// The callback must belong to the login this browser started with this provider
+	stored := r.Session.MustGet(oauthLoginKey).MapStrStr()
+	r.Session.Remove(oauthLoginKey)
+	intent := stored["intent"]
+
+	provider, ok := oauth.Get(r.Get("provider").String())
+	if !ok {
+		redirectProviderError(r, intent, providerErrUnknown)
+		return
+	}
+
 	state := r.Get("state").String()
-	sessionState, _ := r.Session.Get("oauth_state")
-	sessionStateStr := sessionState.String()
+	if state == "" || stored["provider"] != provider.Name || state != stored["state"] {
+		redirectProviderError(r, intent, providerErrInvalidState)
+		return
+	}
 
-	if state == "" || state != sessionStateStr {
-		r.Response.WriteJson(g.Map{
-			"error": "Invalid state parameter",
-		})
+	if errCode := r.Get("error").String(); errCode != "" {
+		g.Log().Warningf(ctx, "Login provider %s returned an error: %s", provider.Name, errCode)
+		redirectProviderError(r, intent, providerErrCancelled)
 		return
 	}
 
-	// Exchange code for token
-	code := r.Get("code").String()
-	token, err := oauth.GoogleOAuthConfig.Exchange(ctx, code)
+	identity, err := provider.Exchange(ctx, r.Get("code").String(), &oauth.LoginRequest{
+		State:    stored["state"],
+		Nonce:    stored["nonce"],
+		Verifier: stored["verifier"],
+	})
 	if err != nil {
-		g.Log().Error(ctx, "Failed to exchange token:", err)
-		r.Response.WriteJson(g.Map{
-			"error": "Failed to exchange token",
-		})
+		g.Log().Errorf(ctx, "Login with provider %s failed: %v", provider.Name, err)
+		metrics.RecordLogin(provider.Name, false)
+		redirectProviderError(r, intent, providerErrVerification)
 		return
 	}
 
-	// Get user info from Google
-	googleUser, err := oauth.GetGoogleUserInfo(ctx, token)
-	if err != nil {
-		g.Log().Error(ctx, "Failed to get user info:", err)
-		r.Response.WriteJson(g.Map{
-			"error": "Failed to get user info",
-		})
+	if intent == "link" {
+		c.linkIdentity(r, provider, identity, stored["zehut"])
 		return
 	}
 
-	// Find user by email (match against existing users)
-	user, err := c.userRepo.FindByEmail(ctx, googleUser.Email)
+	user, err := c.identityService.ResolveLogin(ctx, provider.Name, identity)
 	if err != nil {
-		// User doesn't exist - this means they're not authorized
-		// In a real system, you might want to auto-create users or show a registration page
-		g.Log().Warning(ctx, "User not found in system:", googleUser.Email)
-		r.Response.WriteJson(g.Map{
-			"error": "User not authorized. Please contact administrator.",
-		})
+		if errors.Is(err, service.ErrIdentityNotLinked) || errors.Is(err, sql.ErrNoRows) {
+			// User doesn't exist - this means they're not authorized
+			g.Log().Warningf(ctx, "No user for %s identity %s (%s)", provider.Name, identity.Subject, identity.Email)
+			metrics.RecordLogin(provider.Name, false)
+			redirectProviderError(r, intent, providerErrNotAuthorized)
+			return
+		}
+
+		g.Log().Error(ctx, "Failed to resolve provider login:", err)
+		redirectProviderError(r, intent, providerErrUnavailable)
 		return
 	}
 
 	// User exists, update their confirmed_at if not set
 	if user.ConfirmedAt == nil {
-		now := time.Now()
-		user.ConfirmedAt = &now
-		user.Avatar = googleUser.Picture // Update avatar from Google
-		if err := c.userRepo.Update(ctx, user); err != nil {
+		if err := c.userService.ConfirmUser(ctx, user, identity.Picture); err != nil {
 			g.Log().Error(ctx, "Failed to update user:", err)
 		}
 	}
 
-	// Create session
-	sessionID, _ := r.Session.Id()
-	fullName := user.FirstName + " " + user.LastName
-	sess := &session.Session{
-		UserID: 0, // We'll use zehut instead
-		Email:  user.Email,
-		Name:   fullName,
+	returnTo, ok := auth.SafeReturnTo(stored["return_to"])
+	if !ok {
+		returnTo = "/"
+	}
+
+	pending, err := c.holdForSecondFactor(r, user, provider.Name)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to start two-factor challenge:", err)
+		redirectProviderError(r, intent, providerErrUnavailable)
+		return
+	}
+	if pending {
+		// The login page asks for the code and then continues to return_to
+		r.Response.RedirectTo("/login?" + url.Values{"mfa": {"1"}, "return_to": {returnTo}}.Encode())
+		return
 	}
 
-	if err := c.sessionManager.Create(ctx, sessionID, sess); err != nil {
+	if _, err := c.sessionService.Establish(r, user, provider.Name); err != nil {
+		if errors.Is(err, service.ErrAccountFrozen) {
+			g.Log().Warningf(ctx, "%s login attempt for frozen user: %s", provider.Name, user.Zehut)
+			redirectProviderError(r, intent, providerErrAccountFrozen)
+			return
+		}
+
 		g.Log().Error(ctx, "Failed to create session:", err)
-		r.Response.WriteJson(g.Map{
-			"error": "Failed to create session",
-		})
+		redirectProviderError(r, intent, providerErrUnavailable)
 		return
 	}
 
-	// Set session cookie
-	r.Session.Set("user_zehut", user.Zehut)
-	r.Session.Set("user_email", user.Email)
-	r.Session.Set("user_name", fullName)
+	recordLogin(r, user, provider.Name)
+	r.Response.RedirectTo(returnTo)
+}
+
+// redirectProviderError sends the browser back to the page the provider flow
+// started from, with an error code for it to show
+func redirectProviderError(r *ghttp.Request, intent, code string) {
+	page := "/login"
+	if intent == "link" {
+		page = "/security"
+	}
 
-	// Redirect to home page
-	r.Response.RedirectTo("/")
+	r.Response.RedirectTo(page + "?" + url.Values{"error": {code}}.Encode())
 }
 
-func (c *AuthController) Logout(r *ghttp.Request) {
+// linkIdentity completes a link started by LinkProvider. The browser must still
+// be logged in as the user who started it.
+func (c *AuthController) linkIdentity(r *ghttp.Request, provider *oauth.Provider, identity *oauth.Identity, zehut string) {
 	ctx := r.Context()
 
-	// Delete session from Redis
 	sessionID, _ := r.Session.Id()
-	if err := c.sessionManager.Delete(ctx, sessionID); err != nil {
-		g.Log().Error(ctx, "Failed to delete session:", err)
+	current, err := c.sessionService.Resolve(ctx, sessionID, auth.ClientIP(r))
+	if err != nil || zehut == "" || current.Zehut != zehut {
+		redirectProviderError(r, "link", providerErrNotAuthenticated)
+		return
 	}
 
-	// Clear session cookie
-	r.Session.RemoveAll()
+	linked, err := c.identityService.Link(ctx, zehut, provider.Name, identity)
+	if err != nil {
+		if errors.Is(err, service.ErrIdentityLinkedToOther) {
+			g.Log().Warningf(ctx, "User %s tried to link %s identity %s owned by another user", zehut, provider.Name, identity.Subject)
+			redirectProviderError(r, "link", providerErrLinkedToOther)
+			return
+		}
+
+		g.Log().Error(ctx, "Failed to link identity:", err)
+		redirectProviderError(r, "link", providerErrLinkFailed)
+		return
+	}
 
-	r.Response.WriteJson(g.Map{
-		"status":  "ok",
-		"message": "Logged out successfully",
+	audit.Record(ctx, audit.Entry{
+		Actor:    zehut,
+		Action:   "identity.linked",
+		Entity:   "user_identity",
+		EntityID: strconv.FormatInt(linked.ID, 10),
+		IP:       auth.ClientIP(r),
+		Details: g.Map{
+			"provider": provider.Name,
+			"subject":  identity.Subject,
+		},
 	})
+
+	r.Response.RedirectTo("/security")
 }
 
-func (c *AuthController) GetCurrentUser(r *ghttp.Request) {
-	ctx := r.Context()
+func (c *AuthController) Logout(ctx context.Context, req *v1.LogoutReq) (*v1.LogoutRes, error) {
+	r := g.RequestFromCtx(ctx)
 
-	// Get session from Redis
 	sessionID, _ := r.Session.Id()
-	sess, err := c.sessionManager.Get(ctx, sessionID)
-	if err != nil {
-		r.Response.Status = 401
-		r.Response.WriteJson(g.Map{
-			"error": "Not authenticated",
+	if current, err := c.sessionService.Resolve(ctx, sessionID, auth.ClientIP(r)); err == nil {
+		audit.Record(ctx, audit.Entry{
+			Actor:    current.Zehut,
+			Action:   "logout",
+			Entity:   "user",
+			EntityID: current.Zehut,
+			IP:       auth.ClientIP(r),
 		})
-		return
+	}
+
+	// Delete session from Redis and clear the session cookie
+	if err := c.sessionService.End(r); err != nil {
+		g.Log().Error(ctx, "Failed to delete session:", err)
+	}
+
+	return &v1.LogoutRes{
+		Status:  "ok",
+		Message: "Logged out successfully",
+	}, nil
+}
+
+// GetCurrentUser returns the profile of the authenticated user
+func (c *AuthController) GetCurrentUser(ctx context.Context, req *v1.GetCurrentUserReq) (*v1.GetCurrentUserRes, error) {
+	identity := auth.IdentityFromContext(ctx)
+	if identity == nil {
+		return nil, apperror.Unauthorized("Not authenticated")
 	}
 
 	// Get full user data from database
-	user, err := c.userRepo.FindByZehut(ctx, sess.Zehut)
+	user, err := c.userRepo.FindByZehut(ctx, identity.Zehut)
 	if err != nil {
-		r.Response.Status = 401
-		r.Response.WriteJson(g.Map{
-			"error": "User not found",
-		})
-		return
+		return nil, apperror.Unauthorized("User not found")
 	}
 
-	r.Response.WriteJson(g.Map{
-		"user": g.Map{
-			"zehut":      user.Zehut,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"email":      user.Email,
-			"avatar":     user.Avatar,
-			"role":       user.Role,
-			"is_admin":   user.IsAdmin,
+	return &v1.GetCurrentUserRes{
+		User: v1.CurrentUser{
+			Zehut:     user.Zehut,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			Avatar:    user.Avatar,
+			Role:      user.Role,
+			IsAdmin:   user.IsAdmin,
 		},
-	})
+		MFAEnrollmentRequired: identity.MFAEnrollmentRequired,
+		CanImpersonate:        !identity.IsImpersonated() && !identity.IsAPIKey() && service.CanImpersonate(user),
+		Impersonator:          identity.Impersonator,
+	}, nil
 }
@@ -1,232 +1,197 @@
 package controller
 
 import (
-	"strconv"
+	"context"
 
 	"github.com/gogf/gf/v2/frame/g"
-	"github.com/gogf/gf/v2/net/ghttp"
-	"github.com/gogf/gf/v2/os/gctx"
 
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/auth"
 	"tzlev/internal/model"
 	"tzlev/internal/repository"
+	"tzlev/internal/service"
 )
 
+// maxClassroomPageSize caps the number of classrooms returned by a single list request
+const maxClassroomPageSize = 500
+
 type ClassroomController struct {
 	classroomRepo *repository.ClassroomRepository
+	userService   *service.UserService
 }
 
 func NewClassroomController() *ClassroomController {
 	return &ClassroomController{
 		classroomRepo: repository.NewClassroomRepository(),
+		userService:   service.NewUserService(),
 	}
 }
 
-// GetClassrooms retrieves classrooms based on query parameters
-func (c *ClassroomController) GetClassrooms(r *ghttp.Request) {
-	ctx := gctx.New()
-
-	// Get query parameters
-	academicYear := r.Get("academic_year").String()
-	schoolIDStr := r.Get("school_id").String()
-	teacherIDStr := r.Get("teacher_id").String()
-
-	var classrooms []model.Classroom
-	var err error
-
-	// Filter by academic year if provided
-	if academicYear != "" {
-		classrooms, err = c.classroomRepo.FindByAcademicYear(ctx, academicYear)
-	} else if schoolIDStr != "" {
-		schoolID, parseErr := strconv.ParseInt(schoolIDStr, 10, 64)
-		if parseErr != nil {
-			r.Response.WriteJson(g.Map{
-				"success": false,
-				"message": "Invalid school_id",
-			})
-			return
-		}
-		classrooms, err = c.classroomRepo.FindBySchoolID(ctx, schoolID)
-	} else if teacherIDStr != "" {
-		teacherID, parseErr := strconv.ParseInt(teacherIDStr, 10, 64)
-		if parseErr != nil {
-			r.Response.WriteJson(g.Map{
-				"success": false,
-				"message": "Invalid teacher_id",
-			})
-			return
-		}
-		classrooms, err = c.classroomRepo.FindByTeacherID(ctx, teacherID)
-	} else {
-		// Get all classrooms with pagination
-		offset := r.Get("offset", 0).Int()
-		limit := r.Get("limit", 50).Int()
-		classrooms, err = c.classroomRepo.List(ctx, offset, limit)
+// classroomsResource is the app resource API keys must be scoped to
+const classroomsResource = "classrooms"
+
+// checkClassroomScope refuses API keys that are not scoped to action on classrooms
+func checkClassroomScope(ctx context.Context, action model.PermissionAction) error {
+	identity := auth.IdentityFromContext(ctx)
+	if identity != nil && !identity.ScopeAllows(classroomsResource, action) {
+		g.Log().Warningf(ctx, "Permission denied: API key %d is not scoped to %s %s", identity.APIKeyID, action, classroomsResource)
+		return apperror.Forbidden("Permission denied")
+	}
+	return nil
+}
+
+// authorizeWrite checks that the current user is an admin or the teacher who owns
+// the classroom, and that an API key is scoped to action
+func (c *ClassroomController) authorizeWrite(ctx context.Context, action model.PermissionAction, teacherID int64) error {
+	if err := checkClassroomScope(ctx, action); err != nil {
+		return err
 	}
 
+	user, err := c.userService.GetUserByZehut(ctx, auth.ZehutFromContext(ctx))
 	if err != nil {
-		g.Log().Error(ctx, "Error getting classrooms:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to retrieve classrooms",
-		})
-		return
-	}
-
-	r.Response.WriteJson(g.Map{
-		"success":    true,
-		"classrooms": classrooms,
-	})
+		g.Log().Error(ctx, "Error loading current user:", err)
+		return apperror.Forbidden("Permission denied")
+	}
+
+	if user.IsAdmin || (user.Id != nil && *user.Id == teacherID) {
+		return nil
+	}
+
+	g.Log().Warningf(ctx, "User %s may not modify classrooms of teacher %d", user.Zehut, teacherID)
+	return apperror.Forbidden("Only admins or the classroom's teacher can modify it")
 }
 
-// GetClassroom retrieves a specific classroom by ID
-func (c *ClassroomController) GetClassroom(r *ghttp.Request) {
-	ctx := gctx.New()
+// GetClassrooms retrieves classrooms based on query parameters.
+// The academic_year, school_id and teacher_id filters can be combined.
+func (c *ClassroomController) GetClassrooms(ctx context.Context, req *v1.GetClassroomsReq) (*v1.GetClassroomsRes, error) {
+	if err := checkClassroomScope(ctx, model.ActionView); err != nil {
+		return nil, err
+	}
+
+	filter := repository.ClassroomFilter{
+		AcademicYear: req.AcademicYear,
+		SchoolID:     req.SchoolID,
+		TeacherID:    req.TeacherID,
+	}
 
-	idStr := r.Get("id").String()
-	if idStr == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Classroom ID is required",
-		})
-		return
+	limit := req.Limit
+	if limit <= 0 || limit > maxClassroomPageSize {
+		limit = maxClassroomPageSize
 	}
 
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	classrooms, err := c.classroomRepo.Search(ctx, filter, req.Offset, limit)
 	if err != nil {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Invalid classroom ID",
-		})
-		return
+		return nil, apperror.Internal(err, "Failed to retrieve classrooms")
 	}
 
-	classroom, err := c.classroomRepo.FindByID(ctx, id)
+	return &v1.GetClassroomsRes{
+		Classrooms: classrooms,
+	}, nil
+}
+
+// GetClassroom retrieves a specific classroom by ID
+func (c *ClassroomController) GetClassroom(ctx context.Context, req *v1.GetClassroomReq) (*v1.GetClassroomRes, error) {
+	if err := checkClassroomScope(ctx, model.ActionView); err != nil {
+		return nil, err
+	}
+
+	classroom, err := c.classroomRepo.FindByID(ctx, req.Id)
 	if err != nil {
 		g.Log().Error(ctx, "Error getting classroom:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Classroom not found",
-		})
-		return
+		return nil, apperror.NotFound("Classroom not found")
 	}
 
-	r.Response.WriteJson(g.Map{
-		"success":   true,
-		"classroom": classroom,
-	})
+	return &v1.GetClassroomRes{
+		Classroom: classroom,
+	}, nil
 }
 
 // CreateClassroom creates a new classroom
-func (c *ClassroomController) CreateClassroom(r *ghttp.Request) {
-	ctx := gctx.New()
-
-	var classroom model.Classroom
-	if err := r.Parse(&classroom); err != nil {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Invalid request format",
-		})
-		return
-	}
-
-	if err := c.classroomRepo.Create(ctx, &classroom); err != nil {
-		g.Log().Error(ctx, "Error creating classroom:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to create classroom",
-		})
-		return
-	}
-
-	r.Response.WriteJson(g.Map{
-		"success":   true,
-		"message":   "Classroom created successfully",
-		"classroom": classroom,
-	})
+func (c *ClassroomController) CreateClassroom(ctx context.Context, req *v1.CreateClassroomReq) (*v1.CreateClassroomRes, error) {
+	classroom := classroomFromFields(req.ClassroomFields)
+
+	if err := c.authorizeWrite(ctx, model.ActionCreate, classroom.TeacherID); err != nil {
+		return nil, err
+	}
+
+	if err := c.classroomRepo.Create(ctx, classroom); err != nil {
+		return nil, apperror.Internal(err, "Failed to create classroom")
+	}
+
+	return &v1.CreateClassroomRes{
+		Message:   "Classroom created successfully",
+		Classroom: classroom,
+	}, nil
 }
 
 // UpdateClassroom updates an existing classroom
-func (c *ClassroomController) UpdateClassroom(r *ghttp.Request) {
-	ctx := gctx.New()
+func (c *ClassroomController) UpdateClassroom(ctx context.Context, req *v1.UpdateClassroomReq) (*v1.UpdateClassroomRes, error) {
+	existing, err := c.classroomRepo.FindByID(ctx, req.Id)
+	if err != nil {
+		g.Log().Error(ctx, "Error getting classroom:", err)
+		return nil, apperror.NotFound("Classroom not found")
+	}
 
-	idStr := r.Get("id").String()
-	if idStr == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Classroom ID is required",
-		})
-		return
+	if err := c.authorizeWrite(ctx, model.ActionEdit, existing.TeacherID); err != nil {
+		return nil, err
 	}
 
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Invalid classroom ID",
-		})
-		return
-	}
-
-	var classroom model.Classroom
-	if err := r.Parse(&classroom); err != nil {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Invalid request format",
-		})
-		return
-	}
-
-	classroom.ID = id
-
-	if err := c.classroomRepo.Update(ctx, &classroom); err != nil {
-		g.Log().Error(ctx, "Error updating classroom:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to update classroom",
-		})
-		return
-	}
-
-	r.Response.WriteJson(g.Map{
-		"success":   true,
-		"message":   "Classroom updated successfully",
-		"classroom": classroom,
-	})
+	classroom := classroomFromFields(req.ClassroomFields)
+
+	// Reassigning a classroom requires the right to write for the new teacher too
+	if classroom.TeacherID != existing.TeacherID {
+		if err := c.authorizeWrite(ctx, model.ActionEdit, classroom.TeacherID); err != nil {
+			return nil, err
+		}
+	}
+
+	classroom.ID = req.Id
+
+	if err := c.classroomRepo.Update(ctx, classroom); err != nil {
+		return nil, apperror.Internal(err, "Failed to update classroom")
+	}
+
+	return &v1.UpdateClassroomRes{
+		Message:   "Classroom updated successfully",
+		Classroom: classroom,
+	}, nil
 }
 
 // DeleteClassroom deletes a classroom
-func (c *ClassroomController) DeleteClassroom(r *ghttp.Request) {
-	ctx := gctx.New()
+func (c *ClassroomController) DeleteClassroom(ctx context.Context, req *v1.DeleteClassroomReq) (*v1.DeleteClassroomRes, error) {
+	existing, err := c.classroomRepo.FindByID(ctx, req.Id)
+	if err != nil {
+		g.Log().Error(ctx, "Error getting classroom:", err)
+		return nil, apperror.NotFound("Classroom not found")
+	}
 
-	idStr := r.Get("id").String()
-	if idStr == "" {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Classroom ID is required",
-		})
-		return
+	if err := c.authorizeWrite(ctx, model.ActionDelete, existing.TeacherID); err != nil {
+		return nil, err
 	}
 
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Invalid classroom ID",
-		})
-		return
-	}
-
-	if err := c.classroomRepo.Delete(ctx, id); err != nil {
-		g.Log().Error(ctx, "Error deleting classroom:", err)
-		r.Response.WriteJson(g.Map{
-			"success": false,
-			"message": "Failed to delete classroom",
-		})
-		return
-	}
-
-	r.Response.WriteJson(g.Map{
-		"success": true,
-		"message": "Classroom deleted successfully",
-	})
+	if err := c.classroomRepo.Delete(ctx, req.Id); err != nil {
+		return nil, apperror.Internal(err, "Failed to delete classroom")
+	}
+
+	return &v1.DeleteClassroomRes{
+		Message: "Classroom deleted successfully",
+	}, nil
+}
+
+func classroomFromFields(fields v1.ClassroomFields) *model.Classroom {
+	return &model.Classroom{
+		Code:           fields.Code,
+		ClassroomName:  fields.ClassroomName,
+		SchoolID:       fields.SchoolID,
+		TeacherID:      fields.TeacherID,
+		AcademicYear:   fields.AcademicYear,
+		ClassroomType:  fields.ClassroomType,
+		ClassroomSemel: fields.ClassroomSemel,
+		SymbolType:     fields.SymbolType,
+		OrderID:        fields.OrderID,
+		StartFrom:      fields.StartFrom,
+		EndTo:          fields.EndTo,
+		ManualStart:    fields.ManualStart,
+	}
 }
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/frame/g"
+
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/model"
+	"tzlev/internal/repository"
+	"tzlev/internal/service"
+)
+
+type GroupController struct {
+	groupRepo         *repository.GroupRepository
+	permissionService *service.PermissionService
+}
+
+func NewGroupController() *GroupController {
+	return &GroupController{
+		groupRepo:         repository.NewGroupRepository(),
+		permissionService: service.NewPermissionService(),
+	}
+}
+
+// GetGroups retrieves all groups
+func (c *GroupController) GetGroups(ctx context.Context, req *v1.GetGroupsReq) (*v1.GetGroupsRes, error) {
+	groups, err := c.groupRepo.ListAll(ctx)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve groups")
+	}
+
+	return &v1.GetGroupsRes{
+		Groups: groups,
+	}, nil
+}
+
+// GetGroup retrieves a specific group by ID
+func (c *GroupController) GetGroup(ctx context.Context, req *v1.GetGroupReq) (*v1.GetGroupRes, error) {
+	group, err := c.groupRepo.FindByID(ctx, req.Id)
+	if err != nil {
+		g.Log().Error(ctx, "Error getting group:", err)
+		return nil, apperror.NotFound("Group not found")
+	}
+
+	return &v1.GetGroupRes{
+		Group: group,
+	}, nil
+}
+
+// CreateGroup creates a new group
+func (c *GroupController) CreateGroup(ctx context.Context, req *v1.CreateGroupReq) (*v1.CreateGroupRes, error) {
+	group := model.Group{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	if err := c.groupRepo.Create(ctx, &group); err != nil {
+		return nil, apperror.Internal(err, "Failed to create group")
+	}
+
+	return &v1.CreateGroupRes{
+		Message: "Group created successfully",
+		Group:   &group,
+	}, nil
+}
+
+// UpdateGroup updates the name and description of a group
+func (c *GroupController) UpdateGroup(ctx context.Context, req *v1.UpdateGroupReq) (*v1.UpdateGroupRes, error) {
+	group := model.Group{
+		ID:          req.Id,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	if err := c.groupRepo.Update(ctx, &group); err != nil {
+		return nil, apperror.Internal(err, "Failed to update group")
+	}
+
+	return &v1.UpdateGroupRes{
+		Message: "Group updated successfully",
+		Group:   &group,
+	}, nil
+}
+
+// DeleteGroup deletes a group together with its members and permissions
+func (c *GroupController) DeleteGroup(ctx context.Context, req *v1.DeleteGroupReq) (*v1.DeleteGroupRes, error) {
+	if err := c.permissionService.DeleteGroup(ctx, req.Id); err != nil {
+		return nil, apperror.Internal(err, "Failed to delete group")
+	}
+
+	return &v1.DeleteGroupRes{
+		Message: "Group deleted successfully",
+	}, nil
+}
+
+// GetGroupMembers lists the users in a group
+func (c *GroupController) GetGroupMembers(ctx context.Context, req *v1.GetGroupMembersReq) (*v1.GetGroupMembersRes, error) {
+	members, err := c.groupRepo.ListMembers(ctx, req.Id)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve group members")
+	}
+
+	return &v1.GetGroupMembersRes{
+		Members: members,
+	}, nil
+}
+
+// AddGroupMember adds a user to a group
+func (c *GroupController) AddGroupMember(ctx context.Context, req *v1.AddGroupMemberReq) (*v1.AddGroupMemberRes, error) {
+	if err := c.permissionService.AddGroupMember(ctx, req.Id, req.Zehut); err != nil {
+		return nil, apperror.Internal(err, "Failed to add group member")
+	}
+
+	return &v1.AddGroupMemberRes{
+		Message: "Member added successfully",
+	}, nil
+}
+
+// RemoveGroupMember removes a user from a group
+func (c *GroupController) RemoveGroupMember(ctx context.Context, req *v1.RemoveGroupMemberReq) (*v1.RemoveGroupMemberRes, error) {
+	if err := c.permissionService.RemoveGroupMember(ctx, req.Id, req.Zehut); err != nil {
+		return nil, apperror.Internal(err, "Failed to remove group member")
+	}
+
+	return &v1.RemoveGroupMemberRes{
+		Message: "Member removed successfully",
+	}, nil
+}
+
+// GetGroupPermissions lists the per-resource permissions of a group
+func (c *GroupController) GetGroupPermissions(ctx context.Context, req *v1.GetGroupPermissionsReq) (*v1.GetGroupPermissionsRes, error) {
+	permissions, err := c.permissionService.GetGroupPermissions(ctx, req.Id)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve group permissions")
+	}
+
+	return &v1.GetGroupPermissionsRes{
+		Permissions: permissions,
+	}, nil
+}
+
+// SetGroupPermissions replaces the per-resource permissions of a group
+func (c *GroupController) SetGroupPermissions(ctx context.Context, req *v1.SetGroupPermissionsReq) (*v1.SetGroupPermissionsRes, error) {
+	if err := c.permissionService.SetGroupPermissions(ctx, req.Id, req.Permissions); err != nil {
+		return nil, apperror.Internal(err, "Failed to save group permissions")
+	}
+
+	return &v1.SetGroupPermissionsRes{
+		Message: "Group permissions saved successfully",
+	}, nil
+}
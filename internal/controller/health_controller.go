@@ -1,8 +1,9 @@
 package controller
 
 import (
-	"github.com/gogf/gf/v2/frame/g"
-	"github.com/gogf/gf/v2/net/ghttp"
+	"context"
+
+	v1 "tzlev/api/v1"
 )
 
 type HealthController struct{}
@@ -11,9 +12,9 @@ func NewHealthController() *HealthController {
 	return &HealthController{}
 }
 
-func (c *HealthController) Check(r *ghttp.Request) {
-	r.Response.WriteJson(g.Map{
-		"status":  "ok",
-		"message": "Server is running",
-	})
+func (c *HealthController) Check(ctx context.Context, req *v1.HealthCheckReq) (*v1.HealthCheckRes, error) {
+	return &v1.HealthCheckRes{
+		Status:  "ok",
+		Message: "Server is running",
+	}, nil
 }
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/auth"
+	"tzlev/internal/ratelimit"
+	"tzlev/internal/service"
+)
+
+type PasswordController struct {
+	resetService *service.PasswordResetService
+	resetLimiter *ratelimit.ResetLimiter
+}
+
+func NewPasswordController() *PasswordController {
+	return &PasswordController{
+		resetService: service.NewPasswordResetService(),
+		resetLimiter: ratelimit.NewResetLimiter(),
+	}
+}
+
+// ForgotPassword emails a reset link. It responds the same way whether or not
+// the account exists.
+func (c *PasswordController) ForgotPassword(ctx context.Context, req *v1.ForgotPasswordReq) (*v1.ForgotPasswordRes, error) {
+	if req.Zehut == "" && req.Email == "" {
+		return nil, apperror.BadRequest("Zehut or email is required")
+	}
+
+	r := g.RequestFromCtx(ctx)
+	retryAfter, err := c.resetLimiter.Allow(ctx, ratelimit.SubjectIP, auth.ClientIP(r))
+	if err != nil {
+		return nil, apperror.Internal(err, "Password reset is temporarily unavailable")
+	}
+	if retryAfter > 0 {
+		g.Log().Warning(ctx, "Too many password reset requests from", auth.ClientIP(r))
+		return nil, tooManyRequests(r, retryAfter, "Too many password reset requests. Please try again later.")
+	}
+
+	if err := c.resetService.RequestReset(ctx, req.Zehut, req.Email); err != nil {
+		g.Log().Error(ctx, "Failed to process password reset request:", err)
+	}
+
+	return &v1.ForgotPasswordRes{
+		Status:  "ok",
+		Message: "If the account exists, a password reset link has been sent",
+	}, nil
+}
+
+// ResetPassword sets a new password using the token from the reset email
+func (c *PasswordController) ResetPassword(ctx context.Context, req *v1.ResetPasswordReq) (*v1.ResetPasswordRes, error) {
+	if err := c.resetService.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		var policyErr *auth.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyError(policyErr)
+		}
+
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			return nil, apperror.BadRequest("The reset link is invalid or has expired")
+		}
+
+		return nil, apperror.Internal(err, "Failed to reset password")
+	}
+
+	return &v1.ResetPasswordRes{
+		Status:  "ok",
+		Message: "Password has been reset. Please log in again.",
+	}, nil
+}
+
+// passwordPolicyError reports every rule a new password broke as an error on
+// the password field
+func passwordPolicyError(err *auth.PasswordPolicyError) error {
+	fields := make([]apperror.FieldError, 0, len(err.Violations))
+	for _, violation := range err.Violations {
+		fields = append(fields, apperror.FieldError{Field: "password", Message: violation})
+	}
+	return apperror.Validation(err.Error(), fields...)
+}
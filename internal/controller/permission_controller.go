@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/auth"
+	"tzlev/internal/service"
+)
+
+type PermissionController struct {
+	permissionService *service.PermissionService
+}
+
+func NewPermissionController() *PermissionController {
+	return &PermissionController{
+		permissionService: service.NewPermissionService(),
+	}
+}
+
+// GetUserPermissions lists the permissions granted directly to a user
+func (c *PermissionController) GetUserPermissions(ctx context.Context, req *v1.GetUserPermissionsReq) (*v1.GetUserPermissionsRes, error) {
+	permissions, err := c.permissionService.GetUserPermissions(ctx, req.Zehut)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve user permissions")
+	}
+
+	return &v1.GetUserPermissionsRes{
+		Permissions: permissions,
+	}, nil
+}
+
+// SetUserPermissions replaces the permissions granted directly to a user
+func (c *PermissionController) SetUserPermissions(ctx context.Context, req *v1.SetUserPermissionsReq) (*v1.SetUserPermissionsRes, error) {
+	if err := c.permissionService.SetUserPermissions(ctx, req.Zehut, req.Permissions); err != nil {
+		return nil, apperror.Internal(err, "Failed to save user permissions")
+	}
+
+	return &v1.SetUserPermissionsRes{
+		Message: "User permissions saved successfully",
+	}, nil
+}
+
+// GetMyPermissions returns the effective permissions of the current user, keyed by resource name
+func (c *PermissionController) GetMyPermissions(ctx context.Context, req *v1.GetMyPermissionsReq) (*v1.GetMyPermissionsRes, error) {
+	zehut := auth.ZehutFromContext(ctx)
+
+	permissions, err := c.permissionService.GetEffectivePermissions(ctx, zehut)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve permissions")
+	}
+
+	return &v1.GetMyPermissionsRes{
+		Permissions: permissions,
+	}, nil
+}
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/audit"
+	"tzlev/internal/auth"
+	"tzlev/internal/service"
+)
+
+type SessionController struct {
+	sessionService *service.SessionService
+}
+
+func NewSessionController() *SessionController {
+	return &SessionController{
+		sessionService: service.NewSessionService(),
+	}
+}
+
+// rejectSessionAPIKey stops API keys, which carry no scope for it, from
+// managing their owner's sessions
+func rejectSessionAPIKey(ctx context.Context) error {
+	if identity := auth.IdentityFromContext(ctx); identity != nil && identity.IsAPIKey() {
+		return apperror.Forbidden("API keys cannot manage sessions")
+	}
+	return nil
+}
+
+// requireBrowserSession refuses API keys and bearer tokens on routes that act
+// on the caller's own session. Without one there is no current session to
+// keep, so revoking the others would end them all.
+func requireBrowserSession(ctx context.Context) error {
+	if identity := auth.IdentityFromContext(ctx); identity == nil || identity.IsAPIKey() || identity.SessionID == "" {
+		return apperror.Forbidden("This action requires a browser session")
+	}
+	return nil
+}
+
+// GetMySessions lists the active sessions of the current user
+func (c *SessionController) GetMySessions(ctx context.Context, req *v1.GetMySessionsReq) (*v1.GetMySessionsRes, error) {
+	if err := rejectSessionAPIKey(ctx); err != nil {
+		return nil, err
+	}
+
+	identity := auth.IdentityFromContext(ctx)
+
+	sessions, err := c.sessionService.ListSessions(ctx, identity.Zehut, identity.SessionID)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve sessions")
+	}
+
+	return &v1.GetMySessionsRes{
+		Sessions: sessions,
+	}, nil
+}
+
+// RevokeMySession ends one of the current user's sessions
+func (c *SessionController) RevokeMySession(ctx context.Context, req *v1.RevokeMySessionReq) (*v1.RevokeMySessionRes, error) {
+	if err := rejectSessionAPIKey(ctx); err != nil {
+		return nil, err
+	}
+
+	zehut := auth.ZehutFromContext(ctx)
+
+	if err := c.sessionService.RevokeSession(ctx, zehut, req.Id); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			return nil, apperror.NotFound("Session not found")
+		}
+
+		return nil, apperror.Internal(err, "Failed to revoke session")
+	}
+
+	return &v1.RevokeMySessionRes{
+		Message: "Session revoked successfully",
+	}, nil
+}
+
+// RevokeMyOtherSessions ends every session of the current user except this one
+func (c *SessionController) RevokeMyOtherSessions(ctx context.Context, req *v1.RevokeMyOtherSessionsReq) (*v1.RevokeMyOtherSessionsRes, error) {
+	if err := requireBrowserSession(ctx); err != nil {
+		return nil, err
+	}
+
+	identity := auth.IdentityFromContext(ctx)
+
+	revoked, err := c.sessionService.RevokeOtherSessions(ctx, identity.Zehut, identity.SessionID)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to revoke sessions")
+	}
+
+	return &v1.RevokeMyOtherSessionsRes{
+		Message: "Other sessions revoked successfully",
+		Revoked: revoked,
+	}, nil
+}
+
+// GetUserSessions lists the active sessions of any user
+func (c *SessionController) GetUserSessions(ctx context.Context, req *v1.GetUserSessionsReq) (*v1.GetUserSessionsRes, error) {
+	sessions, err := c.sessionService.ListSessions(ctx, req.Zehut, auth.IdentityFromContext(ctx).SessionID)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve sessions")
+	}
+
+	return &v1.GetUserSessionsRes{
+		Sessions: sessions,
+	}, nil
+}
+
+// ForceLogout ends every session of a user
+func (c *SessionController) ForceLogout(ctx context.Context, req *v1.ForceLogoutReq) (*v1.ForceLogoutRes, error) {
+	if err := c.sessionService.RevokeAllSessions(ctx, req.Zehut); err != nil {
+		return nil, apperror.Internal(err, "Failed to revoke sessions")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    auth.ZehutFromContext(ctx),
+		Action:   "session.force_logout",
+		Entity:   "user",
+		EntityID: req.Zehut,
+	})
+
+	return &v1.ForceLogoutRes{
+		Message: "User logged out from all sessions",
+	}, nil
+}
+
+// StartImpersonation switches the current session to act as another user
+func (c *SessionController) StartImpersonation(ctx context.Context, req *v1.StartImpersonationReq) (*v1.StartImpersonationRes, error) {
+	if err := requireBrowserSession(ctx); err != nil {
+		return nil, err
+	}
+
+	target, err := c.sessionService.StartImpersonation(g.RequestFromCtx(ctx), req.Zehut)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrImpersonationNotAllowed):
+			return nil, apperror.Forbidden("Only admins and technical staff may impersonate users")
+		case errors.Is(err, service.ErrCannotImpersonate):
+			return nil, apperror.BadRequest("This user cannot be impersonated")
+		case errors.Is(err, service.ErrAlreadyImpersonating):
+			return nil, apperror.Conflict("Return to your own account before impersonating another user")
+		case errors.Is(err, service.ErrInvalidSession):
+			return nil, apperror.BadRequest("Impersonation requires a browser session")
+		default:
+			return nil, apperror.Internal(err, "Failed to start impersonation")
+		}
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Action:   "impersonation.start",
+		Entity:   "user",
+		EntityID: target.Zehut,
+	})
+
+	return &v1.StartImpersonationRes{
+		Message: "Impersonation started",
+	}, nil
+}
+
+// StopImpersonation ends an impersonation session and returns to the
+// impersonator's own account
+func (c *SessionController) StopImpersonation(ctx context.Context, req *v1.StopImpersonationReq) (*v1.StopImpersonationRes, error) {
+	if err := requireBrowserSession(ctx); err != nil {
+		return nil, err
+	}
+
+	identity := auth.IdentityFromContext(ctx)
+
+	_, err := c.sessionService.StopImpersonation(g.RequestFromCtx(ctx))
+	if err != nil && !errors.Is(err, service.ErrInvalidSession) {
+		if errors.Is(err, service.ErrNotImpersonating) {
+			return nil, apperror.BadRequest("Not impersonating a user")
+		}
+
+		return nil, apperror.Internal(err, "Failed to stop impersonation")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Action:   "impersonation.stop",
+		Entity:   "user",
+		EntityID: identity.Zehut,
+	})
+
+	// The impersonator's own session may have expired in the meantime
+	if err != nil {
+		return nil, apperror.Unauthorized("Your session has expired; please log in again")
+	}
+
+	return &v1.StopImpersonationRes{
+		Message: "Impersonation ended",
+	}, nil
+}
@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/audit"
+	"tzlev/internal/auth"
+	"tzlev/internal/model"
+	"tzlev/internal/ratelimit"
+	"tzlev/internal/service"
+)
+
+type TwoFactorController struct {
+	twoFactorService *service.TwoFactorService
+	userService      *service.UserService
+	loginLimiter     *ratelimit.LoginLimiter
+}
+
+func NewTwoFactorController() *TwoFactorController {
+	return &TwoFactorController{
+		twoFactorService: service.NewTwoFactorService(),
+		userService:      service.NewUserService(),
+		loginLimiter:     ratelimit.NewLoginLimiter(),
+	}
+}
+
+// currentUser loads the authenticated user. API keys cannot change two-factor
+// settings.
+func (c *TwoFactorController) currentUser(ctx context.Context) (*model.User, error) {
+	identity := auth.IdentityFromContext(ctx)
+	if identity == nil || identity.IsAPIKey() {
+		return nil, apperror.Forbidden("Two-factor settings can only be changed by the user")
+	}
+
+	user, err := c.userService.GetUserByZehut(ctx, identity.Zehut)
+	if err != nil {
+		g.Log().Error(ctx, "Error getting user:", err)
+		return nil, apperror.NotFound("User not found")
+	}
+
+	return user, nil
+}
+
+// verifyCode checks a code for the current user subject to the login throttle,
+// so a stolen session cannot be used to guess codes
+func (c *TwoFactorController) verifyCode(r *ghttp.Request, zehut, code string) error {
+	ctx := r.Context()
+	ip := auth.ClientIP(r)
+
+	attempt, lockout, err := c.loginLimiter.Begin(ctx, zehut, ip)
+	if err != nil {
+		return twoFactorError(err, "Failed to verify code")
+	}
+	if lockout != nil {
+		return lockedOut(r, lockout.RetryAfter)
+	}
+
+	_, err = c.twoFactorService.Verify(ctx, zehut, code)
+	if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+		if _, err := c.loginLimiter.RecordFailure(ctx, attempt); err != nil {
+			g.Log().Error(ctx, "Failed to record login failure:", err)
+		}
+		return twoFactorError(err, "Failed to verify code")
+	}
+
+	// Right codes, and failures on our side, do not count
+	if releaseErr := c.loginLimiter.Release(ctx, attempt); releaseErr != nil {
+		g.Log().Error(ctx, "Failed to release login attempt:", releaseErr)
+	}
+	if err != nil {
+		return twoFactorError(err, "Failed to verify code")
+	}
+
+	return nil
+}
+
+// twoFactorError maps two-factor service errors to responses
+func twoFactorError(err error, message string) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		return apperror.BadRequest("Invalid verification code")
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		return apperror.Conflict("Two-factor authentication is already enabled")
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		return apperror.BadRequest("Two-factor authentication is not enabled")
+	case errors.Is(err, service.ErrTwoFactorRequired):
+		return apperror.Forbidden("Two-factor authentication is required for your account")
+	default:
+		return apperror.Internal(err, message)
+	}
+}
+
+// GetMyTwoFactor reports the current user's two-factor status
+func (c *TwoFactorController) GetMyTwoFactor(ctx context.Context, req *v1.GetMyTwoFactorReq) (*v1.GetMyTwoFactorRes, error) {
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := c.twoFactorService.Status(ctx, user)
+	if err != nil {
+		return nil, twoFactorError(err, "Failed to retrieve two-factor status")
+	}
+
+	return &v1.GetMyTwoFactorRes{
+		TwoFactor: status,
+	}, nil
+}
+
+// EnrollMyTwoFactor starts enrollment and returns the secret and provisioning URI
+func (c *TwoFactorController) EnrollMyTwoFactor(ctx context.Context, req *v1.EnrollMyTwoFactorReq) (*v1.EnrollMyTwoFactorRes, error) {
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := c.twoFactorService.BeginEnrollment(ctx, user)
+	if err != nil {
+		return nil, twoFactorError(err, "Failed to start enrollment")
+	}
+
+	g.RequestFromCtx(ctx).Response.Header().Set("Cache-Control", "no-store")
+	return &v1.EnrollMyTwoFactorRes{
+		Enrollment: enrollment,
+	}, nil
+}
+
+// ConfirmMyTwoFactor enables two-factor authentication and returns the
+// recovery codes. They are shown only this once.
+func (c *TwoFactorController) ConfirmMyTwoFactor(ctx context.Context, req *v1.ConfirmMyTwoFactorReq) (*v1.ConfirmMyTwoFactorRes, error) {
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := c.twoFactorService.ConfirmEnrollment(ctx, user.Zehut, req.Code)
+	if err != nil {
+		return nil, twoFactorError(err, "Failed to enable two-factor authentication")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    user.Zehut,
+		Action:   "mfa.enabled",
+		Entity:   "user",
+		EntityID: user.Zehut,
+	})
+
+	g.RequestFromCtx(ctx).Response.Header().Set("Cache-Control", "no-store")
+	return &v1.ConfirmMyTwoFactorRes{
+		Message:       "Two-factor authentication enabled",
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// DisableMyTwoFactor turns two-factor authentication off after checking a current code
+func (c *TwoFactorController) DisableMyTwoFactor(ctx context.Context, req *v1.DisableMyTwoFactorReq) (*v1.DisableMyTwoFactorRes, error) {
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.twoFactorService.IsRequired(user) {
+		return nil, twoFactorError(service.ErrTwoFactorRequired, "")
+	}
+
+	if err := c.verifyCode(g.RequestFromCtx(ctx), user.Zehut, req.Code); err != nil {
+		return nil, err
+	}
+
+	if err := c.twoFactorService.Disable(ctx, user); err != nil {
+		return nil, twoFactorError(err, "Failed to disable two-factor authentication")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    user.Zehut,
+		Action:   "mfa.disabled",
+		Entity:   "user",
+		EntityID: user.Zehut,
+	})
+
+	return &v1.DisableMyTwoFactorRes{
+		Message: "Two-factor authentication disabled",
+	}, nil
+}
+
+// RegenerateMyRecoveryCodes replaces the current user's recovery codes
+func (c *TwoFactorController) RegenerateMyRecoveryCodes(ctx context.Context, req *v1.RegenerateMyRecoveryCodesReq) (*v1.RegenerateMyRecoveryCodesRes, error) {
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.verifyCode(g.RequestFromCtx(ctx), user.Zehut, req.Code); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := c.twoFactorService.RegenerateRecoveryCodes(ctx, user.Zehut)
+	if err != nil {
+		return nil, twoFactorError(err, "Failed to regenerate recovery codes")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    user.Zehut,
+		Action:   "mfa.recovery_codes_regenerated",
+		Entity:   "user",
+		EntityID: user.Zehut,
+	})
+
+	g.RequestFromCtx(ctx).Response.Header().Set("Cache-Control", "no-store")
+	return &v1.RegenerateMyRecoveryCodesRes{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// ResetUserTwoFactor removes a user's two-factor setup, e.g. after they lost
+// their phone and recovery codes
+func (c *TwoFactorController) ResetUserTwoFactor(ctx context.Context, req *v1.ResetUserTwoFactorReq) (*v1.ResetUserTwoFactorRes, error) {
+	identity := auth.IdentityFromContext(ctx)
+	if identity != nil && req.Zehut == identity.Zehut {
+		return nil, apperror.BadRequest("Change your own two-factor authentication from your security settings")
+	}
+
+	user, err := c.userService.LoadUser(ctx, req.Zehut)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("User not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve user")
+	}
+
+	// Only admins may strip the second factor the policy protects an account with
+	if (user.IsAdmin || c.twoFactorService.IsRequired(user)) && (identity == nil || !identity.IsAdmin) {
+		return nil, apperror.Forbidden("Only admins can reset two-factor authentication for this user")
+	}
+
+	if err := c.twoFactorService.Reset(ctx, req.Zehut); err != nil {
+		return nil, twoFactorError(err, "Failed to reset two-factor authentication")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    auth.ZehutFromContext(ctx),
+		Action:   "mfa.reset",
+		Entity:   "user",
+		EntityID: req.Zehut,
+	})
+
+	return &v1.ResetUserTwoFactorRes{
+		Message: "Two-factor authentication reset",
+	}, nil
+}
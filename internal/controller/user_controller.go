@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/audit"
+	"tzlev/internal/auth"
+	"tzlev/internal/model"
+	"tzlev/internal/ratelimit"
+	"tzlev/internal/service"
+)
+
+// maxUserPageSize caps the number of users returned by a single list request
+const maxUserPageSize = 200
+
+type UserController struct {
+	userService  *service.UserService
+	loginLimiter *ratelimit.LoginLimiter
+}
+
+func NewUserController() *UserController {
+	return &UserController{
+		userService:  service.NewUserService(),
+		loginLimiter: ratelimit.NewLoginLimiter(),
+	}
+}
+
+// applyProfile copies the profile fields that were given onto user
+func applyProfile(p v1.UserProfile, user *model.User) {
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setInt := func(dst *int, src *int) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setBool := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	setString(&user.FirstName, p.FirstName)
+	setString(&user.LastName, p.LastName)
+	setString(&user.Email, p.Email)
+	setString(&user.Mobile, p.Mobile)
+	setString(&user.Phone, p.Phone)
+	setString(&user.Address, p.Address)
+	setString(&user.City, p.City)
+	setString(&user.Zipcode, p.Zipcode)
+	setString(&user.Role, p.Role)
+	setString(&user.RoleDescription, p.RoleDescription)
+	setString(&user.Remarks, p.Remarks)
+	setString(&user.ManagerId, p.ManagerId)
+	setString(&user.UnitCode, p.UnitCode)
+	setInt(&user.PaymentPerHour, p.PaymentPerHour)
+	setInt(&user.Allowance, p.Allowance)
+	setInt(&user.MonthlyTicket, p.MonthlyTicket)
+	setBool(&user.HoursCount, p.HoursCount)
+	setBool(&user.DailyAllowance, p.DailyAllowance)
+	setBool(&user.MonthlyAllowance, p.MonthlyAllowance)
+	setBool(&user.TravelConsiderate, p.TravelConsiderate)
+}
+
+// isCurrentUserAdmin reports whether the authenticated user has admin rights
+func (c *UserController) isCurrentUserAdmin(ctx context.Context) bool {
+	identity := auth.IdentityFromContext(ctx)
+	return identity != nil && identity.IsAdmin
+}
+
+// guardAdminAccount stops anyone but admins from changing an admin's account.
+// Otherwise a user manager could, say, change an admin's email and take the
+// account over with a password reset.
+func guardAdminAccount(ctx context.Context, target *model.User) error {
+	identity := auth.IdentityFromContext(ctx)
+	if target.IsAdmin && (identity == nil || !identity.IsAdmin) {
+		return apperror.Forbidden("Only admins can change admin accounts")
+	}
+	return nil
+}
+
+// GetUsers lists users with optional search and pagination
+func (c *UserController) GetUsers(ctx context.Context, req *v1.GetUsersReq) (*v1.GetUsersRes, error) {
+	offset := req.Offset
+	limit := req.Limit
+	if limit <= 0 || limit > maxUserPageSize {
+		limit = maxUserPageSize
+	}
+
+	users, total, err := c.userService.ListUsers(ctx, req.Search, offset, limit)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve users")
+	}
+
+	return &v1.GetUsersRes{
+		Users:  users,
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+	}, nil
+}
+
+// GetUser retrieves a user by zehut
+func (c *UserController) GetUser(ctx context.Context, req *v1.GetUserReq) (*v1.GetUserRes, error) {
+	user, err := c.userService.GetUserByZehut(ctx, req.Zehut)
+	if err != nil {
+		g.Log().Error(ctx, "Error getting user:", err)
+		return nil, apperror.NotFound("User not found")
+	}
+
+	return &v1.GetUserRes{
+		User: user,
+	}, nil
+}
+
+// CreateUser creates a new user with a hashed password
+func (c *UserController) CreateUser(ctx context.Context, req *v1.CreateUserReq) (*v1.CreateUserRes, error) {
+	if req.FirstName == nil || req.LastName == nil {
+		return nil, apperror.BadRequest("Zehut, first name and last name are required")
+	}
+
+	if req.IsAdmin && !c.isCurrentUserAdmin(ctx) {
+		return nil, apperror.Forbidden("Only admins can create admin users")
+	}
+
+	if _, err := c.userService.LoadUser(ctx, req.Zehut); err == nil {
+		return nil, apperror.Conflict("A user with this zehut already exists")
+	}
+
+	user := &model.User{
+		Zehut:   req.Zehut,
+		IsAdmin: req.IsAdmin,
+	}
+	applyProfile(req.UserProfile, user)
+
+	if err := c.userService.CreateUser(ctx, user, req.Password); err != nil {
+		var policyErr *auth.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyError(policyErr)
+		}
+
+		return nil, apperror.Internal(err, "Failed to create user")
+	}
+
+	return &v1.CreateUserRes{
+		Message: "User created successfully",
+		User:    user,
+	}, nil
+}
+
+// UpdateUser updates the profile and payroll fields of a user
+func (c *UserController) UpdateUser(ctx context.Context, req *v1.UpdateUserReq) (*v1.UpdateUserRes, error) {
+	user, err := c.userService.LoadUser(ctx, req.Zehut)
+	if err != nil {
+		g.Log().Error(ctx, "Error getting user:", err)
+		return nil, apperror.NotFound("User not found")
+	}
+
+	if err := guardAdminAccount(ctx, user); err != nil {
+		return nil, err
+	}
+
+	applyProfile(req.UserProfile, user)
+
+	if err := c.userService.UpdateUser(ctx, user); err != nil {
+		return nil, apperror.Internal(err, "Failed to update user")
+	}
+
+	return &v1.UpdateUserRes{
+		Message: "User updated successfully",
+		User:    user,
+	}, nil
+}
+
+// SetFreezed freezes or unfreezes a user account
+func (c *UserController) SetFreezed(ctx context.Context, req *v1.SetFreezedReq) (*v1.SetFreezedRes, error) {
+	if req.IsFreezed && req.Zehut == auth.ZehutFromContext(ctx) {
+		return nil, apperror.BadRequest("You cannot freeze your own account")
+	}
+
+	user, err := c.userService.LoadUser(ctx, req.Zehut)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("User not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve user")
+	}
+	if err := guardAdminAccount(ctx, user); err != nil {
+		return nil, err
+	}
+
+	err = c.userService.SetFreezed(ctx, req.Zehut, req.IsFreezed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("User not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to update user")
+	}
+
+	return &v1.SetFreezedRes{
+		Message: "User updated successfully",
+	}, nil
+}
+
+// SetAdmin grants or revokes admin rights. Only admins may call it.
+func (c *UserController) SetAdmin(ctx context.Context, req *v1.SetAdminReq) (*v1.SetAdminRes, error) {
+	if !c.isCurrentUserAdmin(ctx) {
+		return nil, apperror.Forbidden("Only admins can change admin rights")
+	}
+
+	if !req.IsAdmin && req.Zehut == auth.ZehutFromContext(ctx) {
+		return nil, apperror.BadRequest("You cannot remove your own admin rights")
+	}
+
+	err := c.userService.SetAdmin(ctx, req.Zehut, req.IsAdmin)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("User not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to update user")
+	}
+
+	return &v1.SetAdminRes{
+		Message: "User updated successfully",
+	}, nil
+}
+
+// GetLoginLockout reports recent failed logins and any active lockout for a zehut
+func (c *UserController) GetLoginLockout(ctx context.Context, req *v1.GetLoginLockoutReq) (*v1.GetLoginLockoutRes, error) {
+	failures, remaining, err := c.loginLimiter.Status(ctx, ratelimit.SubjectZehut, req.Zehut)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve lockout status")
+	}
+
+	return &v1.GetLoginLockoutRes{
+		FailedAttempts: failures,
+		Locked:         remaining > 0,
+		RetryAfter:     int(remaining.Seconds()),
+	}, nil
+}
+
+// ClearLoginLockout lifts a login lockout for a zehut
+func (c *UserController) ClearLoginLockout(ctx context.Context, req *v1.ClearLoginLockoutReq) (*v1.ClearLoginLockoutRes, error) {
+	return c.clearLockout(ctx, ratelimit.SubjectZehut, req.Zehut)
+}
+
+// ClearIPLockout lifts a login lockout for a client IP
+func (c *UserController) ClearIPLockout(ctx context.Context, req *v1.ClearIPLockoutReq) (*v1.ClearIPLockoutRes, error) {
+	return c.clearLockout(ctx, ratelimit.SubjectIP, req.IP)
+}
+
+func (c *UserController) clearLockout(ctx context.Context, subject, value string) (*v1.ClearLoginLockoutRes, error) {
+	if err := c.loginLimiter.Clear(ctx, subject, value); err != nil {
+		return nil, apperror.Internal(err, "Failed to clear lockout")
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    auth.ZehutFromContext(ctx),
+		Action:   "login.lockout_cleared",
+		Entity:   subject,
+		EntityID: value,
+	})
+
+	return &v1.ClearLoginLockoutRes{
+		Message: "Lockout cleared successfully",
+	}, nil
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gogf/gf/v2/frame/g"
+
+	v1 "tzlev/api/v1"
+	"tzlev/internal/apperror"
+	"tzlev/internal/audit"
+	"tzlev/internal/auth"
+	"tzlev/internal/model"
+	"tzlev/internal/service"
+)
+
+type UserIdentityController struct {
+	identityService *service.UserIdentityService
+}
+
+func NewUserIdentityController() *UserIdentityController {
+	return &UserIdentityController{
+		identityService: service.NewUserIdentityService(),
+	}
+}
+
+// GetMyIdentities lists the external accounts linked to the current user
+func (c *UserIdentityController) GetMyIdentities(ctx context.Context, req *v1.GetMyIdentitiesReq) (*v1.GetMyIdentitiesRes, error) {
+	identities, err := c.list(ctx, auth.ZehutFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.GetMyIdentitiesRes{
+		Identities: identities,
+	}, nil
+}
+
+// UnlinkMyIdentity removes one of the current user's linked accounts
+func (c *UserIdentityController) UnlinkMyIdentity(ctx context.Context, req *v1.UnlinkMyIdentityReq) (*v1.UnlinkMyIdentityRes, error) {
+	if identity := auth.IdentityFromContext(ctx); identity != nil && identity.IsAPIKey() {
+		return nil, apperror.Forbidden("API keys cannot unlink accounts")
+	}
+
+	if err := c.unlink(ctx, auth.ZehutFromContext(ctx), req.Id); err != nil {
+		return nil, err
+	}
+
+	return &v1.UnlinkMyIdentityRes{
+		Message: "Account unlinked successfully",
+	}, nil
+}
+
+// GetUserIdentities lists the external accounts linked to any user
+func (c *UserIdentityController) GetUserIdentities(ctx context.Context, req *v1.GetUserIdentitiesReq) (*v1.GetUserIdentitiesRes, error) {
+	identities, err := c.list(ctx, req.Zehut)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.GetUserIdentitiesRes{
+		Identities: identities,
+	}, nil
+}
+
+// UnlinkUserIdentity removes a linked account from any user, e.g. one that
+// was linked by mistake
+func (c *UserIdentityController) UnlinkUserIdentity(ctx context.Context, req *v1.UnlinkUserIdentityReq) (*v1.UnlinkUserIdentityRes, error) {
+	if err := c.unlink(ctx, req.Zehut, req.Id); err != nil {
+		return nil, err
+	}
+
+	return &v1.UnlinkUserIdentityRes{
+		Message: "Account unlinked successfully",
+	}, nil
+}
+
+func (c *UserIdentityController) list(ctx context.Context, zehut string) ([]model.UserIdentity, error) {
+	identities, err := c.identityService.List(ctx, zehut)
+	if err != nil {
+		return nil, apperror.Internal(err, "Failed to retrieve linked accounts")
+	}
+	return identities, nil
+}
+
+func (c *UserIdentityController) unlink(ctx context.Context, zehut string, id int64) error {
+	identity, err := c.identityService.Unlink(ctx, zehut, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIdentityNotFound):
+			return apperror.NotFound("Linked account not found")
+		case errors.Is(err, service.ErrLastLoginMethod):
+			return apperror.Conflict("Set a password before unlinking the only linked account")
+		default:
+			return apperror.Internal(err, "Failed to unlink account")
+		}
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    auth.ZehutFromContext(ctx),
+		Action:   "identity.unlinked",
+		Entity:   "user_identity",
+		EntityID: strconv.FormatInt(id, 10),
+		Details: g.Map{
+			"zehut":    zehut,
+			"provider": identity.Provider,
+			"subject":  identity.Subject,
+		},
+	})
+
+	return nil
+}
@@ -111,3 +111,37 @@ func (es *EmailService) SendWelcomeEmail(to, name string) error {
 		Body:    body.String(),
 	})
 }
+
+type PasswordResetEmailData struct {
+	Name     string
+	Link     string
+	ValidFor string
+}
+
+func (es *EmailService) SendPasswordResetEmail(to, name, link, validFor string) error {
+	// Parse template
+	tmplPath := filepath.Join(es.templatePath, "password_reset.html")
+	tmpl, err := template.ParseFiles(tmplPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	// Execute template
+	var body bytes.Buffer
+	data := PasswordResetEmailData{
+		Name:     name,
+		Link:     link,
+		ValidFor: validFor,
+	}
+
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	// Send email
+	return es.Send(&EmailData{
+		To:      []string{to},
+		Subject: "Reset your Tzlev password",
+		Body:    body.String(),
+	})
+}
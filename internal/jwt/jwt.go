@@ -1,8 +1,9 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/gogf/gf/v2/frame/g"
@@ -10,50 +11,94 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims identifies the user behind an access token. SessionID is the refresh
+// token family the access token was issued from, so revoking the family also
+// invalidates its access tokens.
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Name   string `json:"name"`
+	Zehut     string `json:"zehut"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, email, name string) (string, error) {
+// Expiration returns the lifetime of access tokens
+func Expiration() time.Duration {
 	ctx := gctx.New()
-	cfg := g.Cfg()
+	return g.Cfg().MustGet(ctx, "security.jwtExpiration", "15m").Duration()
+}
+
+// issuer is the "iss" claim of issued tokens, so other systems can tell our tokens apart
+func issuer() string {
+	ctx := gctx.New()
+	return g.Cfg().MustGet(ctx, "app.baseURL").String()
+}
+
+// GenerateToken signs an access token with the current signing key
+func GenerateToken(zehut, email, name, sessionID string) (string, error) {
+	if manager == nil {
+		return "", fmt.Errorf("JWT keys are not initialized")
+	}
 
-	secret := os.Getenv("JWT_SECRET")
-	expiration := cfg.MustGet(ctx, "security.jwtExpiration").Duration()
+	key, err := manager.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
 
+	now := time.Now()
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
+		Zehut:     zehut,
+		Email:     email,
+		Name:      name,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    issuer(),
+			Subject:   zehut,
+			ID:        hex.EncodeToString(b),
+			ExpiresAt: jwt.NewNumericDate(now.Add(Expiration())),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token := jwt.NewWithClaims(key.signingMethod(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
 }
 
+// VerifyToken checks the signature against the key named by the token's kid
+// header and validates the standard claims
 func VerifyToken(tokenString string) (*Claims, error) {
-	secret := os.Getenv("JWT_SECRET")
+	if manager == nil {
+		return nil, fmt.Errorf("JWT keys are not initialized")
+	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, err := manager.verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(secret), nil
-	})
+		return key.PrivateKey.Public(), nil
+	},
+		jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}),
+		jwt.WithIssuer(issuer()),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.Zehut != "" {
 		return claims, nil
 	}
 
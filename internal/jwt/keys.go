@@ -0,0 +1,400 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtimer"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 3072
+
+// reloadCooldown limits how often an unknown kid triggers a reload of the key directory
+const reloadCooldown = time.Minute
+
+// JWKSMaxAge is how long clients may cache the JWKS document
+const JWKSMaxAge = 5 * time.Minute
+
+// Key is a signing key loaded from the key directory. The file name without
+// its extension is the key ID published as "kid".
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	CreatedAt  time.Time
+}
+
+// KeyManager holds the signing keys. A new key is published for publishDelay
+// before it signs, so that clients with a cached JWKS can verify its tokens.
+// Older keys stay available for verification for the retention period after
+// being replaced.
+type KeyManager struct {
+	dir              string
+	algorithm        string
+	rotationInterval time.Duration
+	retention        time.Duration
+	publishDelay     time.Duration
+
+	mu         sync.RWMutex
+	keys       []*Key // newest first
+	lastReload time.Time
+}
+
+var manager *KeyManager
+
+// Init loads the signing keys and schedules rotation. It fails when no usable
+// key exists, so the server never signs tokens with an empty secret.
+func Init(ctx context.Context) error {
+	cfg := g.Cfg()
+
+	m := &KeyManager{
+		dir:              cfg.MustGet(ctx, "security.jwt.keysPath", "keys/jwt").String(),
+		algorithm:        cfg.MustGet(ctx, "security.jwt.algorithm", AlgorithmEdDSA).String(),
+		rotationInterval: cfg.MustGet(ctx, "security.jwt.rotationInterval", "0").Duration(),
+		retention:        cfg.MustGet(ctx, "security.jwt.retention", "24h").Duration(),
+	}
+
+	if m.algorithm != AlgorithmRS256 && m.algorithm != AlgorithmEdDSA {
+		return fmt.Errorf("unsupported JWT algorithm %q (expected %s or %s)", m.algorithm, AlgorithmRS256, AlgorithmEdDSA)
+	}
+	if m.retention < Expiration() {
+		return fmt.Errorf("security.jwt.retention (%s) must be at least security.jwtExpiration (%s)", m.retention, Expiration())
+	}
+
+	// Other instances only see a rotated key on their next rotation check, and
+	// their clients may have cached the JWKS just before
+	m.publishDelay = JWKSMaxAge
+	if m.rotationInterval > 0 {
+		m.publishDelay += m.rotationInterval / 10
+		if m.rotationInterval <= m.publishDelay {
+			return fmt.Errorf("security.jwt.rotationInterval (%s) must be longer than the %s a new key is published before it signs", m.rotationInterval, m.publishDelay)
+		}
+	}
+
+	if err := m.reload(); err != nil {
+		return err
+	}
+	// Rotation only replaces keys; the first key is provisioned deliberately
+	if len(m.keys) == 0 {
+		return fmt.Errorf("no JWT signing keys found in %s; generate one with: ./tzlev jwtkey --action=generate", m.dir)
+	}
+
+	if m.rotationInterval > 0 {
+		if err := m.rotate(ctx); err != nil {
+			return err
+		}
+
+		// Check well before the interval elapses so rotation is not late by a whole interval
+		gtimer.AddSingleton(ctx, m.rotationInterval/10, func(ctx context.Context) {
+			if err := m.rotate(ctx); err != nil {
+				g.Log().Error(ctx, "JWT key rotation failed:", err)
+			}
+		})
+	}
+
+	signing, err := m.signingKey()
+	if err != nil {
+		return err
+	}
+
+	manager = m
+	g.Log().Infof(ctx, "JWT keys loaded (%d active, signing with %s)", len(m.keys), signing.ID)
+
+	return nil
+}
+
+// GenerateKey creates a new private key in dir and returns it
+func GenerateKey(dir, algorithm string) (*Key, error) {
+	var signer crypto.Signer
+	var err error
+
+	switch algorithm {
+	case AlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate key id: %w", err)
+	}
+
+	now := time.Now().UTC()
+	key := &Key{
+		ID:         now.Format("20060102T150405") + "-" + hex.EncodeToString(b),
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+		CreatedAt:  now,
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	block := &pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{"Created": now.Format(time.RFC3339)},
+		Bytes:   der,
+	}
+	path := filepath.Join(dir, key.ID+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write key: %w", err)
+	}
+
+	return key, nil
+}
+
+// LoadKeys reads every *.pem private key in dir, newest first
+func LoadKeys(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+func loadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("expected a PKCS#8 \"PRIVATE KEY\" PEM block")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+		key.Algorithm = AlgorithmRS256
+		key.PrivateKey = k
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgorithmEdDSA
+		key.PrivateKey = k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	// Keys created outside GenerateKey fall back to the file time
+	if created, err := time.Parse(time.RFC3339, block.Headers["Created"]); err == nil {
+		key.CreatedAt = created
+	} else if info, err := os.Stat(path); err == nil {
+		key.CreatedAt = info.ModTime()
+	}
+
+	return key, nil
+}
+
+// reload reads the key directory and keeps the signing key plus the replaced
+// keys that are still within their retention period
+func (m *KeyManager) reload() error {
+	keys, err := LoadKeys(m.dir)
+	if err != nil {
+		return err
+	}
+
+	active := make([]*Key, 0, len(keys))
+	for i, key := range keys {
+		// A key is retired once the next newer key takes over signing
+		if i > 0 && time.Since(keys[i-1].CreatedAt) > m.publishDelay+m.retention {
+			break
+		}
+		active = append(active, key)
+	}
+
+	m.mu.Lock()
+	m.keys = active
+	m.lastReload = time.Now()
+	m.mu.Unlock()
+
+	return nil
+}
+
+// rotate generates a new signing key once the current one is older than the
+// rotation interval. Other instances pick the key up on their next reload.
+func (m *KeyManager) rotate(ctx context.Context) error {
+	if err := m.reload(); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	due := len(m.keys) == 0 || time.Since(m.keys[0].CreatedAt) >= m.rotationInterval
+	m.mu.RUnlock()
+
+	if !due {
+		return nil
+	}
+
+	key, err := GenerateKey(m.dir, m.algorithm)
+	if err != nil {
+		return err
+	}
+	g.Log().Infof(ctx, "Rotated JWT signing key, new kid %s signs from %s", key.ID, key.CreatedAt.Add(m.publishDelay).Format(time.RFC3339))
+
+	return m.reload()
+}
+
+// signingKey returns the newest key that has been published for publishDelay.
+// Until one has, such as right after the first key was generated, the oldest
+// key signs.
+func (m *KeyManager) signingKey() (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.keys) == 0 {
+		return nil, fmt.Errorf("no JWT signing key available")
+	}
+	for _, key := range m.keys {
+		if time.Since(key.CreatedAt) >= m.publishDelay {
+			return key, nil
+		}
+	}
+	return m.keys[len(m.keys)-1], nil
+}
+
+// verificationKey returns the key with the given ID. Unknown IDs trigger a
+// reload, at most once per cooldown, in case another instance rotated.
+func (m *KeyManager) verificationKey(kid string) (*Key, error) {
+	if key := m.find(kid); key != nil {
+		return key, nil
+	}
+
+	m.mu.RLock()
+	stale := time.Since(m.lastReload) > reloadCooldown
+	m.mu.RUnlock()
+
+	if stale {
+		if err := m.reload(); err != nil {
+			return nil, err
+		}
+		if key := m.find(kid); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (m *KeyManager) find(kid string) *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+func (k *Key) signingMethod() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// JWK is the public part of a key as published in the JWKS document
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS returns the public keys that may have signed a currently valid token
+func JWKS() []JWK {
+	if manager == nil {
+		return []JWK{}
+	}
+
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	jwks := make([]JWK, 0, len(manager.keys))
+	for _, key := range manager.keys {
+		jwk := JWK{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Algorithm,
+		}
+
+		switch pub := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+
+		jwks = append(jwks, jwk)
+	}
+
+	return jwks
+}
@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/glog"
+	"tzlev/internal/auth"
+)
+
+// Fields are structured values for a log line. Pass them as the last value to
+// a logger, e.g. g.Log().Info(ctx, "Request completed", logging.Fields{...}).
+type Fields map[string]interface{}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the current request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request in ctx. Outside of requests, e.g.
+// in CLI commands, it falls back to the trace ID of ctx.
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return gctx.CtxId(ctx)
+}
+
+// Init configures the default and audit loggers from the logging block of the
+// config: level, format ("json" or "text"), output ("stdout" or "file") and
+// filePath for file output
+func Init() error {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	level := cfg.MustGet(ctx, "logging.level", "info").String()
+	format := cfg.MustGet(ctx, "logging.format", "text").String()
+	output := cfg.MustGet(ctx, "logging.output", "stdout").String()
+	filePath := cfg.MustGet(ctx, "logging.filePath", "logs/app.log").String()
+
+	switch format {
+	case "json":
+		glog.SetDefaultHandler(jsonHandler)
+	case "text":
+	default:
+		return fmt.Errorf("unknown logging format %q, use json or text", format)
+	}
+
+	for _, logger := range []*glog.Logger{g.Log(), g.Log("audit")} {
+		if err := logger.SetLevelStr(level); err != nil {
+			return fmt.Errorf("invalid logging level %q: %w", level, err)
+		}
+		// Text lines show the request ID next to the trace ID
+		logger.SetCtxKeys(requestIDKey{})
+
+		switch output {
+		case "stdout":
+			logger.SetStdoutPrint(true)
+		case "file":
+			if err := logger.SetPath(filepath.Dir(filePath)); err != nil {
+				return fmt.Errorf("invalid logging file path %q: %w", filePath, err)
+			}
+			logger.SetFile(filepath.Base(filePath))
+			logger.SetStdoutPrint(false)
+		default:
+			return fmt.Errorf("unknown logging output %q, use stdout or file", output)
+		}
+	}
+
+	return nil
+}
+
+// jsonHandler writes each log line as a JSON object with the request ID, user
+// and route of the request it was logged for
+func jsonHandler(ctx context.Context, in *glog.HandlerInput) {
+	entry := map[string]interface{}{
+		"time":  in.Time.Format(time.RFC3339Nano),
+		"level": levelName(in.Level),
+	}
+
+	var values []interface{}
+	for _, value := range in.Values {
+		if fields, ok := value.(Fields); ok {
+			for key, field := range fields {
+				entry[key] = field
+			}
+			continue
+		}
+		values = append(values, value)
+	}
+	in.Values = values
+
+	message := in.Content
+	if len(values) > 0 {
+		if message != "" {
+			message += " "
+		}
+		message += in.ValuesContent()
+	}
+	entry["msg"] = message
+
+	if r := ghttp.RequestFromCtx(ctx); r != nil {
+		entry["request_id"] = RequestID(ctx)
+		if r.Router != nil {
+			entry["route"] = r.Router.Method + " " + r.Router.Uri
+		}
+	}
+	if zehut := auth.ZehutFromContext(ctx); zehut != "" {
+		entry["zehut"] = zehut
+	}
+	if in.TraceId != "" {
+		entry["trace_id"] = in.TraceId
+	}
+	if in.Stack != "" {
+		entry["stack"] = in.Stack
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"level": "error", "msg": "cannot encode log entry: " + err.Error()})
+	}
+	in.Buffer.Write(data)
+	in.Buffer.WriteString("\n")
+	in.Next(ctx)
+}
+
+func levelName(level int) string {
+	switch level {
+	case glog.LEVEL_DEBU:
+		return "debug"
+	case glog.LEVEL_INFO:
+		return "info"
+	case glog.LEVEL_NOTI:
+		return "notice"
+	case glog.LEVEL_WARN:
+		return "warning"
+	case glog.LEVEL_ERRO:
+		return "error"
+	case glog.LEVEL_CRIT:
+		return "critical"
+	case glog.LEVEL_PANI:
+		return "panic"
+	case glog.LEVEL_FATA:
+		return "fatal"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"tzlev/internal/redis"
+)
+
+const namespace = "tzlev"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	loginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_logins_total",
+		Help:      "Login attempts by method (password, token or provider name) and result.",
+	}, []string{"method", "result"})
+
+	authenticationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_requests_total",
+		Help:      "Requests authenticated with a session, bearer token or API key, by method and result.",
+	}, []string{"method", "result"})
+
+	cacheLookupsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "CacheManager lookups by result (hit or miss).",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		loginsTotal,
+		authenticationsTotal,
+		cacheLookupsTotal,
+	)
+}
+
+// ObserveRequest records a served HTTP request. route is the route pattern,
+// not the path, to keep the number of series bounded.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// RecordLogin counts a login attempt
+func RecordLogin(method string, ok bool) {
+	loginsTotal.WithLabelValues(method, result(ok)).Inc()
+}
+
+// RecordAuthentication counts a request that presented credentials
+func RecordAuthentication(method string, ok bool) {
+	authenticationsTotal.WithLabelValues(method, result(ok)).Inc()
+}
+
+// RecordCacheLookup counts a cache hit or miss
+func RecordCacheLookup(hit bool) {
+	if hit {
+		cacheLookupsTotal.WithLabelValues("hit").Inc()
+	} else {
+		cacheLookupsTotal.WithLabelValues("miss").Inc()
+	}
+}
+
+func result(ok bool) string {
+	if ok {
+		return "success"
+	}
+	return "failure"
+}
+
+// Init adds the database and Redis pool stats and serves the metrics at
+// /metrics on metrics.address. They get a listener of their own so that they
+// are not reachable through the public port. An empty address disables them.
+func Init(ctx context.Context) error {
+	address := g.Cfg().MustGet(ctx, "metrics.address", "").String()
+	if address == "" {
+		return nil
+	}
+
+	db, err := g.DB().Master()
+	if err != nil {
+		return fmt.Errorf("failed to get database pool: %w", err)
+	}
+	if err := registry.Register(collectors.NewDBStatsCollector(db, g.DB().GetGroup())); err != nil {
+		return fmt.Errorf("failed to register database pool metrics: %w", err)
+	}
+	if err := registry.Register(newRedisPoolCollector(redis.Client)); err != nil {
+		return fmt.Errorf("failed to register Redis pool metrics: %w", err)
+	}
+
+	// Listen now so that a port in use stops the server from starting
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics on %s: %w", address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			g.Log().Error(ctx, "Metrics server stopped:", err)
+		}
+	}()
+
+	g.Log().Infof(ctx, "Serving metrics on %s/metrics", address)
+
+	return nil
+}
@@ -1,48 +1,107 @@
 package middleware
 
 import (
+	"strings"
+
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
-	"tzlev/internal/session"
+	"tzlev/internal/apperror"
+	"tzlev/internal/audit"
+	"tzlev/internal/auth"
+	"tzlev/internal/metrics"
+	"tzlev/internal/service"
 )
 
+// Auth rejects requests without a valid session, bearer token or API key and
+// puts the caller's auth.Identity into the request context
 func Auth() func(r *ghttp.Request) {
-	sessionManager := session.NewSessionManager()
+	sessionService := service.NewSessionService()
+	tokenService := service.NewTokenService()
+	apiKeyService := service.NewAPIKeyService()
 
 	return func(r *ghttp.Request) {
 		ctx := r.Context()
 
+		// Scripts and service accounts authenticate with an API key
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			identity, err := apiKeyService.Authenticate(ctx, apiKey)
+			metrics.RecordAuthentication(auth.MethodAPIKey, err == nil)
+			if err != nil {
+				g.Log().Warning(ctx, "Invalid API key:", err)
+				WriteError(r, apperror.Unauthorized("Invalid or expired API key"))
+				return
+			}
+
+			r.SetCtx(auth.WithIdentity(ctx, identity))
+			r.Middleware.Next()
+			return
+		}
+
+		// API clients authenticate with an access token instead of the cookie
+		if header := r.Header.Get("Authorization"); header != "" {
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				WriteError(r, apperror.Unauthorized("Unsupported authorization scheme"))
+				return
+			}
+
+			identity, err := tokenService.Authenticate(ctx, strings.TrimSpace(token))
+			metrics.RecordAuthentication(auth.MethodToken, err == nil)
+			if err != nil {
+				g.Log().Warning(ctx, "Invalid bearer token:", err)
+				r.Response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				WriteError(r, apperror.Unauthorized("Invalid or expired token"))
+				return
+			}
+
+			r.SetCtx(auth.WithIdentity(ctx, identity))
+			r.Middleware.Next()
+			return
+		}
+
 		// Get session ID from cookie
 		sessionID, err := r.Session.Id()
 		if err != nil || sessionID == "" {
-			r.Response.Status = 401
-			r.Response.WriteJson(g.Map{
-				"error": "Not authenticated",
-			})
+			WriteError(r, apperror.Unauthorized("Not authenticated"))
 			return
 		}
 
 		// Verify session in Redis
-		sess, err := sessionManager.Get(ctx, sessionID)
+		identity, err := sessionService.Resolve(ctx, sessionID, auth.ClientIP(r))
+		metrics.RecordAuthentication("session", err == nil)
 		if err != nil {
 			g.Log().Warning(ctx, "Invalid session:", err)
-			r.Response.Status = 401
-			r.Response.WriteJson(g.Map{
-				"error": "Invalid or expired session",
-			})
+			WriteError(r, apperror.Unauthorized("Invalid or expired session"))
 			return
 		}
 
-		// Refresh session TTL
-		if err := sessionManager.Refresh(ctx, sessionID); err != nil {
-			g.Log().Warning(ctx, "Failed to refresh session:", err)
+		// Users the 2FA policy applies to may only set it up until they have
+		if identity.MFAEnrollmentRequired && !allowedDuringEnrollment(r.URL.Path) {
+			WriteError(r, apperror.Forbidden("Two-factor authentication must be set up first").With("mfa_enrollment_required", true))
+			return
 		}
 
-		// Store user info in request context
-		r.SetCtxVar("user_id", sess.UserID)
-		r.SetCtxVar("user_email", sess.Email)
-		r.SetCtxVar("user_name", sess.Name)
+		// Store the identity in request context
+		r.SetCtx(auth.WithIdentity(ctx, identity))
 
 		r.Middleware.Next()
+
+		// Everything done while impersonating is traceable to the staff member
+		if identity.IsImpersonated() {
+			audit.Record(r.Context(), audit.Entry{
+				Action: "impersonation.request",
+				Details: g.Map{
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"status": r.Response.Status,
+				},
+			})
+		}
 	}
 }
+
+// allowedDuringEnrollment reports whether a session that still has to enroll
+// in two-factor authentication may call path
+func allowedDuringEnrollment(path string) bool {
+	return path == "/api/me" || path == "/api/me/2fa" || strings.HasPrefix(path, "/api/me/2fa/")
+}
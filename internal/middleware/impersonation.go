@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"tzlev/internal/apperror"
+	"tzlev/internal/auth"
+)
+
+// DenyImpersonation rejects sensitive requests made from an impersonation
+// session, such as changing credentials or permissions. It must run after Auth.
+func DenyImpersonation() func(r *ghttp.Request) {
+	return func(r *ghttp.Request) {
+		ctx := r.Context()
+
+		identity := auth.IdentityFromContext(ctx)
+		if identity != nil && identity.IsImpersonated() {
+			g.Log().Warningf(ctx, "Denied %s %s to %s impersonating %s", r.Method, r.URL.Path, identity.Impersonator.Zehut, identity.Zehut)
+			WriteError(r, apperror.Forbidden("Not allowed while impersonating a user").With("impersonating", true))
+			return
+		}
+
+		r.Middleware.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"tzlev/internal/apperror"
+	"tzlev/internal/auth"
+	"tzlev/internal/model"
+	"tzlev/internal/service"
+)
+
+// RequirePermission allows the request through only if the authenticated user
+// may perform action on the named app resource. It must run after Auth.
+func RequirePermission(resource string, action model.PermissionAction) func(r *ghttp.Request) {
+	permissionService := service.NewPermissionService()
+
+	return func(r *ghttp.Request) {
+		ctx := r.Context()
+
+		identity := auth.IdentityFromContext(ctx)
+		if identity == nil {
+			WriteError(r, apperror.Unauthorized("Not authenticated"))
+			return
+		}
+
+		// API keys never exceed their scopes, whatever their owner may do
+		if !identity.ScopeAllows(resource, action) {
+			g.Log().Warningf(ctx, "Permission denied: API key %d is not scoped to %s %s", identity.APIKeyID, action, resource)
+			WriteError(r, apperror.Forbidden("Permission denied"))
+			return
+		}
+
+		// Service account keys have no user behind them; their scopes are all they hold
+		if identity.Zehut == "" && identity.IsAPIKey() {
+			r.Middleware.Next()
+			return
+		}
+
+		zehut := identity.Zehut
+		allowed, err := permissionService.HasPermission(ctx, zehut, resource, action)
+		if err != nil {
+			WriteError(r, apperror.Internal(err, "Failed to check permissions"))
+			return
+		}
+
+		if !allowed {
+			g.Log().Warningf(ctx, "Permission denied: %s cannot %s %s", zehut, action, resource)
+			WriteError(r, apperror.Forbidden("Permission denied"))
+			return
+		}
+
+		r.Middleware.Next()
+	}
+}
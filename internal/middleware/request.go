@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/guid"
+	"tzlev/internal/auth"
+	"tzlev/internal/logging"
+	"tzlev/internal/metrics"
+)
+
+// maxRequestIDLength bounds the X-Request-ID values accepted from clients
+const maxRequestIDLength = 128
+
+// RequestID tags the request with the X-Request-ID sent by the client, or with
+// its trace ID when there is none, and returns it in the response. Log lines,
+// audit entries and error responses carry the ID.
+func RequestID() func(r *ghttp.Request) {
+	return func(r *ghttp.Request) {
+		ctx := r.Context()
+
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = gctx.CtxId(ctx)
+		}
+		if id == "" {
+			id = guid.S()
+		}
+
+		r.SetCtx(logging.WithRequestID(ctx, id))
+		r.Response.Header().Set("X-Request-ID", id)
+		r.Middleware.Next()
+	}
+}
+
+// AccessLog logs every request with its route, status and latency once it has
+// been served. It must run after RequestID.
+func AccessLog() func(r *ghttp.Request) {
+	return func(r *ghttp.Request) {
+		start := time.Now()
+
+		r.Middleware.Next()
+
+		g.Log().Info(r.Context(), "Request completed", logging.Fields{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"status":     responseStatus(r),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"ip":         auth.ClientIP(r),
+		})
+	}
+}
+
+// Metrics records the latency of every request by route for Prometheus
+func Metrics() func(r *ghttp.Request) {
+	return func(r *ghttp.Request) {
+		start := time.Now()
+
+		r.Middleware.Next()
+
+		route := "unmatched"
+		if r.Router != nil {
+			route = r.Router.Uri
+		}
+		metrics.ObserveRequest(r.Method, route, responseStatus(r), time.Since(start))
+	}
+}
+
+// responseStatus is the status code sent for r; handlers that set none send 200
+func responseStatus(r *ghttp.Request) int {
+	if r.Response.Status == 0 {
+		return 200
+	}
+	return r.Response.Status
+}
+
+// validRequestID accepts IDs that are safe to put into logs and headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
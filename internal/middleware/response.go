@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"tzlev/internal/apperror"
+	"tzlev/internal/logging"
+)
+
+// Response is the envelope of every successful JSON API response. The fields
+// of the handler's response struct are merged into it, so clients read e.g.
+// {"success": true, "users": [...]} rather than a nested data object.
+type Response struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// Problem is the RFC 7807 body of a failed request. Extension members of the
+// error, such as retry_after, are added next to these fields.
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail"`
+	Instance  string                `json:"instance"`
+	RequestID string                `json:"request_id"`
+	Errors    []apperror.FieldError `json:"errors,omitempty"`
+}
+
+// HandlerResponse writes the result of typed handlers. Errors are written by
+// WriteError. Handlers that wrote the response themselves, like file downloads
+// and redirects, are left alone.
+func HandlerResponse() func(r *ghttp.Request) {
+	return func(r *ghttp.Request) {
+		r.Middleware.Next()
+
+		if r.Response.BufferLength() > 0 || r.Response.BytesWritten() > 0 {
+			return
+		}
+		if r.Response.Status >= 300 && r.Response.Status < 400 {
+			return
+		}
+
+		if err := r.GetError(); err != nil {
+			WriteError(r, err)
+			return
+		}
+
+		body := map[string]json.RawMessage{}
+		if res := r.GetHandlerResponse(); res != nil {
+			data, err := json.Marshal(res)
+			if err == nil {
+				err = json.Unmarshal(data, &body)
+			}
+			if err != nil {
+				WriteError(r, apperror.Internal(err, "Failed to encode response"))
+				return
+			}
+		}
+		body["success"] = json.RawMessage("true")
+
+		r.Response.WriteJson(body)
+	}
+}
+
+// WriteError replaces the response with the problem details of err. Server
+// errors are logged with their cause; the client only gets the request ID to
+// quote when reporting them.
+func WriteError(r *ghttp.Request, err error) {
+	ctx := r.Context()
+	appErr := apperror.From(err)
+
+	if appErr.Status >= http.StatusInternalServerError {
+		g.Log().Errorf(ctx, "%s %s: %v", r.Method, r.URL.Path, err)
+	}
+
+	// Request validation names fields as in Go; clients know them by JSON name
+	fields := make([]apperror.FieldError, len(appErr.Fields))
+	for i, field := range appErr.Fields {
+		fields[i] = apperror.FieldError{Field: requestFieldName(r, field.Field), Message: field.Message}
+	}
+
+	body := g.Map{}
+	for key, value := range appErr.Extensions {
+		body[key] = value
+	}
+	data, _ := json.Marshal(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(appErr.Status),
+		Status:    appErr.Status,
+		Detail:    appErr.Detail,
+		Instance:  r.URL.Path,
+		RequestID: logging.RequestID(ctx),
+		Errors:    fields,
+	})
+	_ = json.Unmarshal(data, &body)
+	content, _ := json.Marshal(body)
+
+	r.Response.ClearBuffer()
+	r.Response.Header().Set("Content-Type", "application/problem+json")
+	r.Response.Status = appErr.Status
+	r.Response.Write(content)
+}
+
+// TokenErrors adds the RFC 6749 error code, such as invalid_grant, to the
+// problem details of failed token requests. It goes inside HandlerResponse.
+func TokenErrors() func(r *ghttp.Request) {
+	return func(r *ghttp.Request) {
+		r.Middleware.Next()
+
+		err := r.GetError()
+		if err == nil {
+			return
+		}
+
+		appErr := apperror.From(err)
+		if _, ok := appErr.Extensions["error"]; !ok {
+			appErr.With("error", tokenErrorCode(appErr.Status))
+		}
+		r.SetError(appErr)
+	}
+}
+
+func tokenErrorCode(status int) string {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return "server_error"
+	case status == http.StatusBadRequest:
+		return "invalid_request"
+	default:
+		// Wrong credentials, frozen accounts and lockouts alike
+		return "invalid_grant"
+	}
+}
+
+// requestFieldName returns the JSON name of a field of the typed handler's
+// request struct, or name itself if there is no such field
+func requestFieldName(r *ghttp.Request, name string) string {
+	handler := r.GetServeHandler()
+	if handler == nil || !handler.Handler.Info.IsStrictRoute {
+		return name
+	}
+
+	field, ok := handler.Handler.Info.Type.In(1).Elem().FieldByName(name)
+	if !ok {
+		return name
+	}
+	if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+		return tag
+	}
+	return name
+}
@@ -0,0 +1,188 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates a migrations directory with the given files
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"000002_add_email.up.sql":        "ALTER TABLE users ADD email TEXT;",
+		"000002_add_email.down.sql":      "ALTER TABLE users DROP email;",
+		"000001_create_users.up.sql":     "CREATE TABLE users (zehut TEXT);",
+		"000001_create_users.down.sql":   "DROP TABLE users;",
+		"000010_seed_roles.up.sql":       "INSERT INTO roles VALUES ('teacher');",
+		"README.md":                      "not a migration",
+		"000003_bad-name.up.sql":         "ignored: the name has a dash",
+		"000004_no_direction.sql":        "ignored: no direction",
+		"000005_wrong_suffix.up.sql.bak": "ignored: wrong suffix",
+	})
+
+	migrations, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		version int64
+		name    string
+		hasDown bool
+	}{
+		{1, "create_users", true},
+		{2, "add_email", true},
+		{10, "seed_roles", false},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("loaded %d migrations, want %d", len(migrations), len(want))
+	}
+	for i, w := range want {
+		mig := migrations[i]
+		if mig.Version != w.version || mig.Name != w.name || mig.HasDown() != w.hasDown {
+			t.Errorf("migration %d = %d_%s (down %v), want %d_%s (down %v)",
+				i, mig.Version, mig.Name, mig.HasDown(), w.version, w.name, w.hasDown)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "down without up",
+			files: map[string]string{"000001_create_users.down.sql": "DROP TABLE users;"},
+			want:  "has no up file",
+		},
+		{
+			name: "version used twice",
+			files: map[string]string{
+				"000001_create_users.up.sql":   "CREATE TABLE users (zehut TEXT);",
+				"000001_create_groups.up.sql":  "CREATE TABLE groups (id INT);",
+				"000001_create_users.down.sql": "DROP TABLE users;",
+			},
+			want: "is used by both",
+		},
+		{
+			name:  "version out of range",
+			files: map[string]string{"99999999999999999999_huge.up.sql": "SELECT 1;"},
+			want:  "invalid migration version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeFiles(t, tt.files))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMissingDirectory(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Load() of a missing directory succeeded")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	const up = "CREATE TABLE users (zehut TEXT);"
+	const down = "DROP TABLE users;"
+
+	load := func(files map[string]string) *Migration {
+		t.Helper()
+		migrations, err := Load(writeFiles(t, files))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return migrations[0]
+	}
+
+	mig := load(map[string]string{"000001_users.up.sql": up, "000001_users.down.sql": down})
+
+	// Migrations applied before down scripts were checksummed recorded this
+	sum := sha256.Sum256([]byte(up))
+	legacy := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		recorded string
+		want     bool
+	}{
+		{"current checksum", mig.Checksum, true},
+		{"up-only checksum", legacy, true},
+		{"edited up script", load(map[string]string{"000001_users.up.sql": up + " ", "000001_users.down.sql": down}).Checksum, false},
+		{"edited down script", load(map[string]string{"000001_users.up.sql": up, "000001_users.down.sql": down + " "}).Checksum, false},
+		{"down script removed", load(map[string]string{"000001_users.up.sql": up}).Checksum, false},
+		{"scripts swapped at the boundary", checksum(up+down[:4], down[4:]), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mig.matches(appliedMigration{Version: 1, Checksum: tt.recorded}); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	migrations := []*Migration{
+		{Version: 1, Name: "users", Checksum: "a", upChecksum: "a-up"},
+		{Version: 2, Name: "groups", Checksum: "b", upChecksum: "b-up"},
+	}
+
+	tests := []struct {
+		name    string
+		applied map[int64]appliedMigration
+		wantErr bool
+	}{
+		{"nothing applied", map[int64]appliedMigration{}, false},
+		{"matching", map[int64]appliedMigration{1: {Checksum: "a"}, 2: {Checksum: "b-up"}}, false},
+		{"modified", map[int64]appliedMigration{1: {Checksum: "a"}, 2: {Checksum: "c"}}, true},
+		{"applied without files", map[int64]appliedMigration{3: {Checksum: "c"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyChecksums(migrations, tt.applied); (err != nil) != tt.wantErr {
+				t.Errorf("verifyChecksums() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewestFirst(t *testing.T) {
+	applied := map[int64]appliedMigration{
+		3:  {Version: 3},
+		10: {Version: 10},
+		1:  {Version: 1},
+	}
+
+	var versions []int64
+	for _, a := range newestFirst(applied) {
+		versions = append(versions, a.Version)
+	}
+	if len(versions) != 3 || versions[0] != 10 || versions[1] != 3 || versions[2] != 1 {
+		t.Errorf("newestFirst() versions = %v, want [10 3 1]", versions)
+	}
+}
@@ -0,0 +1,439 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	// trackingTable records every applied migration
+	trackingTable = "schema_migrations"
+
+	// advisoryLockKey is the Postgres advisory lock held while migrating,
+	// so concurrent deploys run one after another instead of interleaving
+	advisoryLockKey int64 = 7318261049
+
+	// noTransactionDirective disables the per-migration transaction, which is
+	// required for statements such as CREATE INDEX CONCURRENTLY
+	noTransactionDirective = "-- migrate:no-transaction"
+)
+
+// Status describes the state of a single migration
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool // applied checksum differs from the file on disk
+	Missing   bool // applied in the database but the file no longer exists
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	dir string
+}
+
+func NewMigrator(dir string) *Migrator {
+	return &Migrator{
+		dir: dir,
+	}
+}
+
+// Up applies pending migrations in order. A steps value of 0 applies all of them.
+func (m *Migrator) Up(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration, applied map[int64]appliedMigration) error {
+		if err := verifyChecksums(migrations, applied); err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if steps > 0 && len(done) >= steps {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the most recently applied migrations. A steps value of 0 rolls back one.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var done []*Migration
+	err := m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration, applied map[int64]appliedMigration) error {
+		if err := verifyChecksums(migrations, applied); err != nil {
+			return err
+		}
+
+		for _, a := range newestFirst(applied) {
+			if len(done) >= steps {
+				break
+			}
+			mig := findMigration(migrations, a.Version)
+			if mig == nil {
+				return missingFiles(a)
+			}
+			if err := m.revert(ctx, conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Redo rolls back the latest applied migration and applies it again
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+	err := m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration, applied map[int64]appliedMigration) error {
+		if err := verifyChecksums(migrations, applied); err != nil {
+			return err
+		}
+
+		latest := newestFirst(applied)
+		if len(latest) == 0 {
+			return fmt.Errorf("no applied migrations to redo")
+		}
+		if redone = findMigration(migrations, latest[0].Version); redone == nil {
+			return missingFiles(latest[0])
+		}
+
+		if err := m.revert(ctx, conn, redone); err != nil {
+			return err
+		}
+		return m.apply(ctx, conn, redone)
+	})
+	return redone, err
+}
+
+// Goto migrates up or down until version is the latest applied migration.
+// A version of 0 rolls back every migration.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *sql.Conn, migrations []*Migration, applied map[int64]appliedMigration) error {
+		if version != 0 && findMigration(migrations, version) == nil {
+			return fmt.Errorf("migration %d does not exist", version)
+		}
+		if err := verifyChecksums(migrations, applied); err != nil {
+			return err
+		}
+
+		// Roll back everything above the target, newest first
+		for _, a := range newestFirst(applied) {
+			if a.Version <= version {
+				break
+			}
+			mig := findMigration(migrations, a.Version)
+			if mig == nil {
+				return missingFiles(a)
+			}
+			if err := m.revert(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+
+		// Apply everything up to and including the target
+		for _, mig := range migrations {
+			if mig.Version > version {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status returns the state of every known migration, including applied
+// migrations whose files have been removed. It only reads, so it does not wait
+// for the migration lock while a deploy is migrating.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := Load(m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := g.DB().Master()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	var tracked bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", trackingTable).Scan(&tracked); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]appliedMigration)
+	if tracked {
+		if applied, err = loadApplied(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []Status
+	for _, mig := range migrations {
+		status := Status{Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			appliedAt := a.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = !mig.matches(a)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, a := range applied {
+		if findMigration(migrations, a.Version) != nil {
+			continue
+		}
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, Status{
+			Version:   a.Version,
+			Name:      a.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// withLock loads the migration files, takes the advisory lock on a dedicated
+// connection and passes the current state to fn
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, migrations []*Migration, applied map[int64]appliedMigration) error) error {
+	migrations, err := Load(m.dir)
+	if err != nil {
+		return err
+	}
+
+	db, err := g.DB().Master()
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	// Advisory locks are held per connection, so everything runs on the same one
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	g.Log().Debug(ctx, "Waiting for migration lock")
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey); err != nil {
+			g.Log().Error(ctx, "Failed to release migration lock:", err)
+		}
+	}()
+
+	if err := ensureTrackingTable(ctx, conn); err != nil {
+		return err
+	}
+
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := upgradeChecksums(ctx, conn, migrations, applied); err != nil {
+		return err
+	}
+
+	return fn(conn, migrations, applied)
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig *Migration) error {
+	g.Log().Infof(ctx, "Applying migration %d_%s", mig.Version, mig.Name)
+
+	err := runScript(ctx, conn, mig.UpSQL, func(exec execer) error {
+		_, err := exec.ExecContext(ctx,
+			"INSERT INTO "+trackingTable+" (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)",
+			mig.Version, mig.Name, mig.Checksum, time.Now(),
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, mig *Migration) error {
+	if !mig.HasDown() {
+		return fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+	}
+
+	g.Log().Infof(ctx, "Reverting migration %d_%s", mig.Version, mig.Name)
+
+	err := runScript(ctx, conn, mig.DownSQL, func(exec execer) error {
+		_, err := exec.ExecContext(ctx, "DELETE FROM "+trackingTable+" WHERE version = $1", mig.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("rollback of %d_%s failed: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// runScript executes script and then record, inside a single transaction
+// unless the script opts out with the no-transaction directive
+func runScript(ctx context.Context, conn *sql.Conn, script string, record func(exec execer) error) error {
+	if strings.HasPrefix(strings.TrimSpace(script), noTransactionDirective) {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		return record(conn)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := record(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func ensureTrackingTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+trackingTable+` (
+		version    BIGINT PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		checksum   VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create %s table: %w", trackingTable, err)
+	}
+	return nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func loadApplied(ctx context.Context, db queryer) (map[int64]appliedMigration, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+trackingTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[a.Version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+// matches reports whether a was applied from the current files of mig
+func (mig *Migration) matches(a appliedMigration) bool {
+	return a.Checksum == mig.Checksum || a.Checksum == mig.upChecksum
+}
+
+// upgradeChecksums records the full checksum of migrations that were applied
+// when only the up script was checksummed, so that later edits to their down
+// scripts are noticed too
+func upgradeChecksums(ctx context.Context, conn *sql.Conn, migrations []*Migration, applied map[int64]appliedMigration) error {
+	for _, mig := range migrations {
+		a, ok := applied[mig.Version]
+		if !ok || a.Checksum == mig.Checksum || a.Checksum != mig.upChecksum {
+			continue
+		}
+
+		_, err := conn.ExecContext(ctx, "UPDATE "+trackingTable+" SET checksum = $1 WHERE version = $2", mig.Checksum, mig.Version)
+		if err != nil {
+			return fmt.Errorf("failed to update checksum of %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		a.Checksum = mig.Checksum
+		applied[mig.Version] = a
+	}
+	return nil
+}
+
+// verifyChecksums refuses to continue when an applied migration was edited after the fact
+func verifyChecksums(migrations []*Migration, applied map[int64]appliedMigration) error {
+	for _, mig := range migrations {
+		a, ok := applied[mig.Version]
+		if ok && !mig.matches(a) {
+			return fmt.Errorf("migration %d_%s was modified after it was applied (checksum mismatch)", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// newestFirst returns the applied migrations, latest version first
+func newestFirst(applied map[int64]appliedMigration) []appliedMigration {
+	sorted := make([]appliedMigration, 0, len(applied))
+	for _, a := range applied {
+		sorted = append(sorted, a)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version > sorted[j].Version
+	})
+	return sorted
+}
+
+// missingFiles is the error for an applied migration that cannot be rolled
+// back because its files were removed
+func missingFiles(a appliedMigration) error {
+	return fmt.Errorf("migration %d_%s is applied but its files are missing; restore them to roll it back", a.Version, a.Name)
+}
+
+func findMigration(migrations []*Migration, version int64) *Migration {
+	for _, mig := range migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+// APIKey is a long-lived credential for scripts and service accounts. Only the
+// SHA-256 hash of the key is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID             int64      `json:"id" orm:"id"`
+	Name           string     `json:"name" orm:"name"`
+	Prefix         string     `json:"prefix" orm:"prefix"`
+	KeyHash        string     `json:"-" orm:"key_hash"`
+	Zehut          *string    `json:"zehut,omitempty" orm:"zehut"`                     // Owning user
+	ServiceAccount *string    `json:"service_account,omitempty" orm:"service_account"` // Or owning service account
+	CreatedBy      string     `json:"created_by" orm:"created_by"`
+	ExpiresAt      time.Time  `json:"expires_at" orm:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty" orm:"last_used_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty" orm:"revoked_at"`
+	InsertedAt     time.Time  `json:"inserted_at" orm:"inserted_at"`
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil && time.Now().Before(k.ExpiresAt)
+}
+
+// APIKeyScope matches the api_key_scopes table and limits what a key may do on a resource
+type APIKeyScope struct {
+	APIKeyID   int64  `json:"api_key_id" orm:"api_key_id"`
+	ResourceID string `json:"resource_id" orm:"resource_id"`
+	PermissionFlags
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+// AuditLogEntry is a stored audit record: who did what to which record, from where
+type AuditLogEntry struct {
+	ID           int64                  `json:"id" orm:"id"`
+	Actor        string                 `json:"actor" orm:"actor"`
+	Impersonator string                 `json:"impersonator,omitempty" orm:"impersonator"`
+	Action       string                 `json:"action" orm:"action"`
+	Entity       string                 `json:"entity" orm:"entity"`
+	EntityID     string                 `json:"entity_id" orm:"entity_id"`
+	Changes      map[string]interface{} `json:"changes,omitempty" orm:"changes"`
+	Details      map[string]interface{} `json:"details,omitempty" orm:"details"`
+	IP           string                 `json:"ip" orm:"ip"`
+	RequestID    string                 `json:"request_id" orm:"request_id"`
+	InsertedAt   time.Time              `json:"inserted_at" orm:"inserted_at"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// Group is a named set of users that permissions can be granted to
+type Group struct {
+	ID          int64     `json:"id" orm:"id"`
+	Name        string    `json:"name" orm:"name"`
+	Description string    `json:"description,omitempty" orm:"description"`
+	InsertedAt  time.Time `json:"inserted_at" orm:"inserted_at"`
+	UpdatedAt   time.Time `json:"updated_at" orm:"updated_at"`
+}
+
+// GroupMember links a user to a group
+type GroupMember struct {
+	GroupID    int64     `json:"group_id" orm:"group_id"`
+	Zehut      string    `json:"zehut" orm:"zehut"`
+	InsertedAt time.Time `json:"inserted_at" orm:"inserted_at"`
+}
+
+// GroupMemberUser is a member of a group as listed to group managers, without
+// the contact and payroll fields of the user
+type GroupMemberUser struct {
+	Zehut     string `json:"zehut" orm:"zehut"`
+	FirstName string `json:"first_name" orm:"first_name"`
+	LastName  string `json:"last_name" orm:"last_name"`
+	Email     string `json:"email" orm:"email"`
+}
@@ -0,0 +1,65 @@
+package model
+
+// PermissionAction is an operation that can be granted on an app resource
+type PermissionAction string
+
+const (
+	ActionView   PermissionAction = "view"
+	ActionCreate PermissionAction = "create"
+	ActionEdit   PermissionAction = "edit"
+	ActionDelete PermissionAction = "delete"
+)
+
+// PermissionFlags holds the actions granted on a single app resource
+type PermissionFlags struct {
+	CanView   bool `json:"can_view" orm:"can_view"`
+	CanCreate bool `json:"can_create" orm:"can_create"`
+	CanEdit   bool `json:"can_edit" orm:"can_edit"`
+	CanDelete bool `json:"can_delete" orm:"can_delete"`
+}
+
+// Allows reports whether action is granted
+func (f PermissionFlags) Allows(action PermissionAction) bool {
+	switch action {
+	case ActionView:
+		return f.CanView
+	case ActionCreate:
+		return f.CanCreate
+	case ActionEdit:
+		return f.CanEdit
+	case ActionDelete:
+		return f.CanDelete
+	default:
+		return false
+	}
+}
+
+// Merge returns the union of both sets of flags
+func (f PermissionFlags) Merge(other PermissionFlags) PermissionFlags {
+	return PermissionFlags{
+		CanView:   f.CanView || other.CanView,
+		CanCreate: f.CanCreate || other.CanCreate,
+		CanEdit:   f.CanEdit || other.CanEdit,
+		CanDelete: f.CanDelete || other.CanDelete,
+	}
+}
+
+// GroupPermission matches the group_permissions table
+type GroupPermission struct {
+	GroupID    int64  `json:"group_id" orm:"group_id"`
+	ResourceID string `json:"resource_id" orm:"resource_id"`
+	PermissionFlags
+}
+
+// UserPermission matches the user_permissions table and grants actions to a single user
+type UserPermission struct {
+	Zehut      string `json:"zehut" orm:"zehut"`
+	ResourceID string `json:"resource_id" orm:"resource_id"`
+	PermissionFlags
+}
+
+// ResourcePermission is the effective permission of a user on a resource, keyed by resource name
+type ResourcePermission struct {
+	Resource string `json:"resource" orm:"resource"`
+	PermissionFlags
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// UserTOTP holds a user's TOTP secret. Two-factor authentication is enabled
+// once the enrollment is confirmed with a valid code.
+type UserTOTP struct {
+	Zehut        string     `json:"zehut" orm:"zehut"`
+	Secret       string     `json:"-" orm:"secret"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty" orm:"confirmed_at"`
+	LastUsedStep int64      `json:"-" orm:"last_used_step"`
+	InsertedAt   time.Time  `json:"inserted_at" orm:"inserted_at"`
+}
+
+// IsConfirmed reports whether enrollment was completed
+func (t *UserTOTP) IsConfirmed() bool {
+	return t.ConfirmedAt != nil
+}
+
+// RecoveryCode is a single-use code for logging in without the authenticator.
+// Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID         int64      `json:"id" orm:"id"`
+	Zehut      string     `json:"zehut" orm:"zehut"`
+	CodeHash   string     `json:"-" orm:"code_hash"`
+	UsedAt     *time.Time `json:"used_at,omitempty" orm:"used_at"`
+	InsertedAt time.Time  `json:"inserted_at" orm:"inserted_at"`
+}
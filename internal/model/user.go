@@ -45,6 +45,7 @@ type User struct {
 	EncryptPassword    string     `json:"-" orm:"encrypt_password"` // Don't serialize
 	CanUpdateDocs      bool       `json:"can_update_docs" orm:"can_update_docs"`
 	EmailToken         string     `json:"-" orm:"email_token"` // Don't serialize token
+	EmailTokenSentAt   *time.Time `json:"-" orm:"email_token_sent_at"`
 	Color              string     `json:"color,omitempty" orm:"color"`
 	LicenceNumber      string     `json:"licence_number,omitempty" orm:"licence_number"`
 	CanUpdateDisciplines bool     `json:"can_update_disciplines" orm:"can_update_disciplines"`
@@ -53,3 +54,8 @@ type User struct {
 	IsTechnical        bool       `json:"is_technical" orm:"is_technical"`
 	CanSeeSensitiveDocs bool      `json:"can_see_sensitive_docs" orm:"can_see_sensitive_docs"`
 }
+
+// IsFrozen reports whether the account has been frozen and must not log in
+func (u *User) IsFrozen() bool {
+	return u.IsFreezed != nil && *u.IsFreezed
+}
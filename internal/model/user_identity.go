@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// UserIdentity links a user to their account at an external identity provider.
+// Subject is the provider's stable user ID; it never changes, unlike the email address.
+type UserIdentity struct {
+	ID          int64      `json:"id" orm:"id"`
+	Zehut       string     `json:"zehut" orm:"zehut"`
+	Provider    string     `json:"provider" orm:"provider"`
+	Subject     string     `json:"subject" orm:"subject"`
+	Email       string     `json:"email" orm:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" orm:"last_login_at"`
+	InsertedAt  time.Time  `json:"inserted_at" orm:"inserted_at"`
+}
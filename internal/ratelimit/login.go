@@ -0,0 +1,275 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/guid"
+	goredis "github.com/redis/go-redis/v9"
+
+	"tzlev/internal/redis"
+)
+
+// Subject kinds tracked by the login limiter
+const (
+	SubjectZehut = "zehut"
+	SubjectIP    = "ip"
+)
+
+// Lockout describes an active lockout of a zehut or client IP
+type Lockout struct {
+	Subject    string        `json:"subject"`
+	Value      string        `json:"value"`
+	RetryAfter time.Duration `json:"-"`
+}
+
+// LoginLimiter counts login attempts per zehut and per client IP in Redis
+// sliding windows and locks a subject out once it exceeds its limit. Attempts
+// are counted before the credentials are checked and taken back when they
+// succeed, so only failed and in-flight attempts count.
+type LoginLimiter struct {
+	prefix           string
+	window           time.Duration
+	maxAttemptsZehut int
+	maxAttemptsIP    int
+	lockoutDuration  time.Duration
+	delayAfter       int
+	baseDelay        time.Duration
+	maxDelay         time.Duration
+}
+
+func NewLoginLimiter() *LoginLimiter {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	return &LoginLimiter{
+		prefix:           "tzlev:login:",
+		window:           cfg.MustGet(ctx, "security.login.window", "15m").Duration(),
+		maxAttemptsZehut: cfg.MustGet(ctx, "security.login.maxAttemptsPerZehut", 5).Int(),
+		maxAttemptsIP:    cfg.MustGet(ctx, "security.login.maxAttemptsPerIP", 50).Int(),
+		lockoutDuration:  cfg.MustGet(ctx, "security.login.lockoutDuration", "15m").Duration(),
+		delayAfter:       cfg.MustGet(ctx, "security.login.delayAfter", 2).Int(),
+		baseDelay:        cfg.MustGet(ctx, "security.login.baseDelay", "500ms").Duration(),
+		maxDelay:         cfg.MustGet(ctx, "security.login.maxDelay", "8s").Duration(),
+	}
+}
+
+func (l *LoginLimiter) failuresKey(subject, value string) string {
+	return fmt.Sprintf("%sfailures:%s:%s", l.prefix, subject, value)
+}
+
+func (l *LoginLimiter) lockKey(subject, value string) string {
+	return fmt.Sprintf("%slock:%s:%s", l.prefix, subject, value)
+}
+
+func (l *LoginLimiter) limit(subject string) int {
+	if subject == SubjectIP {
+		return l.maxAttemptsIP
+	}
+	return l.maxAttemptsZehut
+}
+
+// beginScript admits a login attempt atomically for all of its subjects. For
+// each subject pair of KEYS (lock, failures) it refuses the attempt if the lock
+// is set or the window already holds the subject's limit of attempts, returning
+// the index of the subject and the milliseconds until it may retry. Otherwise
+// it adds the attempt to every window and returns the number of attempts that
+// were in the first one before it.
+//
+// ARGV: now (ns), window start (ns), window (ms), attempt ID, limit per subject
+var beginScript = goredis.NewScript(`
+local now = tonumber(ARGV[1])
+local windowMs = tonumber(ARGV[3])
+local previous = 0
+
+for i = 1, #KEYS / 2 do
+	local lock = KEYS[2 * i - 1]
+	local failures = KEYS[2 * i]
+
+	local ttl = redis.call('PTTL', lock)
+	if ttl > 0 then
+		return {i, ttl}
+	end
+
+	redis.call('ZREMRANGEBYSCORE', failures, '-inf', ARGV[2])
+	local count = redis.call('ZCARD', failures)
+	if count >= tonumber(ARGV[4 + i]) then
+		local oldest = redis.call('ZRANGE', failures, 0, 0, 'WITHSCORES')
+		local retry = math.ceil((tonumber(oldest[2]) - now) / 1000000) + windowMs
+		return {i, math.max(retry, 1)}
+	end
+	if i == 1 then
+		previous = count
+	end
+end
+
+for i = 1, #KEYS / 2 do
+	redis.call('ZADD', KEYS[2 * i], ARGV[1], ARGV[4])
+	redis.call('PEXPIRE', KEYS[2 * i], windowMs)
+end
+
+return {0, previous}
+`)
+
+// Attempt is a login attempt admitted by Begin. It counts towards the limits
+// from the start, so concurrent guesses cannot get past them; Release takes
+// back an attempt that did not fail.
+type Attempt struct {
+	id       string
+	subjects []subject
+
+	// Previous is the number of earlier attempts for the zehut in the window
+	Previous int
+}
+
+type subject struct {
+	kind, value string
+}
+
+func loginSubjects(zehut, ip string) []subject {
+	var subjects []subject
+	for _, s := range []subject{{SubjectZehut, zehut}, {SubjectIP, ip}} {
+		if s.value != "" {
+			subjects = append(subjects, s)
+		}
+	}
+	return subjects
+}
+
+// Begin admits a login attempt for the zehut and the client IP before the
+// credentials are checked. It returns the lockout instead if either subject is
+// locked out or already has its limit of attempts in the window.
+func (l *LoginLimiter) Begin(ctx context.Context, zehut, ip string) (*Attempt, *Lockout, error) {
+	attempt := &Attempt{id: guid.S(), subjects: loginSubjects(zehut, ip)}
+	if len(attempt.subjects) == 0 {
+		return attempt, nil, nil
+	}
+
+	now := time.Now()
+	keys := make([]string, 0, 2*len(attempt.subjects))
+	args := []interface{}{
+		now.UnixNano(),
+		now.Add(-l.window).UnixNano(),
+		l.window.Milliseconds(),
+		attempt.id,
+	}
+	for _, s := range attempt.subjects {
+		keys = append(keys, l.lockKey(s.kind, s.value), l.failuresKey(s.kind, s.value))
+		args = append(args, l.limit(s.kind))
+	}
+
+	result, err := beginScript.Run(ctx, redis.Client, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if refused := result[0]; refused > 0 {
+		s := attempt.subjects[refused-1]
+		return nil, &Lockout{Subject: s.kind, Value: s.value, RetryAfter: time.Duration(result[1]) * time.Millisecond}, nil
+	}
+
+	if attempt.subjects[0].kind == SubjectZehut {
+		attempt.Previous = int(result[1])
+	}
+	return attempt, nil, nil
+}
+
+// Delay returns how long to hold an attempt before checking the credentials.
+// It doubles with every recent attempt for the zehut past the free ones.
+func (l *LoginLimiter) Delay(attempt *Attempt) time.Duration {
+	excess := attempt.Previous - l.delayAfter
+	if excess <= 0 {
+		return 0
+	}
+
+	delay := l.baseDelay
+	for i := 1; i < excess && delay < l.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > l.maxDelay {
+		delay = l.maxDelay
+	}
+
+	return delay
+}
+
+// RecordFailure keeps a failed attempt counted and returns the lockouts it
+// caused, if any
+func (l *LoginLimiter) RecordFailure(ctx context.Context, attempt *Attempt) ([]Lockout, error) {
+	var lockouts []Lockout
+
+	for _, s := range attempt.subjects {
+		failures, err := l.countFailures(ctx, s.kind, s.value)
+		if err != nil {
+			return lockouts, err
+		}
+		if failures < l.limit(s.kind) {
+			continue
+		}
+
+		// SetNX so repeated failures during a lockout do not extend it
+		locked, err := redis.Client.SetNX(ctx, l.lockKey(s.kind, s.value), failures, l.lockoutDuration).Result()
+		if err != nil {
+			return lockouts, err
+		}
+		if locked {
+			lockouts = append(lockouts, Lockout{Subject: s.kind, Value: s.value, RetryAfter: l.lockoutDuration})
+		}
+	}
+
+	return lockouts, nil
+}
+
+// Release takes back an attempt that did not fail, such as one with the right
+// password, so that it does not count towards the limits
+func (l *LoginLimiter) Release(ctx context.Context, attempt *Attempt) error {
+	pipe := redis.Client.TxPipeline()
+	for _, s := range attempt.subjects {
+		pipe.ZRem(ctx, l.failuresKey(s.kind, s.value), attempt.id)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Reset clears the failure history of a zehut after a successful login.
+// IP counters are left alone so one valid account cannot reset them.
+func (l *LoginLimiter) Reset(ctx context.Context, zehut string) error {
+	return redis.Client.Del(ctx, l.failuresKey(SubjectZehut, zehut)).Err()
+}
+
+// Clear lifts a lockout and forgets the failure history of a zehut or IP
+func (l *LoginLimiter) Clear(ctx context.Context, subject, value string) error {
+	return redis.Client.Del(ctx, l.lockKey(subject, value), l.failuresKey(subject, value)).Err()
+}
+
+// Status returns the number of recent failures and the remaining lockout time
+// of a zehut or IP
+func (l *LoginLimiter) Status(ctx context.Context, subject, value string) (int, time.Duration, error) {
+	failures, err := l.countFailures(ctx, subject, value)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ttl, err := redis.Client.PTTL(ctx, l.lockKey(subject, value)).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return failures, ttl, nil
+}
+
+func (l *LoginLimiter) countFailures(ctx context.Context, subject, value string) (int, error) {
+	min := strconv.FormatInt(time.Now().Add(-l.window).UnixNano(), 10)
+	count, err := redis.Client.ZCount(ctx, l.failuresKey(subject, value), min, "+inf").Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
@@ -0,0 +1,294 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"tzlev/internal/redis"
+)
+
+// useRedis points the shared Redis client at an in-memory server
+func useRedis(t *testing.T) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	previous := redis.Client
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		redis.Client.Close()
+		redis.Client = previous
+	})
+}
+
+func testLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{
+		prefix:           "test:login:",
+		window:           15 * time.Minute,
+		maxAttemptsZehut: 3,
+		maxAttemptsIP:    5,
+		lockoutDuration:  10 * time.Minute,
+		delayAfter:       2,
+		baseDelay:        500 * time.Millisecond,
+		maxDelay:         4 * time.Second,
+	}
+}
+
+func TestBeginThresholds(t *testing.T) {
+	type attempt struct {
+		zehut, ip   string
+		wantRefused string
+	}
+
+	tests := []struct {
+		name     string
+		attempts []attempt
+	}{
+		{
+			name: "zehut limit",
+			attempts: []attempt{
+				{"111", "10.0.0.1", ""},
+				{"111", "10.0.0.2", ""},
+				{"111", "10.0.0.3", ""},
+				{"111", "10.0.0.4", SubjectZehut},
+				{"222", "10.0.0.4", ""},
+			},
+		},
+		{
+			name: "ip limit",
+			attempts: []attempt{
+				{"111", "10.0.0.1", ""},
+				{"222", "10.0.0.1", ""},
+				{"333", "10.0.0.1", ""},
+				{"444", "10.0.0.1", ""},
+				{"555", "10.0.0.1", ""},
+				{"666", "10.0.0.1", SubjectIP},
+				{"666", "10.0.0.2", ""},
+			},
+		},
+		{
+			name: "refused attempts are not counted",
+			attempts: []attempt{
+				{"111", "10.0.0.1", ""},
+				{"111", "10.0.0.1", ""},
+				{"111", "10.0.0.1", ""},
+				{"111", "10.0.0.1", SubjectZehut},
+				{"111", "10.0.0.1", SubjectZehut},
+				{"222", "10.0.0.1", ""},
+				{"333", "10.0.0.1", ""},
+				{"444", "10.0.0.1", SubjectIP},
+			},
+		},
+		{
+			name: "no subjects",
+			attempts: []attempt{
+				{"", "", ""}, {"", "", ""}, {"", "", ""}, {"", "", ""}, {"", "", ""}, {"", "", ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRedis(t)
+			ctx := context.Background()
+			limiter := testLoginLimiter()
+
+			for i, a := range tt.attempts {
+				admitted, lockout, err := limiter.Begin(ctx, a.zehut, a.ip)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				switch {
+				case a.wantRefused == "" && lockout != nil:
+					t.Fatalf("attempt %d refused for %s, want it admitted", i, lockout.Subject)
+				case a.wantRefused == "" && admitted == nil:
+					t.Fatalf("attempt %d neither admitted nor refused", i)
+				case a.wantRefused != "" && lockout == nil:
+					t.Fatalf("attempt %d admitted, want it refused for %s", i, a.wantRefused)
+				case a.wantRefused != "" && lockout.Subject != a.wantRefused:
+					t.Fatalf("attempt %d refused for %s, want %s", i, lockout.Subject, a.wantRefused)
+				case a.wantRefused != "" && (lockout.RetryAfter <= 0 || lockout.RetryAfter > limiter.window):
+					t.Fatalf("attempt %d retry after %s, want within the window", i, lockout.RetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestBeginPrevious(t *testing.T) {
+	useRedis(t)
+	ctx := context.Background()
+	limiter := testLoginLimiter()
+
+	for want := 0; want < limiter.maxAttemptsZehut; want++ {
+		attempt, _, err := limiter.Begin(ctx, "111", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempt.Previous != want {
+			t.Errorf("attempt %d: Previous = %d, want %d", want, attempt.Previous, want)
+		}
+	}
+}
+
+func TestRecordFailureLocksOut(t *testing.T) {
+	useRedis(t)
+	ctx := context.Background()
+	limiter := testLoginLimiter()
+
+	for i := 1; i <= limiter.maxAttemptsZehut; i++ {
+		attempt, lockout, err := limiter.Begin(ctx, "111", "10.0.0.1")
+		if err != nil || lockout != nil {
+			t.Fatalf("attempt %d: lockout %v, error %v", i, lockout, err)
+		}
+
+		lockouts, err := limiter.RecordFailure(ctx, attempt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantLocked := i == limiter.maxAttemptsZehut
+		if got := len(lockouts) == 1 && lockouts[0].Subject == SubjectZehut; got != wantLocked || len(lockouts) > 1 {
+			t.Fatalf("failure %d: lockouts %+v, want zehut locked %v", i, lockouts, wantLocked)
+		}
+	}
+
+	_, lockout, err := limiter.Begin(ctx, "111", "10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lockout == nil || lockout.Subject != SubjectZehut || lockout.RetryAfter != limiter.lockoutDuration {
+		t.Fatalf("lockout = %+v, want the zehut locked for %s", lockout, limiter.lockoutDuration)
+	}
+
+	failures, ttl, err := limiter.Status(ctx, SubjectZehut, "111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures != limiter.maxAttemptsZehut || ttl != limiter.lockoutDuration {
+		t.Errorf("Status() = %d, %s; want %d, %s", failures, ttl, limiter.maxAttemptsZehut, limiter.lockoutDuration)
+	}
+
+	if err := limiter.Clear(ctx, SubjectZehut, "111"); err != nil {
+		t.Fatal(err)
+	}
+	if _, lockout, err := limiter.Begin(ctx, "111", "10.0.0.2"); err != nil || lockout != nil {
+		t.Errorf("after Clear: lockout %+v, error %v", lockout, err)
+	}
+}
+
+func TestReleaseDoesNotCount(t *testing.T) {
+	useRedis(t)
+	ctx := context.Background()
+	limiter := testLoginLimiter()
+
+	for i := 0; i < 2*limiter.maxAttemptsIP; i++ {
+		attempt, lockout, err := limiter.Begin(ctx, "111", "10.0.0.1")
+		if err != nil || lockout != nil {
+			t.Fatalf("attempt %d: lockout %+v, error %v", i, lockout, err)
+		}
+		if err := limiter.Release(ctx, attempt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, s := range []struct{ kind, value string }{{SubjectZehut, "111"}, {SubjectIP, "10.0.0.1"}} {
+		failures, _, err := limiter.Status(ctx, s.kind, s.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failures != 0 {
+			t.Errorf("%s has %d failures, want 0", s.kind, failures)
+		}
+	}
+}
+
+func TestResetKeepsIPFailures(t *testing.T) {
+	useRedis(t)
+	ctx := context.Background()
+	limiter := testLoginLimiter()
+
+	for i := 0; i < 2; i++ {
+		attempt, _, err := limiter.Begin(ctx, "111", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := limiter.RecordFailure(ctx, attempt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := limiter.Reset(ctx, "111"); err != nil {
+		t.Fatal(err)
+	}
+
+	if failures, _, _ := limiter.Status(ctx, SubjectZehut, "111"); failures != 0 {
+		t.Errorf("zehut has %d failures after Reset, want 0", failures)
+	}
+	if failures, _, _ := limiter.Status(ctx, SubjectIP, "10.0.0.1"); failures != 2 {
+		t.Errorf("IP has %d failures after Reset, want 2", failures)
+	}
+}
+
+func TestBeginConcurrent(t *testing.T) {
+	useRedis(t)
+	ctx := context.Background()
+	limiter := testLoginLimiter()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		admitted int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			attempt, _, err := limiter.Begin(ctx, "111", fmt.Sprintf("10.0.0.%d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if attempt != nil {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if admitted != limiter.maxAttemptsZehut {
+		t.Errorf("%d concurrent attempts admitted, want %d", admitted, limiter.maxAttemptsZehut)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	limiter := testLoginLimiter()
+
+	tests := []struct {
+		previous int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, 500 * time.Millisecond},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 4 * time.Second},
+		{100, 4 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := limiter.Delay(&Attempt{Previous: tt.previous}); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.previous, got, tt.want)
+		}
+	}
+}
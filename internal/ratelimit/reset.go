@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	goredis "github.com/redis/go-redis/v9"
+
+	"tzlev/internal/redis"
+)
+
+// SubjectAccount is a user account, tracked by the reset limiter together
+// with SubjectIP
+const SubjectAccount = "account"
+
+// ResetLimiter caps password reset requests per account and per client IP in
+// fixed Redis windows, so that the reset form cannot be used to flood a
+// mailbox or to walk through accounts.
+type ResetLimiter struct {
+	prefix        string
+	window        time.Duration
+	maxPerAccount int
+	maxPerIP      int
+}
+
+func NewResetLimiter() *ResetLimiter {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	return &ResetLimiter{
+		prefix:        "tzlev:reset:",
+		window:        cfg.MustGet(ctx, "security.passwordResetLimit.window", "1h").Duration(),
+		maxPerAccount: cfg.MustGet(ctx, "security.passwordResetLimit.maxPerAccount", 3).Int(),
+		maxPerIP:      cfg.MustGet(ctx, "security.passwordResetLimit.maxPerIP", 20).Int(),
+	}
+}
+
+// allowScript counts a request in the window at KEYS[1], which starts with the
+// first request. It returns 0 while the count is within the limit and
+// otherwise the milliseconds until the window ends.
+//
+// ARGV: window (ms), limit
+var allowScript = goredis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+if count > tonumber(ARGV[2]) then
+	return math.max(redis.call('PTTL', KEYS[1]), 1)
+end
+return 0
+`)
+
+// Allow counts a reset request for a subject. It returns how long the
+// subject must wait when it is over its limit, and zero otherwise.
+func (l *ResetLimiter) Allow(ctx context.Context, subject, value string) (time.Duration, error) {
+	limit := l.maxPerAccount
+	if subject == SubjectIP {
+		limit = l.maxPerIP
+	}
+
+	key := fmt.Sprintf("%s%s:%s", l.prefix, subject, value)
+	retryMs, err := allowScript.Run(ctx, redis.Client, []string{key}, l.window.Milliseconds(), limit).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(retryMs) * time.Millisecond, nil
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResetLimiterAllow(t *testing.T) {
+	type request struct {
+		subject, value string
+		wantRefused    bool
+	}
+
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "account limit",
+			requests: []request{
+				{SubjectAccount, "111", false},
+				{SubjectAccount, "111", false},
+				{SubjectAccount, "111", true},
+				{SubjectAccount, "111", true},
+				{SubjectAccount, "222", false},
+			},
+		},
+		{
+			name: "ip limit",
+			requests: []request{
+				{SubjectIP, "10.0.0.1", false},
+				{SubjectIP, "10.0.0.1", false},
+				{SubjectIP, "10.0.0.1", false},
+				{SubjectIP, "10.0.0.1", true},
+				{SubjectIP, "10.0.0.2", false},
+			},
+		},
+		{
+			name: "subjects are counted apart",
+			requests: []request{
+				{SubjectAccount, "10.0.0.1", false},
+				{SubjectAccount, "10.0.0.1", false},
+				{SubjectIP, "10.0.0.1", false},
+				{SubjectIP, "10.0.0.1", false},
+				{SubjectIP, "10.0.0.1", false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRedis(t)
+			ctx := context.Background()
+			limiter := &ResetLimiter{prefix: "test:reset:", window: time.Hour, maxPerAccount: 2, maxPerIP: 3}
+
+			for i, r := range tt.requests {
+				retry, err := limiter.Allow(ctx, r.subject, r.value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if refused := retry > 0; refused != r.wantRefused {
+					t.Fatalf("request %d: retry after %s, want refused %v", i, retry, r.wantRefused)
+				}
+				if retry > limiter.window {
+					t.Fatalf("request %d: retry after %s, longer than the window", i, retry)
+				}
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gctx"
 	"github.com/redis/go-redis/v9"
+	"tzlev/internal/tracing"
 )
 
 var Client *redis.Client
@@ -30,6 +31,10 @@ func Init() error {
 		PoolSize: poolSize,
 	})
 
+	if err := tracing.InstrumentRedis(Client); err != nil {
+		return fmt.Errorf("failed to trace Redis: %w", err)
+	}
+
 	// Test connection
 	if err := Client.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("failed to connect to Redis: %w", err)
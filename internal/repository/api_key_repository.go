@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tzlev/internal/model"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type APIKeyRepository struct{}
+
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{}
+}
+
+// Create stores a key together with its scopes
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey, scopes []model.APIKeyScope) error {
+	key.InsertedAt = time.Now()
+
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		id, err := tx.Model("api_keys").Ctx(ctx).
+			FieldsEx("id").
+			InsertAndGetId(key)
+		if err != nil {
+			return err
+		}
+		key.ID = id
+
+		if len(scopes) == 0 {
+			return nil
+		}
+
+		for i := range scopes {
+			scopes[i].APIKeyID = id
+		}
+		_, err = tx.Model("api_key_scopes").Ctx(ctx).Insert(scopes)
+		return err
+	})
+}
+
+func (r *APIKeyRepository) FindByID(ctx context.Context, id int64) (*model.APIKey, error) {
+	var key model.APIKey
+	err := g.DB().Model("api_keys").Ctx(ctx).
+		Where("id = ?", id).
+		Scan(&key)
+
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := g.DB().Model("api_keys").Ctx(ctx).
+		Where("key_hash = ?", keyHash).
+		Scan(&key)
+
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListByZehut returns the keys owned by a user, newest first
+func (r *APIKeyRepository) ListByZehut(ctx context.Context, zehut string) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := g.DB().Model("api_keys").Ctx(ctx).
+		Where("zehut = ?", zehut).
+		OrderDesc("id").
+		Scan(&keys)
+
+	return keys, err
+}
+
+// ListAll returns every key, newest first
+func (r *APIKeyRepository) ListAll(ctx context.Context) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := g.DB().Model("api_keys").Ctx(ctx).
+		OrderDesc("id").
+		Scan(&keys)
+
+	return keys, err
+}
+
+// ListScopes returns the scopes of a key keyed by resource name
+func (r *APIKeyRepository) ListScopes(ctx context.Context, keyID int64) ([]model.ResourcePermission, error) {
+	var scopes []model.ResourcePermission
+	err := g.DB().Raw(`
+		SELECT ar.name AS resource, s.can_view, s.can_create, s.can_edit, s.can_delete
+		FROM api_key_scopes s
+		INNER JOIN app_resources ar ON ar.id = s.resource_id
+		WHERE s.api_key_id = ?`, keyID).
+		Ctx(ctx).
+		Scan(&scopes)
+
+	return scopes, err
+}
+
+// Revoke marks a key as revoked. Revoking an already revoked key is a no-op.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	_, err := g.DB().Model("api_keys").Ctx(ctx).
+		Data(g.Map{"revoked_at": time.Now()}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update()
+	return err
+}
+
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	_, err := g.DB().Model("api_keys").Ctx(ctx).
+		Data(g.Map{"last_used_at": usedAt}).
+		Where("id = ?", id).
+		Update()
+	return err
+}
@@ -2,15 +2,20 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"math/rand"
 	"time"
 
+	"tzlev/internal/audit"
 	"tzlev/internal/model"
 
 	"github.com/gogf/gf/v2/frame/g"
 )
 
+// ErrAppResourceNameTaken is returned when creating a resource with the name
+// of an existing one
+var ErrAppResourceNameTaken = errors.New("app resource name is already in use")
+
 type AppResourceRepository struct{}
 
 func NewAppResourceRepository() *AppResourceRepository {
@@ -38,11 +43,15 @@ func (r *AppResourceRepository) Create(ctx context.Context, resource *model.AppR
 	// Check if a resource with the same name already exists
 	existingResource, err := r.FindByName(ctx, resource.Name)
 	if err == nil && existingResource != nil {
-		return fmt.Errorf("a resource with name '%s' already exists", resource.Name)
+		return ErrAppResourceNameTaken
+	}
+
+	if _, err = g.DB().Model("app_resources").Ctx(ctx).Insert(resource); err != nil {
+		return err
 	}
 
-	_, err = g.DB().Model("app_resources").Ctx(ctx).Insert(resource)
-	return err
+	audit.RecordChange(ctx, "app_resource.create", "app_resource", resource.Id, nil, resource)
+	return nil
 }
 
 func (r *AppResourceRepository) FindByID(ctx context.Context, id string) (*model.AppResource, error) {
@@ -70,17 +79,39 @@ func (r *AppResourceRepository) FindByName(ctx context.Context, name string) (*m
 }
 
 func (r *AppResourceRepository) Update(ctx context.Context, resource *model.AppResource) error {
-	_, err := g.DB().Model("app_resources").Ctx(ctx).
+	before, err := r.FindByID(ctx, resource.Id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.DB().Model("app_resources").Ctx(ctx).
 		Where("id = ?", resource.Id).
-		Update(resource)
-	return err
+		Update(resource); err != nil {
+		return err
+	}
+
+	after, err := r.FindByID(ctx, resource.Id)
+	if err != nil {
+		return err
+	}
+	audit.RecordChange(ctx, "app_resource.update", "app_resource", resource.Id, before, after)
+	return nil
 }
 
 func (r *AppResourceRepository) Delete(ctx context.Context, id string) error {
-	_, err := g.DB().Model("app_resources").Ctx(ctx).
+	before, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.DB().Model("app_resources").Ctx(ctx).
 		Where("id = ?", id).
-		Delete()
-	return err
+		Delete(); err != nil {
+		return err
+	}
+
+	audit.RecordChange(ctx, "app_resource.delete", "app_resource", id, before, nil)
+	return nil
 }
 
 func (r *AppResourceRepository) List(ctx context.Context, offset, limit int) ([]model.AppResource, error) {
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"tzlev/internal/model"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// AuditLogRepository reads the audit_log table. Entries are written by
+// audit.Record.
+type AuditLogRepository struct{}
+
+// AuditLogFilter narrows an audit log search. Empty fields are ignored and the
+// remaining ones are combined with AND. An Action ending in "*" matches by prefix.
+type AuditLogFilter struct {
+	Actor        string
+	Impersonator string
+	Action       string
+	Entity       string
+	EntityID     string
+	IP           string
+	RequestID    string
+	From         *time.Time
+	To           *time.Time
+}
+
+func NewAuditLogRepository() *AuditLogRepository {
+	return &AuditLogRepository{}
+}
+
+// Search returns matching entries, newest first
+func (r *AuditLogRepository) Search(ctx context.Context, filter AuditLogFilter, offset, limit int) ([]model.AuditLogEntry, error) {
+	var entries []model.AuditLogEntry
+	err := r.filterModel(ctx, filter).
+		OrderDesc("id").
+		Offset(offset).
+		Limit(limit).
+		Scan(&entries)
+
+	return entries, err
+}
+
+func (r *AuditLogRepository) Count(ctx context.Context, filter AuditLogFilter) (int, error) {
+	return r.filterModel(ctx, filter).Count()
+}
+
+func (r *AuditLogRepository) filterModel(ctx context.Context, filter AuditLogFilter) *gdb.Model {
+	query := g.DB().Model("audit_log").Ctx(ctx)
+
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Impersonator != "" {
+		query = query.Where("impersonator = ?", filter.Impersonator)
+	}
+	if filter.Action != "" {
+		if prefix, ok := strings.CutSuffix(filter.Action, "*"); ok {
+			query = query.Where("action LIKE ?", prefix+"%")
+		} else {
+			query = query.Where("action = ?", filter.Action)
+		}
+	}
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.WhereGTE("inserted_at", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.WhereLT("inserted_at", *filter.To)
+	}
+
+	return query
+}
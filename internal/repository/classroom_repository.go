@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"strconv"
 	"time"
 
+	"tzlev/internal/audit"
 	"tzlev/internal/model"
 
 	"github.com/gogf/gf/v2/frame/g"
@@ -11,6 +13,14 @@ import (
 
 type ClassroomRepository struct{}
 
+// ClassroomFilter narrows a classroom search. Empty fields are ignored and
+// the remaining ones are combined with AND.
+type ClassroomFilter struct {
+	AcademicYear string
+	SchoolID     *int64
+	TeacherID    *int64
+}
+
 func NewClassroomRepository() *ClassroomRepository {
 	return &ClassroomRepository{}
 }
@@ -19,8 +29,16 @@ func (r *ClassroomRepository) Create(ctx context.Context, classroom *model.Class
 	classroom.InsertedAt = time.Now()
 	classroom.UpdatedAt = time.Now()
 
-	_, err := g.DB().Model("classrooms").Ctx(ctx).Insert(classroom)
-	return err
+	id, err := g.DB().Model("classrooms").Ctx(ctx).
+		FieldsEx("id").
+		InsertAndGetId(classroom)
+	if err != nil {
+		return err
+	}
+
+	classroom.ID = id
+	audit.RecordChange(ctx, "classroom.create", "classroom", strconv.FormatInt(id, 10), nil, classroom)
+	return nil
 }
 
 func (r *ClassroomRepository) FindByID(ctx context.Context, id int64) (*model.Classroom, error) {
@@ -78,19 +96,42 @@ func (r *ClassroomRepository) FindByTeacherID(ctx context.Context, teacherID int
 }
 
 func (r *ClassroomRepository) Update(ctx context.Context, classroom *model.Classroom) error {
+	before, err := r.FindByID(ctx, classroom.ID)
+	if err != nil {
+		return err
+	}
+
 	classroom.UpdatedAt = time.Now()
 
-	_, err := g.DB().Model("classrooms").Ctx(ctx).
+	if _, err := g.DB().Model("classrooms").Ctx(ctx).
+		FieldsEx("id", "inserted_at").
 		Where("id = ?", classroom.ID).
-		Update(classroom)
-	return err
+		Update(classroom); err != nil {
+		return err
+	}
+
+	after, err := r.FindByID(ctx, classroom.ID)
+	if err != nil {
+		return err
+	}
+	audit.RecordChange(ctx, "classroom.update", "classroom", strconv.FormatInt(classroom.ID, 10), before, after)
+	return nil
 }
 
 func (r *ClassroomRepository) Delete(ctx context.Context, id int64) error {
-	_, err := g.DB().Model("classrooms").Ctx(ctx).
+	before, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.DB().Model("classrooms").Ctx(ctx).
 		Where("id = ?", id).
-		Delete()
-	return err
+		Delete(); err != nil {
+		return err
+	}
+
+	audit.RecordChange(ctx, "classroom.delete", "classroom", strconv.FormatInt(id, 10), before, nil)
+	return nil
 }
 
 func (r *ClassroomRepository) GetDistinctAcademicYears(ctx context.Context) ([]string, error) {
@@ -126,3 +167,26 @@ func (r *ClassroomRepository) List(ctx context.Context, offset, limit int) ([]mo
 
 	return classrooms, err
 }
+
+func (r *ClassroomRepository) Search(ctx context.Context, filter ClassroomFilter, offset, limit int) ([]model.Classroom, error) {
+	query := g.DB().Model("classrooms").Ctx(ctx)
+
+	if filter.AcademicYear != "" {
+		query = query.Where("academic_year = ?", filter.AcademicYear)
+	}
+	if filter.SchoolID != nil {
+		query = query.Where("school_id = ?", *filter.SchoolID)
+	}
+	if filter.TeacherID != nil {
+		query = query.Where("teacher_id = ?", *filter.TeacherID)
+	}
+
+	var classrooms []model.Classroom
+	err := query.
+		Order("order_id ASC, classroom_name ASC").
+		Offset(offset).
+		Limit(limit).
+		Scan(&classrooms)
+
+	return classrooms, err
+}
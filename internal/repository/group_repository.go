@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tzlev/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type GroupRepository struct{}
+
+func NewGroupRepository() *GroupRepository {
+	return &GroupRepository{}
+}
+
+func (r *GroupRepository) Create(ctx context.Context, group *model.Group) error {
+	group.InsertedAt = time.Now()
+	group.UpdatedAt = time.Now()
+
+	id, err := g.DB().Model("groups").Ctx(ctx).
+		FieldsEx("id").
+		InsertAndGetId(group)
+	if err != nil {
+		return err
+	}
+
+	group.ID = id
+	return nil
+}
+
+func (r *GroupRepository) FindByID(ctx context.Context, id int64) (*model.Group, error) {
+	var group model.Group
+	err := g.DB().Model("groups").Ctx(ctx).
+		Where("id = ?", id).
+		Scan(&group)
+
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *GroupRepository) Update(ctx context.Context, group *model.Group) error {
+	group.UpdatedAt = time.Now()
+
+	_, err := g.DB().Model("groups").Ctx(ctx).
+		Fields("name", "description", "updated_at").
+		Where("id = ?", group.ID).
+		Update(group)
+	return err
+}
+
+func (r *GroupRepository) Delete(ctx context.Context, id int64) error {
+	_, err := g.DB().Model("groups").Ctx(ctx).
+		Where("id = ?", id).
+		Delete()
+	return err
+}
+
+func (r *GroupRepository) ListAll(ctx context.Context) ([]model.Group, error) {
+	var groups []model.Group
+	err := g.DB().Model("groups").Ctx(ctx).
+		Order("name ASC").
+		Scan(&groups)
+
+	return groups, err
+}
+
+// ListByMember returns the groups a user belongs to
+func (r *GroupRepository) ListByMember(ctx context.Context, zehut string) ([]model.Group, error) {
+	var groups []model.Group
+	err := g.DB().Model("groups g").Ctx(ctx).
+		Fields("g.*").
+		InnerJoin("group_members gm", "gm.group_id = g.id").
+		Where("gm.zehut = ?", zehut).
+		Order("g.name ASC").
+		Scan(&groups)
+
+	return groups, err
+}
+
+// ListMembers returns the users that belong to a group
+func (r *GroupRepository) ListMembers(ctx context.Context, groupID int64) ([]model.GroupMemberUser, error) {
+	var users []model.GroupMemberUser
+	err := g.DB().Model("users u").Ctx(ctx).
+		Fields("u.zehut, u.first_name, u.last_name, u.email").
+		InnerJoin("group_members gm", "gm.zehut = u.zehut").
+		Where("gm.group_id = ?", groupID).
+		Order("u.last_name ASC, u.first_name ASC").
+		Scan(&users)
+
+	return users, err
+}
+
+func (r *GroupRepository) AddMember(ctx context.Context, groupID int64, zehut string) error {
+	_, err := g.DB().Model("group_members").Ctx(ctx).
+		OnConflict("group_id", "zehut").
+		Save(model.GroupMember{
+			GroupID:    groupID,
+			Zehut:      zehut,
+			InsertedAt: time.Now(),
+		})
+	return err
+}
+
+func (r *GroupRepository) RemoveMember(ctx context.Context, groupID int64, zehut string) error {
+	_, err := g.DB().Model("group_members").Ctx(ctx).
+		Where("group_id = ? AND zehut = ?", groupID, zehut).
+		Delete()
+	return err
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+
+	"tzlev/internal/model"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type PermissionRepository struct{}
+
+func NewPermissionRepository() *PermissionRepository {
+	return &PermissionRepository{}
+}
+
+func (r *PermissionRepository) ListByGroup(ctx context.Context, groupID int64) ([]model.GroupPermission, error) {
+	var permissions []model.GroupPermission
+	err := g.DB().Model("group_permissions").Ctx(ctx).
+		Where("group_id = ?", groupID).
+		Scan(&permissions)
+
+	return permissions, err
+}
+
+// ReplaceForGroup atomically replaces all permissions of a group
+func (r *PermissionRepository) ReplaceForGroup(ctx context.Context, groupID int64, permissions []model.GroupPermission) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if _, err := tx.Model("group_permissions").Ctx(ctx).
+			Where("group_id = ?", groupID).
+			Delete(); err != nil {
+			return err
+		}
+
+		if len(permissions) == 0 {
+			return nil
+		}
+
+		for i := range permissions {
+			permissions[i].GroupID = groupID
+		}
+		_, err := tx.Model("group_permissions").Ctx(ctx).Insert(permissions)
+		return err
+	})
+}
+
+func (r *PermissionRepository) ListByUser(ctx context.Context, zehut string) ([]model.UserPermission, error) {
+	var permissions []model.UserPermission
+	err := g.DB().Model("user_permissions").Ctx(ctx).
+		Where("zehut = ?", zehut).
+		Scan(&permissions)
+
+	return permissions, err
+}
+
+// ReplaceForUser atomically replaces all direct permissions of a user
+func (r *PermissionRepository) ReplaceForUser(ctx context.Context, zehut string, permissions []model.UserPermission) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if _, err := tx.Model("user_permissions").Ctx(ctx).
+			Where("zehut = ?", zehut).
+			Delete(); err != nil {
+			return err
+		}
+
+		if len(permissions) == 0 {
+			return nil
+		}
+
+		for i := range permissions {
+			permissions[i].Zehut = zehut
+		}
+		_, err := tx.Model("user_permissions").Ctx(ctx).Insert(permissions)
+		return err
+	})
+}
+
+// ListEffective returns the union of a user's group and direct permissions, keyed by resource name
+func (r *PermissionRepository) ListEffective(ctx context.Context, zehut string) ([]model.ResourcePermission, error) {
+	var permissions []model.ResourcePermission
+	err := g.DB().Raw(`
+		SELECT ar.name AS resource,
+		       bool_or(p.can_view)   AS can_view,
+		       bool_or(p.can_create) AS can_create,
+		       bool_or(p.can_edit)   AS can_edit,
+		       bool_or(p.can_delete) AS can_delete
+		FROM (
+			SELECT gp.resource_id, gp.can_view, gp.can_create, gp.can_edit, gp.can_delete
+			FROM group_permissions gp
+			INNER JOIN group_members gm ON gm.group_id = gp.group_id
+			WHERE gm.zehut = ?
+			UNION ALL
+			SELECT up.resource_id, up.can_view, up.can_create, up.can_edit, up.can_delete
+			FROM user_permissions up
+			WHERE up.zehut = ?
+		) p
+		INNER JOIN app_resources ar ON ar.id = p.resource_id
+		GROUP BY ar.name`, zehut, zehut).
+		Ctx(ctx).
+		Scan(&permissions)
+
+	return permissions, err
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tzlev/internal/model"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type TwoFactorRepository struct{}
+
+func NewTwoFactorRepository() *TwoFactorRepository {
+	return &TwoFactorRepository{}
+}
+
+func (r *TwoFactorRepository) FindTOTP(ctx context.Context, zehut string) (*model.UserTOTP, error) {
+	var totp model.UserTOTP
+	err := g.DB().Model("user_totp").Ctx(ctx).
+		Where("zehut = ?", zehut).
+		Scan(&totp)
+
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// SavePendingTOTP stores a new unconfirmed secret, replacing any earlier pending one
+func (r *TwoFactorRepository) SavePendingTOTP(ctx context.Context, zehut, secret string) error {
+	_, err := g.DB().Model("user_totp").Ctx(ctx).
+		Data(g.Map{
+			"zehut":          zehut,
+			"secret":         secret,
+			"confirmed_at":   nil,
+			"last_used_step": 0,
+			"inserted_at":    time.Now(),
+		}).
+		OnConflict("zehut").
+		Save()
+	return err
+}
+
+// ConfirmTOTP enables two-factor authentication and replaces the recovery codes
+func (r *TwoFactorRepository) ConfirmTOTP(ctx context.Context, zehut string, step int64, codeHashes []string) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		_, err := tx.Model("user_totp").Ctx(ctx).
+			Data(g.Map{
+				"confirmed_at":   time.Now(),
+				"last_used_step": step,
+			}).
+			Where("zehut = ?", zehut).
+			Update()
+		if err != nil {
+			return err
+		}
+
+		return replaceRecoveryCodes(ctx, tx, zehut, codeHashes)
+	})
+}
+
+// UseTOTPStep records step as used. It reports false if the same or a later
+// step was already accepted, which means the code is being replayed.
+func (r *TwoFactorRepository) UseTOTPStep(ctx context.Context, zehut string, step int64) (bool, error) {
+	result, err := g.DB().Model("user_totp").Ctx(ctx).
+		Data(g.Map{"last_used_step": step}).
+		Where("zehut = ? AND last_used_step < ?", zehut, step).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// DeleteTOTP disables two-factor authentication and drops the recovery codes
+func (r *TwoFactorRepository) DeleteTOTP(ctx context.Context, zehut string) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if _, err := tx.Model("user_recovery_codes").Ctx(ctx).Where("zehut = ?", zehut).Delete(); err != nil {
+			return err
+		}
+
+		_, err := tx.Model("user_totp").Ctx(ctx).Where("zehut = ?", zehut).Delete()
+		return err
+	})
+}
+
+// ReplaceRecoveryCodes invalidates a user's recovery codes and stores new ones
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, zehut string, codeHashes []string) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		return replaceRecoveryCodes(ctx, tx, zehut, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx gdb.TX, zehut string, codeHashes []string) error {
+	if _, err := tx.Model("user_recovery_codes").Ctx(ctx).Where("zehut = ?", zehut).Delete(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	codes := make([]model.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = model.RecoveryCode{Zehut: zehut, CodeHash: hash, InsertedAt: now}
+	}
+
+	_, err := tx.Model("user_recovery_codes").Ctx(ctx).
+		FieldsEx("id").
+		Insert(codes)
+	return err
+}
+
+// UseRecoveryCode marks an unused recovery code as used and reports whether one matched
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, zehut, codeHash string) (bool, error) {
+	result, err := g.DB().Model("user_recovery_codes").Ctx(ctx).
+		Data(g.Map{"used_at": time.Now()}).
+		Where("zehut = ? AND code_hash = ? AND used_at IS NULL", zehut, codeHash).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes a user has left
+func (r *TwoFactorRepository) CountUnusedRecoveryCodes(ctx context.Context, zehut string) (int, error) {
+	return g.DB().Model("user_recovery_codes").Ctx(ctx).
+		Where("zehut = ? AND used_at IS NULL", zehut).
+		Count()
+}
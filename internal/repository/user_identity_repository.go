@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tzlev/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type UserIdentityRepository struct{}
+
+func NewUserIdentityRepository() *UserIdentityRepository {
+	return &UserIdentityRepository{}
+}
+
+func (r *UserIdentityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	identity.InsertedAt = time.Now()
+
+	id, err := g.DB().Model("user_identities").Ctx(ctx).
+		FieldsEx("id").
+		InsertAndGetId(identity)
+	if err != nil {
+		return err
+	}
+
+	identity.ID = id
+	return nil
+}
+
+func (r *UserIdentityRepository) FindBySubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := g.DB().Model("user_identities").Ctx(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).
+		Scan(&identity)
+
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// ListByZehut returns the identities linked to a user, oldest first
+func (r *UserIdentityRepository) ListByZehut(ctx context.Context, zehut string) ([]model.UserIdentity, error) {
+	var identities []model.UserIdentity
+	err := g.DB().Model("user_identities").Ctx(ctx).
+		Where("zehut = ?", zehut).
+		OrderAsc("id").
+		Scan(&identities)
+
+	return identities, err
+}
+
+// Delete unlinks an identity from a user and reports whether it existed
+func (r *UserIdentityRepository) Delete(ctx context.Context, id int64, zehut string) (bool, error) {
+	result, err := g.DB().Model("user_identities").Ctx(ctx).
+		Where("id = ? AND zehut = ?", id, zehut).
+		Delete()
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *UserIdentityRepository) TouchLastLogin(ctx context.Context, id int64, loginAt time.Time) error {
+	_, err := g.DB().Model("user_identities").Ctx(ctx).
+		Data(g.Map{"last_login_at": loginAt}).
+		Where("id = ?", id).
+		Update()
+	return err
+}
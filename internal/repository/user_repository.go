@@ -4,8 +4,10 @@ import (
 	"context"
 	"time"
 
+	"tzlev/internal/audit"
 	"tzlev/internal/model"
 
+	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
@@ -19,8 +21,12 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 	user.InsertedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
-	_, err := g.DB().Model("users").Ctx(ctx).Insert(user)
-	return err
+	if _, err := g.DB().Model("users").Ctx(ctx).Insert(user); err != nil {
+		return err
+	}
+
+	audit.RecordChange(ctx, "user.create", "user", user.Zehut, nil, user)
+	return nil
 }
 
 func (r *UserRepository) FindByZehut(ctx context.Context, zehut string) (*model.User, error) {
@@ -47,12 +53,69 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.
 	return &user, nil
 }
 
+// FindByEmailToken finds the user holding a (hashed) email token
+func (r *UserRepository) FindByEmailToken(ctx context.Context, tokenHash string) (*model.User, error) {
+	var user model.User
+	err := g.DB().Model("users").Ctx(ctx).
+		Where("email_token = ?", tokenHash).
+		Scan(&user)
+
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ConsumeEmailToken clears a user's email token if it still matches tokenHash.
+// It reports false when the token was already used, so each token works only once.
+func (r *UserRepository) ConsumeEmailToken(ctx context.Context, zehut, tokenHash string) (bool, error) {
+	result, err := g.DB().Model("users").Ctx(ctx).
+		Data(g.Map{
+			"email_token":         nil,
+			"email_token_sent_at": nil,
+		}).
+		Where("zehut = ? AND email_token = ?", zehut, tokenHash).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
 func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	before, err := r.FindByZehut(ctx, user.Zehut)
+	if err != nil {
+		return err
+	}
+
 	user.UpdatedAt = time.Now()
 
-	_, err := g.DB().Model("users").Ctx(ctx).
+	if _, err := g.DB().Model("users").Ctx(ctx).
 		Where("zehut = ?", user.Zehut).
-		Update(user)
+		Update(user); err != nil {
+		return err
+	}
+
+	after, err := r.FindByZehut(ctx, user.Zehut)
+	if err != nil {
+		return err
+	}
+	audit.RecordChange(ctx, "user.update", "user", user.Zehut, before, after)
+	return nil
+}
+
+// UpdatePasswordHash replaces a user's password hash if it is still oldHash,
+// so an upgrade cannot overwrite a password changed in the meantime
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, zehut, oldHash, newHash string) error {
+	_, err := g.DB().Model("users").Ctx(ctx).
+		Data(g.Map{
+			"hashed_password": newHash,
+			"updated_at":      time.Now(),
+		}).
+		Where("zehut = ? AND hashed_password = ?", zehut, oldHash).
+		Update()
 	return err
 }
 
@@ -65,3 +128,32 @@ func (r *UserRepository) List(ctx context.Context, offset, limit int) ([]model.U
 
 	return users, err
 }
+
+// Search lists users whose zehut, name or email contains search, ordered by name
+func (r *UserRepository) Search(ctx context.Context, search string, offset, limit int) ([]model.User, error) {
+	var users []model.User
+	err := r.searchModel(ctx, search).
+		Order("last_name ASC, first_name ASC").
+		Offset(offset).
+		Limit(limit).
+		Scan(&users)
+
+	return users, err
+}
+
+// Count returns the number of users matching search
+func (r *UserRepository) Count(ctx context.Context, search string) (int, error) {
+	return r.searchModel(ctx, search).Count()
+}
+
+func (r *UserRepository) searchModel(ctx context.Context, search string) *gdb.Model {
+	query := g.DB().Model("users").Ctx(ctx)
+	if search != "" {
+		like := "%" + search + "%"
+		query = query.Where(
+			"(zehut ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ? OR email ILIKE ?)",
+			like, like, like, like,
+		)
+	}
+	return query
+}
@@ -0,0 +1,290 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+
+	"tzlev/internal/auth"
+	"tzlev/internal/model"
+	"tzlev/internal/repository"
+)
+
+// baseSet is loaded for every environment before the environment-specific set
+const baseSet = "base"
+
+// Tables lists the seedable tables in the order they are loaded
+var Tables = []string{"app_resources", "users", "classrooms"}
+
+// fixtureExtensions are tried in order when looking for a table's fixture file
+var fixtureExtensions = []string{".yaml", ".yml", ".json"}
+
+// Options controls a seed run
+type Options struct {
+	Env      string
+	Tables   []string
+	Truncate bool
+	DryRun   bool
+}
+
+// Result summarizes what a seed run did (or would do, in dry-run mode) for one table
+type Result struct {
+	Table   string
+	Created int
+	Updated int
+}
+
+type Seeder struct {
+	dir           string
+	userRepo      *repository.UserRepository
+	classroomRepo *repository.ClassroomRepository
+	resourceRepo  *repository.AppResourceRepository
+}
+
+func NewSeeder(dir string) *Seeder {
+	return &Seeder{
+		dir:           dir,
+		userRepo:      repository.NewUserRepository(),
+		classroomRepo: repository.NewClassroomRepository(),
+		resourceRepo:  repository.NewAppResourceRepository(),
+	}
+}
+
+// Run loads the fixtures for each requested table and upserts them
+func (s *Seeder) Run(ctx context.Context, opts Options) ([]Result, error) {
+	var results []Result
+
+	for _, table := range opts.Tables {
+		records, err := s.loadFixtures(table, opts.Env)
+		if err != nil {
+			return results, err
+		}
+
+		if opts.Truncate {
+			if err := s.truncate(ctx, table, opts.DryRun); err != nil {
+				return results, err
+			}
+		}
+
+		result := Result{Table: table}
+		for i, record := range records {
+			created, err := s.seedRecord(ctx, table, record, opts.DryRun)
+			if err != nil {
+				return results, fmt.Errorf("failed to seed %s record #%d: %w", table, i+1, err)
+			}
+			if created {
+				result.Created++
+			} else {
+				result.Updated++
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// IsTable reports whether name is a seedable table
+func IsTable(name string) bool {
+	for _, table := range Tables {
+		if table == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadFixtures returns the base records followed by the environment records for table
+func (s *Seeder) loadFixtures(table, env string) ([]map[string]interface{}, error) {
+	var records []map[string]interface{}
+
+	for _, set := range []string{baseSet, env} {
+		path := s.findFixture(set, table)
+		if path == "" {
+			continue
+		}
+
+		j, err := gjson.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fixture %s: %w", path, err)
+		}
+
+		for _, item := range j.Array() {
+			records = append(records, gconv.Map(item))
+		}
+	}
+
+	return records, nil
+}
+
+func (s *Seeder) findFixture(set, table string) string {
+	for _, ext := range fixtureExtensions {
+		path := filepath.Join(s.dir, set, table+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// truncate empties a table along with every table that references it, such
+// as the group memberships, permissions, 2FA records and API keys of users
+func (s *Seeder) truncate(ctx context.Context, table string, dryRun bool) error {
+	g.Log().Infof(ctx, "Truncating %s and the tables that reference it", table)
+	if dryRun {
+		return nil
+	}
+
+	if _, err := g.DB().Exec(ctx, "TRUNCATE TABLE "+table+" CASCADE"); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", table, err)
+	}
+	return nil
+}
+
+// seedRecord inserts or updates a single fixture record and reports whether it was created
+func (s *Seeder) seedRecord(ctx context.Context, table string, record map[string]interface{}, dryRun bool) (bool, error) {
+	switch table {
+	case "users":
+		return s.seedUser(ctx, record, dryRun)
+	case "classrooms":
+		return s.seedClassroom(ctx, record, dryRun)
+	case "app_resources":
+		return s.seedAppResource(ctx, record, dryRun)
+	default:
+		return false, fmt.Errorf("unknown table: %s", table)
+	}
+}
+
+// seedUser upserts a user by zehut. A plaintext "password" key is hashed with
+// auth.HashPassword; existing passwords are kept when it is omitted.
+func (s *Seeder) seedUser(ctx context.Context, record map[string]interface{}, dryRun bool) (bool, error) {
+	zehut := gconv.String(record["zehut"])
+	if zehut == "" {
+		return false, fmt.Errorf("zehut is required")
+	}
+
+	user, err := s.userRepo.FindByZehut(ctx, zehut)
+	exists, err := existsOrError(err)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		user = &model.User{}
+	}
+
+	// Only the keys present in the fixture overwrite the existing row
+	if err := gconv.Struct(record, user); err != nil {
+		return false, fmt.Errorf("invalid user fixture: %w", err)
+	}
+
+	logSeed(ctx, "users", zehut, exists, dryRun)
+	if dryRun {
+		return !exists, nil
+	}
+
+	if password := gconv.String(record["password"]); password != "" {
+		hashed, err := auth.HashPassword(password)
+		if err != nil {
+			return false, fmt.Errorf("failed to hash password: %w", err)
+		}
+		user.HashedPassword = hashed
+	}
+
+	if exists {
+		return false, s.userRepo.Update(ctx, user)
+	}
+	return true, s.userRepo.Create(ctx, user)
+}
+
+// seedClassroom upserts a classroom by code
+func (s *Seeder) seedClassroom(ctx context.Context, record map[string]interface{}, dryRun bool) (bool, error) {
+	code := gconv.String(record["code"])
+	if code == "" {
+		return false, fmt.Errorf("code is required")
+	}
+
+	classroom, err := s.classroomRepo.FindByCode(ctx, code)
+	exists, err := existsOrError(err)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		classroom = &model.Classroom{}
+	}
+
+	if err := gconv.Struct(record, classroom); err != nil {
+		return false, fmt.Errorf("invalid classroom fixture: %w", err)
+	}
+
+	logSeed(ctx, "classrooms", code, exists, dryRun)
+	if dryRun {
+		return !exists, nil
+	}
+
+	if exists {
+		return false, s.classroomRepo.Update(ctx, classroom)
+	}
+	return true, s.classroomRepo.Create(ctx, classroom)
+}
+
+// seedAppResource upserts an app resource by name
+func (s *Seeder) seedAppResource(ctx context.Context, record map[string]interface{}, dryRun bool) (bool, error) {
+	name := gconv.String(record["name"])
+	if name == "" {
+		return false, fmt.Errorf("name is required")
+	}
+
+	resource, err := s.resourceRepo.FindByName(ctx, name)
+	exists, err := existsOrError(err)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		resource = &model.AppResource{}
+	}
+
+	if err := gconv.Struct(record, resource); err != nil {
+		return false, fmt.Errorf("invalid app resource fixture: %w", err)
+	}
+
+	logSeed(ctx, "app_resources", name, exists, dryRun)
+	if dryRun {
+		return !exists, nil
+	}
+
+	if exists {
+		return false, s.resourceRepo.Update(ctx, resource)
+	}
+	return true, s.resourceRepo.Create(ctx, resource)
+}
+
+// existsOrError turns a repository lookup error into an existence flag,
+// passing through anything other than "no rows"
+func existsOrError(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return false, err
+}
+
+func logSeed(ctx context.Context, table, key string, exists, dryRun bool) {
+	action := "Creating"
+	if exists {
+		action = "Updating"
+	}
+	if dryRun {
+		action = "[dry-run] " + action
+	}
+	g.Log().Debugf(ctx, "%s %s record %s", action, table, key)
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+
+	"tzlev/internal/auth"
+	"tzlev/internal/model"
+	"tzlev/internal/repository"
+)
+
+// apiKeyPrefix marks our keys so they are easy to recognise in configs and secret scanners
+const apiKeyPrefix = "tzk_"
+
+// apiKeyDisplayLength is how much of a key is stored in clear to identify it in listings
+const apiKeyDisplayLength = 12
+
+// lastUsedInterval limits how often a key's last-used timestamp is written
+const lastUsedInterval = time.Minute
+
+var (
+	// ErrInvalidAPIKey is returned for unknown, expired or revoked keys
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyNotFound is returned when revoking a key that does not exist or belongs to someone else
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrScopeNotAllowed is returned when a key would grant more than its owner or creator holds
+	ErrScopeNotAllowed = errors.New("scope exceeds the owner's or creator's permissions")
+	// ErrAdminKeyRequired is returned when someone other than an admin creates a
+	// key for an admin or a service account, which no permissions would limit
+	ErrAdminKeyRequired = errors.New("only admins can create this key")
+)
+
+// APIKeyRequestError is a key request with an invalid field
+type APIKeyRequestError struct {
+	Field   string
+	Message string
+}
+
+func (e *APIKeyRequestError) Error() string {
+	return e.Message
+}
+
+// APIKeyRequest describes a key to create. Exactly one of Zehut and ServiceAccount is set.
+type APIKeyRequest struct {
+	Name           string
+	Zehut          string
+	ServiceAccount string
+	ExpiresAt      *time.Time
+	Scopes         []model.APIKeyScope
+	CreatedBy      string
+}
+
+// APIKeyInfo is a key along with its scopes
+type APIKeyInfo struct {
+	model.APIKey
+	Scopes []model.ResourcePermission `json:"scopes"`
+}
+
+type APIKeyService struct {
+	apiKeyRepo        *repository.APIKeyRepository
+	resourceRepo      *repository.AppResourceRepository
+	userService       *UserService
+	permissionService *PermissionService
+	defaultLifetime   time.Duration
+	maxLifetime       time.Duration
+}
+
+func NewAPIKeyService() *APIKeyService {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	return &APIKeyService{
+		apiKeyRepo:        repository.NewAPIKeyRepository(),
+		resourceRepo:      repository.NewAppResourceRepository(),
+		userService:       NewUserService(),
+		permissionService: NewPermissionService(),
+		defaultLifetime:   cfg.MustGet(ctx, "security.apiKeys.defaultLifetime", "2160h").Duration(),
+		maxLifetime:       cfg.MustGet(ctx, "security.apiKeys.maxLifetime", "8760h").Duration(),
+	}
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create issues a new key and returns it in plaintext. The plaintext is not
+// stored and cannot be retrieved again.
+func (s *APIKeyService) Create(ctx context.Context, req APIKeyRequest) (string, *APIKeyInfo, error) {
+	if req.Name == "" {
+		return "", nil, &APIKeyRequestError{Field: "name", Message: "Name is required"}
+	}
+	if (req.Zehut == "") == (req.ServiceAccount == "") {
+		return "", nil, &APIKeyRequestError{Field: "zehut", Message: "Set exactly one of zehut and service_account"}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.defaultLifetime)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > s.maxLifetime {
+		return "", nil, &APIKeyRequestError{
+			Field:   "expires_at",
+			Message: fmt.Sprintf("Expiry must be in the future and at most %s away", s.maxLifetime),
+		}
+	}
+
+	if err := s.checkScopes(ctx, req); err != nil {
+		return "", nil, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	plaintext := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	key := &model.APIKey{
+		Name:      req.Name,
+		Prefix:    plaintext[:apiKeyDisplayLength],
+		KeyHash:   hashAPIKey(plaintext),
+		CreatedBy: req.CreatedBy,
+		ExpiresAt: expiresAt,
+	}
+	if req.Zehut != "" {
+		key.Zehut = &req.Zehut
+	} else {
+		key.ServiceAccount = &req.ServiceAccount
+	}
+
+	if err := s.apiKeyRepo.Create(ctx, key, req.Scopes); err != nil {
+		return "", nil, err
+	}
+
+	info, err := s.withScopes(ctx, *key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return plaintext, info, nil
+}
+
+// checkScopes ensures every scope names an existing app resource and that a
+// key grants nothing its owner or its creator does not hold. Admins hold
+// everything, so only admins may create keys for admins or service accounts.
+func (s *APIKeyService) checkScopes(ctx context.Context, req APIKeyRequest) error {
+	creator, err := s.userService.GetUserByZehut(ctx, req.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to load key creator: %w", err)
+	}
+
+	var limits []map[string]model.PermissionFlags
+	if !creator.IsAdmin {
+		if req.ServiceAccount != "" {
+			return fmt.Errorf("%w: service account %s", ErrAdminKeyRequired, req.ServiceAccount)
+		}
+
+		held, err := s.permissionService.GetEffectivePermissions(ctx, creator.Zehut)
+		if err != nil {
+			return err
+		}
+		limits = append(limits, held)
+	}
+
+	if req.Zehut != "" && req.Zehut != creator.Zehut {
+		owner, err := s.userService.GetUserByZehut(ctx, req.Zehut)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &APIKeyRequestError{Field: "zehut", Message: "User not found"}
+		}
+		if err != nil {
+			return err
+		}
+
+		if owner.IsAdmin && !creator.IsAdmin {
+			return fmt.Errorf("%w: admin %s", ErrAdminKeyRequired, owner.Zehut)
+		}
+		if !owner.IsAdmin {
+			held, err := s.permissionService.GetEffectivePermissions(ctx, owner.Zehut)
+			if err != nil {
+				return err
+			}
+			limits = append(limits, held)
+		}
+	}
+
+	for _, scope := range req.Scopes {
+		resource, err := s.resourceRepo.FindByID(ctx, scope.ResourceID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &APIKeyRequestError{Field: "scopes", Message: fmt.Sprintf("Unknown app resource %s", scope.ResourceID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, held := range limits {
+			if held[resource.Name].Merge(scope.PermissionFlags) != held[resource.Name] {
+				return fmt.Errorf("%w: %s", ErrScopeNotAllowed, resource.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *APIKeyService) withScopes(ctx context.Context, key model.APIKey) (*APIKeyInfo, error) {
+	scopes, err := s.apiKeyRepo.ListScopes(ctx, key.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &APIKeyInfo{APIKey: key, Scopes: scopes}, nil
+}
+
+func (s *APIKeyService) withScopesAll(ctx context.Context, keys []model.APIKey) ([]APIKeyInfo, error) {
+	infos := make([]APIKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		info, err := s.withScopes(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+// ListForUser returns the keys owned by a user
+func (s *APIKeyService) ListForUser(ctx context.Context, zehut string) ([]APIKeyInfo, error) {
+	keys, err := s.apiKeyRepo.ListByZehut(ctx, zehut)
+	if err != nil {
+		return nil, err
+	}
+	return s.withScopesAll(ctx, keys)
+}
+
+// ListAll returns every key, including service account keys
+func (s *APIKeyService) ListAll(ctx context.Context) ([]APIKeyInfo, error) {
+	keys, err := s.apiKeyRepo.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.withScopesAll(ctx, keys)
+}
+
+// Revoke revokes a key. A non-empty ownerZehut restricts it to that user's keys.
+func (s *APIKeyService) Revoke(ctx context.Context, id int64, ownerZehut string) error {
+	key, err := s.apiKeyRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	}
+
+	if ownerZehut != "" && (key.Zehut == nil || *key.Zehut != ownerZehut) {
+		return ErrAPIKeyNotFound
+	}
+
+	return s.apiKeyRepo.Revoke(ctx, id)
+}
+
+// Authenticate returns the identity behind a plaintext API key
+func (s *APIKeyService) Authenticate(ctx context.Context, plaintext string) (*auth.Identity, error) {
+	key, err := s.apiKeyRepo.FindByHash(ctx, hashAPIKey(plaintext))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if !key.IsActive() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Keys act with their scopes only, so admin rights are never carried over
+	identity := &auth.Identity{
+		Method:   auth.MethodAPIKey,
+		APIKeyID: key.ID,
+		Scopes:   map[string]model.PermissionFlags{},
+	}
+
+	if key.Zehut != nil {
+		user, err := s.userService.GetUserByZehut(ctx, *key.Zehut)
+		if err != nil || user.IsFrozen() {
+			return nil, ErrInvalidAPIKey
+		}
+		identity.Zehut = user.Zehut
+		identity.Email = user.Email
+		identity.Name = user.FirstName + " " + user.LastName
+	} else {
+		identity.ServiceAccount = *key.ServiceAccount
+		identity.Name = *key.ServiceAccount
+	}
+
+	scopes, err := s.apiKeyRepo.ListScopes(ctx, key.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		identity.Scopes[scope.Resource] = identity.Scopes[scope.Resource].Merge(scope.PermissionFlags)
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > lastUsedInterval {
+		if err := s.apiKeyRepo.TouchLastUsed(ctx, key.ID, time.Now()); err != nil {
+			g.Log().Warning(ctx, "Failed to update API key last use:", err)
+		}
+	}
+
+	return identity, nil
+}
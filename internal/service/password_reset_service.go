@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+
+	"tzlev/internal/audit"
+	"tzlev/internal/auth"
+	"tzlev/internal/email"
+	"tzlev/internal/model"
+	"tzlev/internal/ratelimit"
+	"tzlev/internal/repository"
+)
+
+// ErrInvalidResetToken is returned for unknown, used or expired reset tokens
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+type PasswordResetService struct {
+	userRepo       *repository.UserRepository
+	userService    *UserService
+	sessionService *SessionService
+	emailService   *email.EmailService
+	limiter        *ratelimit.ResetLimiter
+	ttl            time.Duration
+	baseURL        string
+}
+
+func NewPasswordResetService() *PasswordResetService {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	return &PasswordResetService{
+		userRepo:       repository.NewUserRepository(),
+		userService:    NewUserService(),
+		sessionService: NewSessionService(),
+		emailService:   email.NewEmailService(),
+		limiter:        ratelimit.NewResetLimiter(),
+		ttl:            cfg.MustGet(ctx, "security.passwordResetTTL", "1h").Duration(),
+		baseURL:        cfg.MustGet(ctx, "app.baseURL").String(),
+	}
+}
+
+// hashResetToken returns the form of the token stored in users.email_token
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestReset emails a reset link to the user identified by zehut or email.
+// Unknown or frozen users are ignored without an error so callers cannot
+// tell which accounts exist.
+func (s *PasswordResetService) RequestReset(ctx context.Context, zehut, emailAddress string) error {
+	var user *model.User
+	var err error
+	if zehut != "" {
+		user, err = s.userRepo.FindByZehut(ctx, zehut)
+	} else {
+		user, err = s.userRepo.FindByEmail(ctx, emailAddress)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			g.Log().Info(ctx, "Password reset requested for unknown user")
+			return nil
+		}
+		return err
+	}
+
+	if user.Email == "" || user.IsFrozen() {
+		g.Log().Infof(ctx, "Password reset not sent for user %s (no email or frozen)", user.Zehut)
+		return nil
+	}
+
+	// Answered like any other request, so that the limit gives nothing away
+	retryAfter, err := s.limiter.Allow(ctx, ratelimit.SubjectAccount, user.Zehut)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		g.Log().Warningf(ctx, "Password reset not sent for user %s (too many requests)", user.Zehut)
+		return nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	user.EmailToken = hashResetToken(token)
+	user.EmailTokenSentAt = &now
+	if err := s.userService.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.baseURL, url.QueryEscape(token))
+	name := user.FirstName + " " + user.LastName
+
+	// Send in the background so the response time does not reveal whether the user exists.
+	// The request's context is cancelled once the response is sent, but keeps its request ID.
+	go func(ctx context.Context, to string) {
+		if err := s.emailService.SendPasswordResetEmail(to, name, link, s.ttl.String()); err != nil {
+			g.Log().Error(ctx, "Failed to send password reset email:", err)
+		}
+	}(context.WithoutCancel(ctx), user.Email)
+
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and revokes all of the
+// user's sessions. A password the policy rejects leaves the token usable.
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if err := s.userService.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	tokenHash := hashResetToken(token)
+
+	user, err := s.userRepo.FindByEmailToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if user.EmailTokenSentAt == nil || time.Since(*user.EmailTokenSentAt) > s.ttl {
+		return ErrInvalidResetToken
+	}
+
+	consumed, err := s.userRepo.ConsumeEmailToken(ctx, user.Zehut, tokenHash)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidResetToken
+	}
+
+	hashed, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user.HashedPassword = hashed
+	user.EmailToken = ""
+	user.EmailTokenSentAt = nil
+	if err := s.userService.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.Entry{
+		Actor:    user.Zehut,
+		Action:   "password.reset",
+		Entity:   "user",
+		EntityID: user.Zehut,
+	})
+
+	if err := s.sessionService.RevokeAllSessions(ctx, user.Zehut); err != nil {
+		g.Log().Error(ctx, "Failed to revoke sessions after password reset:", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tzlev/internal/cache"
+	"tzlev/internal/model"
+	"tzlev/internal/repository"
+)
+
+type PermissionService struct {
+	permissionRepo *repository.PermissionRepository
+	groupRepo      *repository.GroupRepository
+	userService    *UserService
+	cacheManager   *cache.CacheManager
+}
+
+func NewPermissionService() *PermissionService {
+	return &PermissionService{
+		permissionRepo: repository.NewPermissionRepository(),
+		groupRepo:      repository.NewGroupRepository(),
+		userService:    NewUserService(),
+		cacheManager:   cache.NewCacheManager(),
+	}
+}
+
+func permissionsCacheKey(zehut string) string {
+	return fmt.Sprintf("permissions:%s", zehut)
+}
+
+// GetEffectivePermissions returns the permissions a user holds on each resource,
+// combining group and direct grants
+func (s *PermissionService) GetEffectivePermissions(ctx context.Context, zehut string) (map[string]model.PermissionFlags, error) {
+	cacheKey := permissionsCacheKey(zehut)
+
+	// Try to get from cache first
+	var permissions map[string]model.PermissionFlags
+	if err := s.cacheManager.Get(ctx, cacheKey, &permissions); err == nil {
+		return permissions, nil
+	}
+
+	rows, err := s.permissionRepo.ListEffective(ctx, zehut)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions = make(map[string]model.PermissionFlags, len(rows))
+	for _, row := range rows {
+		permissions[row.Resource] = permissions[row.Resource].Merge(row.PermissionFlags)
+	}
+
+	// Store in cache for 5 minutes
+	_ = s.cacheManager.Set(ctx, cacheKey, permissions, 5*time.Minute)
+
+	return permissions, nil
+}
+
+// HasPermission reports whether a user may perform action on the named resource.
+// Admins are allowed everything.
+func (s *PermissionService) HasPermission(ctx context.Context, zehut, resource string, action model.PermissionAction) (bool, error) {
+	user, err := s.userService.GetUserByZehut(ctx, zehut)
+	if err != nil {
+		return false, err
+	}
+	if user.IsAdmin {
+		return true, nil
+	}
+
+	permissions, err := s.GetEffectivePermissions(ctx, zehut)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions[resource].Allows(action), nil
+}
+
+func (s *PermissionService) GetGroupPermissions(ctx context.Context, groupID int64) ([]model.GroupPermission, error) {
+	return s.permissionRepo.ListByGroup(ctx, groupID)
+}
+
+// SetGroupPermissions replaces a group's permissions and invalidates every cached user permission set
+func (s *PermissionService) SetGroupPermissions(ctx context.Context, groupID int64, permissions []model.GroupPermission) error {
+	if err := s.permissionRepo.ReplaceForGroup(ctx, groupID, permissions); err != nil {
+		return err
+	}
+
+	return s.InvalidateAll(ctx)
+}
+
+func (s *PermissionService) GetUserPermissions(ctx context.Context, zehut string) ([]model.UserPermission, error) {
+	return s.permissionRepo.ListByUser(ctx, zehut)
+}
+
+// SetUserPermissions replaces a user's direct permissions
+func (s *PermissionService) SetUserPermissions(ctx context.Context, zehut string, permissions []model.UserPermission) error {
+	if err := s.permissionRepo.ReplaceForUser(ctx, zehut, permissions); err != nil {
+		return err
+	}
+
+	return s.Invalidate(ctx, zehut)
+}
+
+// AddGroupMember adds a user to a group
+func (s *PermissionService) AddGroupMember(ctx context.Context, groupID int64, zehut string) error {
+	if err := s.groupRepo.AddMember(ctx, groupID, zehut); err != nil {
+		return err
+	}
+
+	return s.Invalidate(ctx, zehut)
+}
+
+// RemoveGroupMember removes a user from a group
+func (s *PermissionService) RemoveGroupMember(ctx context.Context, groupID int64, zehut string) error {
+	if err := s.groupRepo.RemoveMember(ctx, groupID, zehut); err != nil {
+		return err
+	}
+
+	return s.Invalidate(ctx, zehut)
+}
+
+// DeleteGroup deletes a group along with its memberships and permissions
+func (s *PermissionService) DeleteGroup(ctx context.Context, groupID int64) error {
+	if err := s.groupRepo.Delete(ctx, groupID); err != nil {
+		return err
+	}
+
+	return s.InvalidateAll(ctx)
+}
+
+// Invalidate drops the cached permissions of a single user
+func (s *PermissionService) Invalidate(ctx context.Context, zehut string) error {
+	return s.cacheManager.Delete(ctx, permissionsCacheKey(zehut))
+}
+
+// InvalidateAll drops every cached permission set, e.g. after a group or resource changes
+func (s *PermissionService) InvalidateAll(ctx context.Context) error {
+	return s.cacheManager.DeletePattern(ctx, permissionsCacheKey("*"))
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gctx"
+
+	"tzlev/internal/auth"
+	"tzlev/internal/model"
+	"tzlev/internal/session"
+)
+
+// ErrAccountFrozen is returned when a frozen user tries to log in
+var ErrAccountFrozen = errors.New("account is frozen")
+
+// ErrInvalidSession is returned for missing, expired or orphaned sessions
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// ErrSessionNotFound is returned when revoking a session the user does not own
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrImpersonationNotAllowed is returned when a user who is neither an admin
+// nor technical staff tries to impersonate
+var ErrImpersonationNotAllowed = errors.New("impersonation is not allowed")
+
+// ErrCannotImpersonate is returned for targets that may not be impersonated:
+// oneself, admins and frozen or unknown users
+var ErrCannotImpersonate = errors.New("user cannot be impersonated")
+
+// ErrAlreadyImpersonating is returned when starting an impersonation from an
+// impersonation session
+var ErrAlreadyImpersonating = errors.New("already impersonating")
+
+// ErrNotImpersonating is returned when ending an impersonation from a regular session
+var ErrNotImpersonating = errors.New("not impersonating")
+
+// lastSeenInterval limits how often a session's last-seen details are rewritten
+const lastSeenInterval = time.Minute
+
+// SessionInfo describes one of a user's sessions without exposing its ID
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	Method     string    `json:"method"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// SessionService establishes and resolves login sessions. Every login method
+// goes through Establish so all sessions carry the same identity.
+type SessionService struct {
+	userService      *UserService
+	twoFactorService *TwoFactorService
+	sessionManager   *session.SessionManager
+	tokenStore       *session.RefreshTokenStore
+	// Longest an impersonation session lives
+	impersonationTTL time.Duration
+}
+
+func NewSessionService() *SessionService {
+	ctx := gctx.New()
+
+	return &SessionService{
+		userService:      NewUserService(),
+		twoFactorService: NewTwoFactorService(),
+		sessionManager:   session.NewSessionManager(),
+		tokenStore:       session.NewRefreshTokenStore(),
+		impersonationTTL: g.Cfg().MustGet(ctx, "security.impersonation.maxDuration", "1h").Duration(),
+	}
+}
+
+// CanImpersonate reports whether user may start impersonation sessions
+func CanImpersonate(user *model.User) bool {
+	return !user.IsFrozen() && (user.IsAdmin || user.IsTechnical)
+}
+
+// Establish logs user in on the request's cookie session. The session ID is
+// regenerated to prevent fixation, and any session stored under the old ID is dropped.
+func (s *SessionService) Establish(r *ghttp.Request, user *model.User, method string) (*session.Session, error) {
+	ctx := r.Context()
+
+	if user.IsFrozen() {
+		return nil, ErrAccountFrozen
+	}
+
+	needsEnrollment, err := s.twoFactorService.NeedsEnrollment(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check two-factor policy: %w", err)
+	}
+
+	limits := s.sessionManager.LimitsFor(user.IsAdmin)
+	sess := &session.Session{
+		Zehut:       user.Zehut,
+		Email:       user.Email,
+		Name:        user.FirstName + " " + user.LastName,
+		Method:      method,
+		UserAgent:   r.UserAgent(),
+		IP:          auth.ClientIP(r),
+		IdleTimeout: limits.Idle,
+		ExpiresAt:   time.Now().Add(limits.Absolute),
+
+		MFAEnrollmentRequired: needsEnrollment,
+	}
+
+	if err := s.replaceSession(r, sess); err != nil {
+		return nil, err
+	}
+
+	return sess, nil
+}
+
+// replaceSession stores sess under a new ID for the request's cookie session
+// and drops the session stored under the old ID
+func (s *SessionService) replaceSession(r *ghttp.Request, sess *session.Session) error {
+	ctx := r.Context()
+
+	if oldID, err := r.Session.Id(); err == nil && oldID != "" {
+		if err := s.sessionManager.Delete(ctx, oldID); err != nil {
+			g.Log().Warning(ctx, "Failed to delete previous session:", err)
+		}
+	}
+
+	sessionID, err := r.Session.RegenerateId(true)
+	if err != nil {
+		return fmt.Errorf("failed to regenerate session id: %w", err)
+	}
+
+	if err := s.sessionManager.Create(ctx, sessionID, sess); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	// Marks the cookie session as logged in so GoFrame issues the cookie
+	r.Session.Set("user_zehut", sess.Zehut)
+
+	return nil
+}
+
+// StartImpersonation replaces the request's session with one acting as the
+// target user. It lasts at most security.impersonation.maxDuration and never
+// outlives the impersonator's own session, which StopImpersonation restores.
+func (s *SessionService) StartImpersonation(r *ghttp.Request, targetZehut string) (*model.User, error) {
+	ctx := r.Context()
+
+	sessionID, _ := r.Session.Id()
+	current, err := s.sessionManager.Get(ctx, sessionID)
+	if err != nil || current.Zehut == "" {
+		return nil, ErrInvalidSession
+	}
+	if current.Impersonator != nil {
+		return nil, ErrAlreadyImpersonating
+	}
+
+	impersonator, err := s.userService.LoadUser(ctx, current.Zehut)
+	if err != nil {
+		return nil, err
+	}
+	if !CanImpersonate(impersonator) {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	target, err := s.userService.LoadUser(ctx, targetZehut)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCannotImpersonate
+		}
+		return nil, err
+	}
+	if target.Zehut == impersonator.Zehut || target.IsAdmin || target.IsFrozen() {
+		return nil, ErrCannotImpersonate
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.impersonationTTL)
+	if current.ExpiresAt.Before(expiresAt) {
+		expiresAt = current.ExpiresAt
+	}
+
+	sess := &session.Session{
+		Zehut:       target.Zehut,
+		Email:       target.Email,
+		Name:        target.FirstName + " " + target.LastName,
+		Method:      current.Method,
+		UserAgent:   r.UserAgent(),
+		IP:          auth.ClientIP(r),
+		IdleTimeout: s.sessionManager.LimitsFor(true).Idle,
+		ExpiresAt:   expiresAt,
+		Impersonator: &session.Impersonator{
+			Zehut:     impersonator.Zehut,
+			Name:      impersonator.FirstName + " " + impersonator.LastName,
+			Method:    current.Method,
+			ExpiresAt: current.ExpiresAt,
+			StartedAt: now,
+		},
+	}
+
+	if err := s.replaceSession(r, sess); err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// StopImpersonation ends an impersonation session and logs the impersonator
+// back in with the lifetime their session had before. It returns the
+// impersonator's session, or ErrInvalidSession when that has expired meanwhile.
+func (s *SessionService) StopImpersonation(r *ghttp.Request) (*session.Session, error) {
+	ctx := r.Context()
+
+	sessionID, _ := r.Session.Id()
+	current, err := s.sessionManager.Get(ctx, sessionID)
+	if err != nil || current.Zehut == "" {
+		return nil, ErrInvalidSession
+	}
+	if current.Impersonator == nil {
+		return nil, ErrNotImpersonating
+	}
+
+	impersonator, err := s.userService.LoadUser(ctx, current.Impersonator.Zehut)
+	if err != nil || impersonator.IsFrozen() || !time.Now().Before(current.Impersonator.ExpiresAt) {
+		if err := s.End(r); err != nil {
+			g.Log().Warning(ctx, "Failed to end impersonation session:", err)
+		}
+		return nil, ErrInvalidSession
+	}
+
+	sess := &session.Session{
+		Zehut:       impersonator.Zehut,
+		Email:       impersonator.Email,
+		Name:        impersonator.FirstName + " " + impersonator.LastName,
+		Method:      current.Impersonator.Method,
+		UserAgent:   r.UserAgent(),
+		IP:          auth.ClientIP(r),
+		IdleTimeout: s.sessionManager.LimitsFor(impersonator.IsAdmin).Idle,
+		ExpiresAt:   current.Impersonator.ExpiresAt,
+	}
+
+	if err := s.replaceSession(r, sess); err != nil {
+		return nil, err
+	}
+
+	return sess, nil
+}
+
+// Resolve returns the identity behind a session ID and extends the session.
+// Sessions of users that were deleted or frozen are revoked.
+func (s *SessionService) Resolve(ctx context.Context, sessionID, ip string) (*auth.Identity, error) {
+	sess, err := s.sessionManager.Get(ctx, sessionID)
+	if err != nil || sess.Zehut == "" {
+		return nil, ErrInvalidSession
+	}
+
+	user, err := s.userService.GetUserByZehut(ctx, sess.Zehut)
+	if err != nil || user.IsFrozen() {
+		if err := s.sessionManager.Delete(ctx, sessionID); err != nil {
+			g.Log().Warning(ctx, "Failed to revoke session:", err)
+		}
+		return nil, ErrInvalidSession
+	}
+
+	// Impersonation ends as soon as the impersonator loses the right to it
+	var impersonator *auth.Impersonator
+	if sess.Impersonator != nil {
+		staff, err := s.userService.GetUserByZehut(ctx, sess.Impersonator.Zehut)
+		if err != nil || !CanImpersonate(staff) {
+			if err := s.sessionManager.Delete(ctx, sessionID); err != nil {
+				g.Log().Warning(ctx, "Failed to revoke impersonation session:", err)
+			}
+			return nil, ErrInvalidSession
+		}
+		impersonator = &auth.Impersonator{
+			Zehut: sess.Impersonator.Zehut,
+			Name:  sess.Impersonator.Name,
+		}
+	}
+
+	changed := false
+	if time.Since(sess.LastSeenAt) > lastSeenInterval || sess.IP != ip {
+		sess.LastSeenAt = time.Now()
+		sess.IP = ip
+		changed = true
+	}
+
+	// Users promoted to admin during a session get the admin limits from now on
+	if user.IsAdmin && sess.Impersonator == nil {
+		limits := s.sessionManager.LimitsFor(true)
+		if sess.IdleTimeout > limits.Idle {
+			sess.IdleTimeout = limits.Idle
+			changed = true
+		}
+		if maxExpiry := sess.CreatedAt.Add(limits.Absolute); sess.ExpiresAt.After(maxExpiry) {
+			sess.ExpiresAt = maxExpiry
+			changed = true
+		}
+	}
+
+	// The enrollment restriction is lifted as soon as the user confirms 2FA
+	if sess.MFAEnrollmentRequired {
+		if needsEnrollment, err := s.twoFactorService.NeedsEnrollment(ctx, user); err == nil && !needsEnrollment {
+			sess.MFAEnrollmentRequired = false
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := s.sessionManager.Touch(ctx, sessionID, sess); err != nil {
+			g.Log().Warning(ctx, "Failed to update session:", err)
+		}
+	}
+
+	if err := s.sessionManager.Refresh(ctx, sessionID, sess); err != nil {
+		g.Log().Warning(ctx, "Failed to refresh session:", err)
+	}
+
+	return &auth.Identity{
+		Zehut:     user.Zehut,
+		Email:     user.Email,
+		Name:      user.FirstName + " " + user.LastName,
+		IsAdmin:   user.IsAdmin,
+		Method:    sess.Method,
+		SessionID: sessionID,
+
+		MFAEnrollmentRequired: sess.MFAEnrollmentRequired,
+		Impersonator:          impersonator,
+	}, nil
+}
+
+// End logs the request's session out
+func (s *SessionService) End(r *ghttp.Request) error {
+	sessionID, err := r.Session.Id()
+	if err != nil || sessionID == "" {
+		return err
+	}
+
+	if err := s.sessionManager.Delete(r.Context(), sessionID); err != nil {
+		return err
+	}
+
+	return r.Session.RemoveAll()
+}
+
+// ListSessions returns the active sessions of a user. currentSessionID marks
+// the caller's own session and may be empty.
+func (s *SessionService) ListSessions(ctx context.Context, zehut, currentSessionID string) ([]SessionInfo, error) {
+	sessions, err := s.sessionManager.ListForUser(ctx, zehut)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		infos = append(infos, SessionInfo{
+			ID:         session.PublicID(sess.ID),
+			Method:     sess.Method,
+			UserAgent:  sess.UserAgent,
+			IP:         sess.IP,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    sess.ID == currentSessionID,
+		})
+	}
+
+	return infos, nil
+}
+
+// RevokeSession ends one of a user's sessions, identified by its public ID
+func (s *SessionService) RevokeSession(ctx context.Context, zehut, publicID string) error {
+	sessions, err := s.sessionManager.ListForUser(ctx, zehut)
+	if err != nil {
+		return err
+	}
+
+	for _, sess := range sessions {
+		if session.PublicID(sess.ID) == publicID {
+			return s.sessionManager.Delete(ctx, sess.ID)
+		}
+	}
+
+	return ErrSessionNotFound
+}
+
+// RevokeOtherSessions ends every session of a user except keepSessionID
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, zehut, keepSessionID string) (int, error) {
+	sessions, err := s.sessionManager.ListForUser(ctx, zehut)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, sess := range sessions {
+		if sess.ID == keepSessionID {
+			continue
+		}
+		if err := s.sessionManager.Delete(ctx, sess.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// RevokeAllSessions logs a user out everywhere, including API clients holding bearer tokens
+func (s *SessionService) RevokeAllSessions(ctx context.Context, zehut string) error {
+	if err := s.sessionManager.DeleteAllForUser(ctx, zehut); err != nil {
+		return err
+	}
+
+	return s.tokenStore.RevokeAllForUser(ctx, zehut)
+}
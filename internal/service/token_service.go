@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+
+	"tzlev/internal/auth"
+	"tzlev/internal/jwt"
+	"tzlev/internal/model"
+	"tzlev/internal/session"
+)
+
+// ErrInvalidToken is returned for access tokens that fail verification or
+// whose token family was revoked
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// TokenPair is issued to API clients by /auth/token
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenService issues and verifies bearer tokens for API clients. Access tokens
+// are short-lived JWTs; refresh tokens are single-use and rotated on every refresh.
+type TokenService struct {
+	userService *UserService
+	tokenStore  *session.RefreshTokenStore
+}
+
+func NewTokenService() *TokenService {
+	return &TokenService{
+		userService: NewUserService(),
+		tokenStore:  session.NewRefreshTokenStore(),
+	}
+}
+
+// Issue starts a new token family for an authenticated user
+func (s *TokenService) Issue(ctx context.Context, user *model.User) (*TokenPair, error) {
+	if user.IsFrozen() {
+		return nil, ErrAccountFrozen
+	}
+
+	refreshToken, family, err := s.tokenStore.Issue(ctx, user.Zehut)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pair(user, family, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Reusing a refresh
+// token revokes its whole family.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	record, next, err := s.tokenStore.Rotate(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, session.ErrRefreshTokenReused) {
+			g.Log().Warningf(ctx, "Refresh token reuse detected for user %s, token family revoked", record.Zehut)
+		}
+		return nil, err
+	}
+
+	user, err := s.userService.GetUserByZehut(ctx, record.Zehut)
+	if err != nil || user.IsFrozen() {
+		if err := s.tokenStore.RevokeFamily(ctx, record.Zehut, record.Family); err != nil {
+			g.Log().Warning(ctx, "Failed to revoke token family:", err)
+		}
+		return nil, session.ErrInvalidRefreshToken
+	}
+
+	return s.pair(user, record.Family, next)
+}
+
+// Revoke ends the token family of a refresh token
+func (s *TokenService) Revoke(ctx context.Context, refreshToken string) error {
+	return s.tokenStore.Revoke(ctx, refreshToken)
+}
+
+// RevokeAll ends every token family of a user
+func (s *TokenService) RevokeAll(ctx context.Context, zehut string) error {
+	return s.tokenStore.RevokeAllForUser(ctx, zehut)
+}
+
+// Authenticate returns the identity behind an access token
+func (s *TokenService) Authenticate(ctx context.Context, accessToken string) (*auth.Identity, error) {
+	claims, err := jwt.VerifyToken(accessToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	active, err := s.tokenStore.FamilyActive(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.userService.GetUserByZehut(ctx, claims.Zehut)
+	if err != nil || user.IsFrozen() {
+		return nil, ErrInvalidToken
+	}
+
+	return &auth.Identity{
+		Zehut:   user.Zehut,
+		Email:   user.Email,
+		Name:    user.FirstName + " " + user.LastName,
+		IsAdmin: user.IsAdmin,
+		Method:  auth.MethodToken,
+	}, nil
+}
+
+func (s *TokenService) pair(user *model.User, family, refreshToken string) (*TokenPair, error) {
+	accessToken, err := jwt.GenerateToken(user.Zehut, user.Email, user.FirstName+" "+user.LastName, family)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(jwt.Expiration().Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
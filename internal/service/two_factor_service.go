@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+
+	"tzlev/internal/auth"
+	"tzlev/internal/model"
+	"tzlev/internal/repository"
+	"tzlev/internal/session"
+)
+
+// ErrTwoFactorAlreadyEnabled is returned when enrolling a user who already confirmed 2FA
+var ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+// ErrTwoFactorNotEnabled is returned when verifying a code for a user without confirmed 2FA
+var ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+
+// ErrTwoFactorRequired is returned when a user the policy applies to tries to disable 2FA
+var ErrTwoFactorRequired = errors.New("two-factor authentication is required for this account")
+
+// ErrInvalidTwoFactorCode is returned for wrong, expired, replayed or used-up codes
+var ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+// recoveryCodeLength is the number of base32 characters in a recovery code
+const recoveryCodeLength = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorStatus describes a user's two-factor setup
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Pending                bool `json:"pending"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TOTPEnrollment is returned when enrollment starts. The URI is meant to be
+// shown as a QR code; the secret is for typing into the app by hand.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// ChallengeResult is a login whose second factor was verified
+type ChallengeResult struct {
+	User             *model.User
+	Method           string
+	RecoveryCodeUsed bool
+}
+
+// TwoFactorService manages TOTP enrollment, recovery codes and the second
+// login step. Which accounts must use 2FA is set by the security.mfa policy.
+type TwoFactorService struct {
+	repo        *repository.TwoFactorRepository
+	userService *UserService
+	challenges  *session.ChallengeStore
+
+	issuer                   string
+	recoveryCodeCount        int
+	requiredForAdmins        bool
+	requiredForSensitiveDocs bool
+	requiredRoles            map[string]bool
+}
+
+func NewTwoFactorService() *TwoFactorService {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	requiredRoles := make(map[string]bool)
+	for _, role := range cfg.MustGet(ctx, "security.mfa.requiredRoles").Strings() {
+		requiredRoles[role] = true
+	}
+
+	return &TwoFactorService{
+		repo:        repository.NewTwoFactorRepository(),
+		userService: NewUserService(),
+		challenges:  session.NewChallengeStore(),
+
+		issuer:                   cfg.MustGet(ctx, "security.mfa.issuer", cfg.MustGet(ctx, "app.name", "Tzlev").String()).String(),
+		recoveryCodeCount:        cfg.MustGet(ctx, "security.mfa.recoveryCodes", 10).Int(),
+		requiredForAdmins:        cfg.MustGet(ctx, "security.mfa.requiredForAdmins", true).Bool(),
+		requiredForSensitiveDocs: cfg.MustGet(ctx, "security.mfa.requiredForSensitiveDocs", true).Bool(),
+		requiredRoles:            requiredRoles,
+	}
+}
+
+// IsRequired reports whether the policy makes 2FA mandatory for user
+func (s *TwoFactorService) IsRequired(user *model.User) bool {
+	return (s.requiredForAdmins && user.IsAdmin) ||
+		(s.requiredForSensitiveDocs && user.CanSeeSensitiveDocs) ||
+		s.requiredRoles[user.Role]
+}
+
+// findTOTP returns a user's TOTP record, or nil if they never started enrollment
+func (s *TwoFactorService) findTOTP(ctx context.Context, zehut string) (*model.UserTOTP, error) {
+	totp, err := s.repo.FindTOTP(ctx, zehut)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return totp, err
+}
+
+// IsEnabled reports whether a user has confirmed 2FA
+func (s *TwoFactorService) IsEnabled(ctx context.Context, zehut string) (bool, error) {
+	totp, err := s.findTOTP(ctx, zehut)
+	if err != nil {
+		return false, err
+	}
+	return totp != nil && totp.IsConfirmed(), nil
+}
+
+// NeedsEnrollment reports whether the policy requires 2FA for a user who has not set it up
+func (s *TwoFactorService) NeedsEnrollment(ctx context.Context, user *model.User) (bool, error) {
+	if !s.IsRequired(user) {
+		return false, nil
+	}
+
+	enabled, err := s.IsEnabled(ctx, user.Zehut)
+	return !enabled, err
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, user *model.User) (*TwoFactorStatus, error) {
+	status := &TwoFactorStatus{Required: s.IsRequired(user)}
+
+	totp, err := s.findTOTP(ctx, user.Zehut)
+	if err != nil || totp == nil {
+		return status, err
+	}
+
+	status.Enabled = totp.IsConfirmed()
+	status.Pending = !totp.IsConfirmed()
+	if status.Enabled {
+		if status.RecoveryCodesRemaining, err = s.repo.CountUnusedRecoveryCodes(ctx, user.Zehut); err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// BeginEnrollment generates a new secret for user. 2FA is not enabled until
+// ConfirmEnrollment receives a code generated from it.
+func (s *TwoFactorService) BeginEnrollment(ctx context.Context, user *model.User) (*TOTPEnrollment, error) {
+	enabled, err := s.IsEnabled(ctx, user.Zehut)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SavePendingTOTP(ctx, user.Zehut, secret); err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Zehut
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    auth.TOTPProvisioningURI(secret, s.issuer, account),
+	}, nil
+}
+
+// ConfirmEnrollment enables 2FA once the user proves their app generates valid
+// codes. It returns the recovery codes, which are never shown again.
+func (s *TwoFactorService) ConfirmEnrollment(ctx context.Context, zehut, code string) ([]string, error) {
+	totp, err := s.findTOTP(ctx, zehut)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if totp.IsConfirmed() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, normalizeCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ConfirmTOTP(ctx, zehut, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks a TOTP code or an unused recovery code and reports whether a
+// recovery code was consumed. Each TOTP code is accepted only once.
+func (s *TwoFactorService) Verify(ctx context.Context, zehut, code string) (bool, error) {
+	totp, err := s.findTOTP(ctx, zehut)
+	if err != nil {
+		return false, err
+	}
+	if totp == nil || !totp.IsConfirmed() {
+		return false, ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeCode(code)
+
+	if step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now()); ok {
+		fresh, err := s.repo.UseTOTPStep(ctx, zehut, step)
+		if err != nil {
+			return false, err
+		}
+		if !fresh {
+			return false, ErrInvalidTwoFactorCode
+		}
+		return false, nil
+	}
+
+	if len(code) != recoveryCodeLength {
+		return false, ErrInvalidTwoFactorCode
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, zehut, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	if !used {
+		return false, ErrInvalidTwoFactorCode
+	}
+	return true, nil
+}
+
+// Disable turns 2FA off. Callers must check a current code with Verify first.
+// Users the policy applies to cannot disable it.
+func (s *TwoFactorService) Disable(ctx context.Context, user *model.User) error {
+	if s.IsRequired(user) {
+		return ErrTwoFactorRequired
+	}
+
+	return s.repo.DeleteTOTP(ctx, user.Zehut)
+}
+
+// Reset removes a user's 2FA without a code, for users who lost their device.
+// Users the policy applies to must enroll again on their next login.
+func (s *TwoFactorService) Reset(ctx context.Context, zehut string) error {
+	return s.repo.DeleteTOTP(ctx, zehut)
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes. Callers must check
+// a current code with Verify first.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, zehut string) ([]string, error) {
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, zehut, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// StartChallenge holds a login whose first factor succeeded until the second
+// factor is verified. purpose is session.ChallengeSession or session.ChallengeToken.
+func (s *TwoFactorService) StartChallenge(ctx context.Context, user *model.User, method, purpose string) (string, error) {
+	return s.challenges.Create(ctx, &session.Challenge{
+		Zehut:   user.Zehut,
+		Method:  method,
+		Purpose: purpose,
+	})
+}
+
+// AttemptChallenge counts an attempt at a pending challenge and returns it
+func (s *TwoFactorService) AttemptChallenge(ctx context.Context, token, purpose string) (*session.Challenge, error) {
+	challenge, err := s.challenges.Attempt(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Purpose != purpose {
+		return nil, session.ErrInvalidChallenge
+	}
+	return challenge, nil
+}
+
+// CompleteChallenge verifies the second factor of a challenge returned by
+// AttemptChallenge and ends the challenge
+func (s *TwoFactorService) CompleteChallenge(ctx context.Context, token string, challenge *session.Challenge, code string) (*ChallengeResult, error) {
+	recoveryCodeUsed, err := s.Verify(ctx, challenge.Zehut, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.challenges.Delete(ctx, token); err != nil {
+		g.Log().Warning(ctx, "Failed to delete two-factor challenge:", err)
+	}
+
+	user, err := s.userService.LoadUser(ctx, challenge.Zehut)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChallengeResult{
+		User:             user,
+		Method:           challenge.Method,
+		RecoveryCodeUsed: recoveryCodeUsed,
+	}, nil
+}
+
+// generateRecoveryCodes returns new recovery codes formatted for display, and their hashes
+func (s *TwoFactorService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, s.recoveryCodeCount)
+	hashes := make([]string, s.recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, recoveryCodeLength*5/8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeCode strips the separators users tend to type or paste with a code
+func normalizeCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+
+	"tzlev/internal/model"
+	"tzlev/internal/oauth"
+	"tzlev/internal/repository"
+)
+
+// ErrIdentityNotLinked is returned when no user may log in with an external identity
+var ErrIdentityNotLinked = errors.New("identity is not linked to a user")
+
+// ErrIdentityLinkedToOther is returned when linking an identity that belongs to another user
+var ErrIdentityLinkedToOther = errors.New("identity is linked to another user")
+
+// ErrIdentityNotFound is returned when unlinking an identity the user does not have
+var ErrIdentityNotFound = errors.New("identity not found")
+
+// ErrLastLoginMethod is returned when unlinking would leave a user unable to log in
+var ErrLastLoginMethod = errors.New("cannot unlink the only way to log in")
+
+// UserIdentityService links users to their accounts at external identity
+// providers and resolves provider logins to users
+type UserIdentityService struct {
+	identityRepo *repository.UserIdentityRepository
+	userRepo     *repository.UserRepository
+	linkByEmail  bool
+}
+
+func NewUserIdentityService() *UserIdentityService {
+	ctx := gctx.New()
+
+	return &UserIdentityService{
+		identityRepo: repository.NewUserIdentityRepository(),
+		userRepo:     repository.NewUserRepository(),
+		linkByEmail:  g.Cfg().MustGet(ctx, "oauth.linkByEmail", false).Bool(),
+	}
+}
+
+// ResolveLogin returns the user an external identity logs in as. Linked
+// identities are looked up by provider subject. With oauth.linkByEmail set, an
+// unlinked identity whose verified email matches a user is linked to that user,
+// unless the user already linked another account at the same provider.
+func (s *UserIdentityService) ResolveLogin(ctx context.Context, provider string, identity *oauth.Identity) (*model.User, error) {
+	linked, err := s.identityRepo.FindBySubject(ctx, provider, identity.Subject)
+	if err == nil {
+		if err := s.identityRepo.TouchLastLogin(ctx, linked.ID, time.Now()); err != nil {
+			g.Log().Warning(ctx, "Failed to update identity last login:", err)
+		}
+		return s.userRepo.FindByZehut(ctx, linked.Zehut)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if !s.linkByEmail || identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrIdentityNotLinked
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, identity.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdentityNotLinked
+		}
+		return nil, err
+	}
+
+	existing, err := s.identityRepo.ListByZehut(ctx, user.Zehut)
+	if err != nil {
+		return nil, err
+	}
+	for _, linked := range existing {
+		if linked.Provider == provider {
+			return nil, ErrIdentityNotLinked
+		}
+	}
+
+	now := time.Now()
+	if err := s.identityRepo.Create(ctx, &model.UserIdentity{
+		Zehut:       user.Zehut,
+		Provider:    provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+
+	g.Log().Infof(ctx, "Linked %s identity %s to user %s by email", provider, identity.Subject, user.Zehut)
+	return user, nil
+}
+
+// Link adds an external identity to a user. Linking an identity the user
+// already has is a no-op.
+func (s *UserIdentityService) Link(ctx context.Context, zehut, provider string, identity *oauth.Identity) (*model.UserIdentity, error) {
+	linked, err := s.identityRepo.FindBySubject(ctx, provider, identity.Subject)
+	if err == nil {
+		if linked.Zehut != zehut {
+			return nil, ErrIdentityLinkedToOther
+		}
+		return linked, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	linked = &model.UserIdentity{
+		Zehut:    zehut,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := s.identityRepo.Create(ctx, linked); err != nil {
+		return nil, err
+	}
+
+	return linked, nil
+}
+
+func (s *UserIdentityService) List(ctx context.Context, zehut string) ([]model.UserIdentity, error) {
+	return s.identityRepo.ListByZehut(ctx, zehut)
+}
+
+// Unlink removes one of a user's identities. A user without a password must
+// keep at least one identity.
+func (s *UserIdentityService) Unlink(ctx context.Context, zehut string, id int64) (*model.UserIdentity, error) {
+	identities, err := s.identityRepo.ListByZehut(ctx, zehut)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *model.UserIdentity
+	for i := range identities {
+		if identities[i].ID == id {
+			target = &identities[i]
+		}
+	}
+	if target == nil {
+		return nil, ErrIdentityNotFound
+	}
+
+	user, err := s.userRepo.FindByZehut(ctx, zehut)
+	if err != nil {
+		return nil, err
+	}
+	if user.HashedPassword == "" && len(identities) == 1 {
+		return nil, ErrLastLoginMethod
+	}
+
+	deleted, err := s.identityRepo.Delete(ctx, id, zehut)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrIdentityNotFound
+	}
+
+	return target, nil
+}
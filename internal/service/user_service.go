@@ -5,20 +5,23 @@ import (
 	"fmt"
 	"time"
 
+	"tzlev/internal/auth"
 	"tzlev/internal/cache"
 	"tzlev/internal/model"
 	"tzlev/internal/repository"
 )
 
 type UserService struct {
-	userRepo     *repository.UserRepository
-	cacheManager *cache.CacheManager
+	userRepo       *repository.UserRepository
+	cacheManager   *cache.CacheManager
+	passwordPolicy *auth.PasswordPolicy
 }
 
 func NewUserService() *UserService {
 	return &UserService{
-		userRepo:     repository.NewUserRepository(),
-		cacheManager: cache.NewCacheManager(),
+		userRepo:       repository.NewUserRepository(),
+		cacheManager:   cache.NewCacheManager(),
+		passwordPolicy: auth.ConfiguredPasswordPolicy(),
 	}
 }
 
@@ -60,3 +63,99 @@ func (s *UserService) UpdateUser(ctx context.Context, user *model.User) error {
 
 	return nil
 }
+
+// LoadUser reads a user straight from the database. Use it instead of
+// GetUserByZehut before writing a user back: the cached copy is JSON and
+// lacks hidden fields such as the password hash.
+func (s *UserService) LoadUser(ctx context.Context, zehut string) (*model.User, error) {
+	return s.userRepo.FindByZehut(ctx, zehut)
+}
+
+// ListUsers returns a page of users matching search along with the total number of matches
+func (s *UserService) ListUsers(ctx context.Context, search string, offset, limit int) ([]model.User, int, error) {
+	users, err := s.userRepo.Search(ctx, search, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.userRepo.Count(ctx, search)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// ValidatePassword checks a new password against the password policy. The
+// error is an *auth.PasswordPolicyError when the password is rejected.
+func (s *UserService) ValidatePassword(password string) error {
+	return s.passwordPolicy.Validate(password)
+}
+
+// UpgradePasswordHash rehashes a password that was just verified against
+// user's hash when the hash algorithm or its cost changed since
+func (s *UserService) UpgradePasswordHash(ctx context.Context, user *model.User, password string) error {
+	if !auth.NeedsRehash(user.HashedPassword) {
+		return nil
+	}
+
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.userRepo.UpdatePasswordHash(ctx, user.Zehut, user.HashedPassword, hashed); err != nil {
+		return err
+	}
+	user.HashedPassword = hashed
+
+	return nil
+}
+
+// CreateUser stores a new user with the given plaintext password hashed. The
+// password must satisfy the password policy.
+func (s *UserService) CreateUser(ctx context.Context, user *model.User, password string) error {
+	if err := s.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.HashedPassword = hashed
+
+	return s.userRepo.Create(ctx, user)
+}
+
+// ConfirmUser marks the first login of a user through an identity provider
+// and takes their avatar from it
+func (s *UserService) ConfirmUser(ctx context.Context, user *model.User, avatar string) error {
+	now := time.Now()
+	user.ConfirmedAt = &now
+	user.Avatar = avatar
+
+	return s.UpdateUser(ctx, user)
+}
+
+// SetFreezed freezes or unfreezes a user account
+func (s *UserService) SetFreezed(ctx context.Context, zehut string, freezed bool) error {
+	user, err := s.LoadUser(ctx, zehut)
+	if err != nil {
+		return err
+	}
+
+	user.IsFreezed = &freezed
+	return s.UpdateUser(ctx, user)
+}
+
+// SetAdmin grants or revokes admin rights
+func (s *UserService) SetAdmin(ctx context.Context, zehut string, isAdmin bool) error {
+	user, err := s.LoadUser(ctx, zehut)
+	if err != nil {
+		return err
+	}
+
+	user.IsAdmin = isAdmin
+	return s.UpdateUser(ctx, user)
+}
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	goredis "github.com/redis/go-redis/v9"
+
+	"tzlev/internal/redis"
+)
+
+// ErrInvalidChallenge is returned for unknown or expired two-factor challenges,
+// and for challenges that used up their attempts
+var ErrInvalidChallenge = errors.New("invalid or expired two-factor challenge")
+
+// Challenge purposes: what a challenge unlocks once the second factor is verified
+const (
+	ChallengeSession = "session"
+	ChallengeToken   = "token"
+)
+
+// Challenge is a half-authenticated login: the password or the external
+// provider login was verified but the second factor was not yet
+type Challenge struct {
+	Zehut     string    `json:"zehut"`
+	Method    string    `json:"method"`
+	Purpose   string    `json:"purpose"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChallengeStore keeps pending two-factor challenges in Redis, keyed by the
+// hash of a random token handed to the client
+type ChallengeStore struct {
+	prefix      string
+	ttl         time.Duration
+	maxAttempts int64
+}
+
+func NewChallengeStore() *ChallengeStore {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	return &ChallengeStore{
+		prefix:      cfg.MustGet(ctx, "redis.mfaChallengePrefix", "tzlev:mfa:").String(),
+		ttl:         cfg.MustGet(ctx, "security.mfa.challengeTTL", "5m").Duration(),
+		maxAttempts: cfg.MustGet(ctx, "security.mfa.maxAttempts", 5).Int64(),
+	}
+}
+
+func (cs *ChallengeStore) key(token string) string {
+	return fmt.Sprintf("%schallenge:%s", cs.prefix, hashRefreshToken(token))
+}
+
+func (cs *ChallengeStore) attemptsKey(token string) string {
+	return fmt.Sprintf("%sattempts:%s", cs.prefix, hashRefreshToken(token))
+}
+
+// Create stores a challenge and returns the token that identifies it
+func (cs *ChallengeStore) Create(ctx context.Context, challenge *Challenge) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate challenge token: %w", err)
+	}
+
+	challenge.CreatedAt = time.Now()
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal challenge: %w", err)
+	}
+
+	if err := redis.Client.Set(ctx, cs.key(token), data, cs.ttl).Err(); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Attempt counts a verification attempt and returns the challenge. Once the
+// attempts are used up the challenge is deleted and the login must start over.
+func (cs *ChallengeStore) Attempt(ctx context.Context, token string) (*Challenge, error) {
+	data, err := redis.Client.Get(ctx, cs.key(token)).Bytes()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	pipe := redis.Client.TxPipeline()
+	incr := pipe.Incr(ctx, cs.attemptsKey(token))
+	pipe.Expire(ctx, cs.attemptsKey(token), cs.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	if incr.Val() > cs.maxAttempts {
+		if err := cs.Delete(ctx, token); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidChallenge
+	}
+
+	var challenge Challenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal challenge: %w", err)
+	}
+
+	return &challenge, nil
+}
+
+// Delete removes a challenge once it has been completed
+func (cs *ChallengeStore) Delete(ctx context.Context, token string) error {
+	return redis.Client.Del(ctx, cs.key(token), cs.attemptsKey(token)).Err()
+}
@@ -0,0 +1,244 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	goredis "github.com/redis/go-redis/v9"
+
+	"tzlev/internal/redis"
+)
+
+// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReused is returned when a refresh token is presented a second
+// time. The whole token family is revoked because the token was probably stolen.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// RefreshToken is the stored record of an issued refresh token. Tokens that
+// descend from the same login share a Family, started at FamilyStartedAt.
+type RefreshToken struct {
+	Zehut           string    `json:"zehut"`
+	Family          string    `json:"family"`
+	FamilyStartedAt time.Time `json:"family_started_at"`
+	IssuedAt        time.Time `json:"issued_at"`
+}
+
+// RefreshTokenStore keeps hashed refresh tokens in Redis. Every token can be
+// used once; using it issues a replacement in the same family. A family ends
+// maxLifetime after the login however often it is refreshed.
+type RefreshTokenStore struct {
+	prefix      string
+	ttl         time.Duration
+	maxLifetime time.Duration
+}
+
+func NewRefreshTokenStore() *RefreshTokenStore {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	return &RefreshTokenStore{
+		prefix:      cfg.MustGet(ctx, "redis.refreshTokenPrefix", "tzlev:refresh:").String(),
+		ttl:         cfg.MustGet(ctx, "security.refreshTokenTTL", "720h").Duration(),
+		maxLifetime: cfg.MustGet(ctx, "security.refreshTokenMaxLifetime", "2160h").Duration(),
+	}
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (rs *RefreshTokenStore) tokenKey(hash string) string {
+	return fmt.Sprintf("%stoken:%s", rs.prefix, hash)
+}
+
+func (rs *RefreshTokenStore) usedKey(hash string) string {
+	return fmt.Sprintf("%sused:%s", rs.prefix, hash)
+}
+
+func (rs *RefreshTokenStore) familyKey(family string) string {
+	return fmt.Sprintf("%sfamily:%s", rs.prefix, family)
+}
+
+// userKey is the set of token families belonging to a user
+func (rs *RefreshTokenStore) userKey(zehut string) string {
+	return fmt.Sprintf("%suser:%s", rs.prefix, zehut)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Issue starts a new token family for zehut. It returns the plaintext token
+// and its family.
+func (rs *RefreshTokenStore) Issue(ctx context.Context, zehut string) (string, string, error) {
+	family, err := randomToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate token family: %w", err)
+	}
+
+	token, err := rs.issue(ctx, zehut, family, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, family, nil
+}
+
+// issue creates a refresh token in a family started at startedAt. The token
+// and the family expire after the TTL, or earlier when the family reaches its
+// absolute lifetime.
+func (rs *RefreshTokenStore) issue(ctx context.Context, zehut, family string, startedAt time.Time) (string, error) {
+	now := time.Now()
+
+	ttl := rs.ttl
+	if remaining := startedAt.Add(rs.maxLifetime).Sub(now); remaining < ttl {
+		ttl = remaining
+	}
+	if ttl <= 0 {
+		return "", ErrInvalidRefreshToken
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	data, err := json.Marshal(RefreshToken{Zehut: zehut, Family: family, FamilyStartedAt: startedAt, IssuedAt: now})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal refresh token: %w", err)
+	}
+
+	pipe := redis.Client.TxPipeline()
+	pipe.Set(ctx, rs.tokenKey(hashRefreshToken(token)), data, ttl)
+	pipe.Set(ctx, rs.familyKey(family), zehut, ttl)
+	pipe.SAdd(ctx, rs.userKey(zehut), family)
+	pipe.Expire(ctx, rs.userKey(zehut), rs.ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (rs *RefreshTokenStore) lookup(ctx context.Context, token string) (*RefreshToken, error) {
+	data, err := redis.Client.Get(ctx, rs.tokenKey(hashRefreshToken(token))).Bytes()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	var record RefreshToken
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal refresh token: %w", err)
+	}
+
+	return &record, nil
+}
+
+// Rotate consumes a refresh token and issues its replacement. Presenting a
+// consumed token revokes the family and returns ErrRefreshTokenReused.
+func (rs *RefreshTokenStore) Rotate(ctx context.Context, token string) (*RefreshToken, string, error) {
+	record, err := rs.lookup(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	first, err := redis.Client.SetNX(ctx, rs.usedKey(hashRefreshToken(token)), 1, rs.ttl).Result()
+	if err != nil {
+		return nil, "", err
+	}
+	if !first {
+		if err := rs.RevokeFamily(ctx, record.Zehut, record.Family); err != nil {
+			return nil, "", err
+		}
+		return record, "", ErrRefreshTokenReused
+	}
+
+	active, err := rs.FamilyActive(ctx, record.Family)
+	if err != nil {
+		return nil, "", err
+	}
+	if !active {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	// Tokens issued before families recorded their start count from their own issue
+	startedAt := record.FamilyStartedAt
+	if startedAt.IsZero() {
+		startedAt = record.IssuedAt
+	}
+
+	next, err := rs.issue(ctx, record.Zehut, record.Family, startedAt)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		if err := rs.RevokeFamily(ctx, record.Zehut, record.Family); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return record, next, nil
+}
+
+// Revoke ends the family of a refresh token, e.g. on logout
+func (rs *RefreshTokenStore) Revoke(ctx context.Context, token string) error {
+	record, err := rs.lookup(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return rs.RevokeFamily(ctx, record.Zehut, record.Family)
+}
+
+// RevokeFamily invalidates every refresh and access token of a family
+func (rs *RefreshTokenStore) RevokeFamily(ctx context.Context, zehut, family string) error {
+	pipe := redis.Client.TxPipeline()
+	pipe.Del(ctx, rs.familyKey(family))
+	pipe.SRem(ctx, rs.userKey(zehut), family)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RevokeAllForUser invalidates every token family of a user
+func (rs *RefreshTokenStore) RevokeAllForUser(ctx context.Context, zehut string) error {
+	families, err := redis.Client.SMembers(ctx, rs.userKey(zehut)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(families)+1)
+	for _, family := range families {
+		keys = append(keys, rs.familyKey(family))
+	}
+	keys = append(keys, rs.userKey(zehut))
+
+	return redis.Client.Del(ctx, keys...).Err()
+}
+
+// FamilyActive reports whether a token family has not been revoked or expired
+func (rs *RefreshTokenStore) FamilyActive(ctx context.Context, family string) (bool, error) {
+	count, err := redis.Client.Exists(ctx, rs.familyKey(family)).Result()
+	return count > 0, err
+}
@@ -0,0 +1,207 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"tzlev/internal/redis"
+)
+
+// useRedis points the shared Redis client at an in-memory server
+func useRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	previous := redis.Client
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		redis.Client.Close()
+		redis.Client = previous
+	})
+	return mr
+}
+
+func testRefreshTokenStore() *RefreshTokenStore {
+	return &RefreshTokenStore{prefix: "test:refresh:", ttl: time.Hour, maxLifetime: 24 * time.Hour}
+}
+
+func TestRotate(t *testing.T) {
+	useRedis(t)
+	ctx := context.Background()
+	store := testRefreshTokenStore()
+
+	token, family, err := store.Issue(ctx, "111")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		record, next, err := store.Rotate(ctx, token)
+		if err != nil {
+			t.Fatalf("rotation %d: %v", i, err)
+		}
+		if record.Zehut != "111" || record.Family != family {
+			t.Fatalf("rotation %d: record %+v, want zehut 111 in family %s", i, record, family)
+		}
+		if next == "" || next == token {
+			t.Fatalf("rotation %d: replacement %q, want a new token", i, next)
+		}
+		token = next
+	}
+
+	if active, err := store.FamilyActive(ctx, family); err != nil || !active {
+		t.Errorf("FamilyActive() = %v, %v; want true", active, err)
+	}
+}
+
+func TestRotateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare returns the token to rotate and the family it should leave inactive
+		prepare func(t *testing.T, ctx context.Context, store *RefreshTokenStore) (string, string)
+		want    error
+	}{
+		{
+			name: "unknown token",
+			prepare: func(t *testing.T, ctx context.Context, store *RefreshTokenStore) (string, string) {
+				return "not-a-token", ""
+			},
+			want: ErrInvalidRefreshToken,
+		},
+		{
+			name: "reused token",
+			prepare: func(t *testing.T, ctx context.Context, store *RefreshTokenStore) (string, string) {
+				token, family, err := store.Issue(ctx, "111")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, _, err := store.Rotate(ctx, token); err != nil {
+					t.Fatal(err)
+				}
+				return token, family
+			},
+			want: ErrRefreshTokenReused,
+		},
+		{
+			name: "revoked family",
+			prepare: func(t *testing.T, ctx context.Context, store *RefreshTokenStore) (string, string) {
+				token, family, err := store.Issue(ctx, "111")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := store.Revoke(ctx, token); err != nil {
+					t.Fatal(err)
+				}
+				return token, family
+			},
+			want: ErrInvalidRefreshToken,
+		},
+		{
+			name: "all families of the user revoked",
+			prepare: func(t *testing.T, ctx context.Context, store *RefreshTokenStore) (string, string) {
+				token, family, err := store.Issue(ctx, "111")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := store.RevokeAllForUser(ctx, "111"); err != nil {
+					t.Fatal(err)
+				}
+				return token, family
+			},
+			want: ErrInvalidRefreshToken,
+		},
+		{
+			name: "family past its absolute lifetime",
+			prepare: func(t *testing.T, ctx context.Context, store *RefreshTokenStore) (string, string) {
+				token, err := store.issue(ctx, "111", "old-family", time.Now().Add(-store.maxLifetime+time.Minute))
+				if err != nil {
+					t.Fatal(err)
+				}
+				store.maxLifetime -= time.Minute
+				return token, "old-family"
+			},
+			want: ErrInvalidRefreshToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRedis(t)
+			ctx := context.Background()
+			store := testRefreshTokenStore()
+
+			token, family := tt.prepare(t, ctx, store)
+			if _, next, err := store.Rotate(ctx, token); !errors.Is(err, tt.want) || next != "" {
+				t.Fatalf("Rotate() = %q, %v; want %v", next, err, tt.want)
+			}
+
+			if family != "" {
+				if active, err := store.FamilyActive(ctx, family); err != nil || active {
+					t.Errorf("FamilyActive() = %v, %v; want false", active, err)
+				}
+			}
+		})
+	}
+}
+
+// Reusing a rotated token must also end the tokens issued after it
+func TestReuseRevokesReplacement(t *testing.T) {
+	useRedis(t)
+	ctx := context.Background()
+	store := testRefreshTokenStore()
+
+	stolen, _, err := store.Issue(ctx, "111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, next, err := store.Rotate(ctx, stolen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := store.Rotate(ctx, stolen); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reusing the first token: %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, err := store.Rotate(ctx, next); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("rotating the replacement: %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestIssueLifetime(t *testing.T) {
+	tests := []struct {
+		name      string
+		startedAt time.Duration
+		wantTTL   time.Duration
+		wantErr   error
+	}{
+		{"new family", 0, time.Hour, nil},
+		{"family near its lifetime", -23*time.Hour - 30*time.Minute, 30 * time.Minute, nil},
+		{"family past its lifetime", -25 * time.Hour, 0, ErrInvalidRefreshToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := useRedis(t)
+			ctx := context.Background()
+			store := testRefreshTokenStore()
+
+			_, err := store.issue(ctx, "111", "family", time.Now().Add(tt.startedAt))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("issue() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			ttl := mr.TTL(store.familyKey("family"))
+			if diff := tt.wantTTL - ttl; diff < 0 || diff > time.Second {
+				t.Errorf("family TTL = %s, want %s", ttl, tt.wantTTL)
+			}
+		})
+	}
+}
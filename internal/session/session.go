@@ -2,47 +2,162 @@ package session
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	goredis "github.com/redis/go-redis/v9"
+
 	"tzlev/internal/redis"
 )
 
 type Session struct {
-	UserID    uint      `json:"user_id"` // Legacy field, not used
-	Zehut     string    `json:"zehut"`   // Israeli ID - primary key in users table
-	Email     string    `json:"email"`
+	ID         string    `json:"-"`       // Set when sessions are listed; never stored or exposed
+	UserID     uint      `json:"user_id"` // Legacy field, not used
+	Zehut      string    `json:"zehut"`   // Israeli ID - primary key in users table
+	Email      string    `json:"email"`
+	Name       string    `json:"name"`
+	Method     string    `json:"method,omitempty"` // How the user authenticated, e.g. password or google
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+
+	// Limits fixed when the session is created
+	IdleTimeout time.Duration `json:"idle_timeout"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+
+	// Set while the user must still enroll in two-factor authentication
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
+
+	// Set on sessions where support staff act as Zehut
+	Impersonator *Impersonator `json:"impersonator,omitempty"`
+}
+
+// Impersonator is the user behind an impersonation session. Their own login is
+// restored from it when the impersonation ends.
+type Impersonator struct {
+	Zehut     string    `json:"zehut"`
 	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	Method    string    `json:"method"`     // How the impersonator logged in
+	ExpiresAt time.Time `json:"expires_at"` // When the impersonator's own session expires
+	StartedAt time.Time `json:"started_at"`
+}
+
+// ErrExpired is returned by Get for sessions past their absolute lifetime
+var ErrExpired = errors.New("session expired")
+
+// Limits bounds the lifetime of a session. Idle is extended on every request
+// by Refresh; Absolute is counted from login and never extended.
+type Limits struct {
+	Idle     time.Duration
+	Absolute time.Duration
+}
+
+// ttl returns how long the session may live in Redis from now
+func (s *Session) ttl(now time.Time) time.Duration {
+	ttl := s.IdleTimeout
+	if remaining := s.ExpiresAt.Sub(now); remaining < ttl {
+		ttl = remaining
+	}
+	return ttl
+}
+
+// PublicID derives a stable handle for a session that can be shown to users.
+// The session ID itself is the cookie value and must never leave the server.
+func PublicID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
 }
 
 type SessionManager struct {
-	prefix string
-	ttl    time.Duration
+	prefix      string
+	indexPrefix string
+	limits      Limits
+	adminLimits Limits
 }
 
 func NewSessionManager() *SessionManager {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	limits := Limits{
+		Idle:     cfg.MustGet(ctx, "redis.sessionTTL", "24h").Duration(),
+		Absolute: cfg.MustGet(ctx, "session.absoluteLifetime", "168h").Duration(),
+	}
+
+	// Admins default to the regular limits unless stricter ones are configured
+	adminLimits := Limits{
+		Idle:     cfg.MustGet(ctx, "session.admin.idleTimeout", limits.Idle).Duration(),
+		Absolute: cfg.MustGet(ctx, "session.admin.absoluteLifetime", limits.Absolute).Duration(),
+	}
+
 	return &SessionManager{
-		prefix: "tzlev:session:",
-		ttl:    24 * time.Hour,
+		prefix:      cfg.MustGet(ctx, "redis.sessionPrefix", "tzlev:session:").String(),
+		indexPrefix: cfg.MustGet(ctx, "redis.sessionIndexPrefix", "tzlev:user_sessions:").String(),
+		limits:      limits,
+		adminLimits: adminLimits,
 	}
 }
 
+// LimitsFor returns the session limits that apply to a regular user or an admin
+func (sm *SessionManager) LimitsFor(isAdmin bool) Limits {
+	if isAdmin {
+		return sm.adminLimits
+	}
+	return sm.limits
+}
+
+// indexTTL keeps a user's session index at least as long as their longest session
+func (sm *SessionManager) indexTTL() time.Duration {
+	if sm.adminLimits.Absolute > sm.limits.Absolute {
+		return sm.adminLimits.Absolute
+	}
+	return sm.limits.Absolute
+}
+
 func (sm *SessionManager) key(sessionID string) string {
 	return fmt.Sprintf("%s%s", sm.prefix, sessionID)
 }
 
+// indexKey is the set of session IDs belonging to a user
+func (sm *SessionManager) indexKey(zehut string) string {
+	return fmt.Sprintf("%s%s", sm.indexPrefix, zehut)
+}
+
+// Create stores a new session. Limits left unset on the session default to
+// the regular user limits.
 func (sm *SessionManager) Create(ctx context.Context, sessionID string, session *Session) error {
-	session.CreatedAt = time.Now()
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	if session.IdleTimeout == 0 {
+		session.IdleTimeout = sm.limits.Idle
+	}
+	if session.ExpiresAt.IsZero() {
+		session.ExpiresAt = now.Add(sm.limits.Absolute)
+	}
 
 	data, err := json.Marshal(session)
 	if err != nil {
 		return fmt.Errorf("failed to marshal session: %w", err)
 	}
 
-	key := sm.key(sessionID)
-	return redis.Client.Set(ctx, key, data, sm.ttl).Err()
+	pipe := redis.Client.TxPipeline()
+	pipe.Set(ctx, sm.key(sessionID), data, session.ttl(now))
+	if session.Zehut != "" {
+		pipe.SAdd(ctx, sm.indexKey(session.Zehut), sessionID)
+		pipe.Expire(ctx, sm.indexKey(session.Zehut), sm.indexTTL())
+	}
+
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
 func (sm *SessionManager) Get(ctx context.Context, sessionID string) (*Session, error) {
@@ -58,15 +173,126 @@ func (sm *SessionManager) Get(ctx context.Context, sessionID string) (*Session,
 		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
 	}
 
+	// Sessions stored before limits were recorded get the regular user limits
+	if session.IdleTimeout == 0 {
+		session.IdleTimeout = sm.limits.Idle
+	}
+	if session.ExpiresAt.IsZero() {
+		session.ExpiresAt = session.CreatedAt.Add(sm.limits.Absolute)
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		if err := sm.Delete(ctx, sessionID); err != nil {
+			return nil, err
+		}
+		return nil, ErrExpired
+	}
+
 	return &session, nil
 }
 
+// Touch stores the updated last-seen details of a session without changing its TTL
+func (sm *SessionManager) Touch(ctx context.Context, sessionID string, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	return redis.Client.SetArgs(ctx, sm.key(sessionID), data, goredis.SetArgs{KeepTTL: true, Mode: "XX"}).Err()
+}
+
+// ListForUser returns the live sessions of a user, most recently seen first.
+// Index entries whose session has expired are pruned.
+func (sm *SessionManager) ListForUser(ctx context.Context, zehut string) ([]*Session, error) {
+	indexKey := sm.indexKey(zehut)
+
+	sessionIDs, err := redis.Client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(sessionIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		keys[i] = sm.key(sessionID)
+	}
+
+	values, err := redis.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(values))
+	var stale []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			stale = append(stale, sessionIDs[i])
+			continue
+		}
+
+		var session Session
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+		}
+		session.ID = sessionIDs[i]
+		sessions = append(sessions, &session)
+	}
+
+	if len(stale) > 0 {
+		if err := redis.Client.SRem(ctx, indexKey, stale...).Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
 func (sm *SessionManager) Delete(ctx context.Context, sessionID string) error {
+	// Look the session up first so it can be removed from its user's index
+	if session, err := sm.Get(ctx, sessionID); err == nil && session.Zehut != "" {
+		if err := redis.Client.SRem(ctx, sm.indexKey(session.Zehut), sessionID).Err(); err != nil {
+			return err
+		}
+	}
+
 	key := sm.key(sessionID)
 	return redis.Client.Del(ctx, key).Err()
 }
 
-func (sm *SessionManager) Refresh(ctx context.Context, sessionID string) error {
-	key := sm.key(sessionID)
-	return redis.Client.Expire(ctx, key, sm.ttl).Err()
+// DeleteAllForUser revokes every session of a user, e.g. after a password change
+func (sm *SessionManager) DeleteAllForUser(ctx context.Context, zehut string) error {
+	indexKey := sm.indexKey(zehut)
+
+	sessionIDs, err := redis.Client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(sessionIDs)+1)
+	for _, sessionID := range sessionIDs {
+		keys = append(keys, sm.key(sessionID))
+	}
+	keys = append(keys, indexKey)
+
+	return redis.Client.Del(ctx, keys...).Err()
+}
+
+// Refresh extends the idle timeout of a session, capped at its absolute lifetime,
+// and keeps its user's session index alive
+func (sm *SessionManager) Refresh(ctx context.Context, sessionID string, session *Session) error {
+	pipe := redis.Client.TxPipeline()
+	pipe.Expire(ctx, sm.key(sessionID), session.ttl(time.Now()))
+	if session.Zehut != "" {
+		pipe.Expire(ctx, sm.indexKey(session.Zehut), sm.indexTTL())
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
 }
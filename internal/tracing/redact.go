@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// GoFrame records every request and response header, and SQL with its values
+const (
+	requestHeadersKey  attribute.Key = "http.request.headers"
+	responseHeadersKey attribute.Key = "http.response.headers"
+	dbStatementKey     attribute.Key = "db.statement"
+)
+
+// contextSpanName is the span gctx.New starts to give a new context a trace
+// ID. It ends right away and records nothing.
+const contextSpanName = "gctx.New"
+
+// sensitiveHeaders carry credentials and never leave the process
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-API-Key"}
+
+// redactingExporter strips credentials, and SQL unless dbStatements is on,
+// from spans before they are exported. The empty spans of gctx.New, dozens of
+// them at startup, are dropped.
+type redactingExporter struct {
+	sdktrace.SpanExporter
+}
+
+func (e redactingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	redacted := make([]sdktrace.ReadOnlySpan, 0, len(spans))
+	for _, span := range spans {
+		if span.Name() == contextSpanName && !span.Parent().IsValid() {
+			continue
+		}
+		redacted = append(redacted, redactSpan(span))
+	}
+	return e.SpanExporter.ExportSpans(ctx, redacted)
+}
+
+// redactedSpan is a finished span with replaced attributes and events
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attributes []attribute.KeyValue
+	events     []sdktrace.Event
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attributes
+}
+
+func (s redactedSpan) Events() []sdktrace.Event {
+	return s.events
+}
+
+func redactSpan(span sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	events := make([]sdktrace.Event, len(span.Events()))
+	for i, event := range span.Events() {
+		event.Attributes = redactAttributes(event.Attributes)
+		events[i] = event
+	}
+
+	return redactedSpan{
+		ReadOnlySpan: span,
+		attributes:   redactAttributes(span.Attributes()),
+		events:       events,
+	}
+}
+
+func redactAttributes(attributes []attribute.KeyValue) []attribute.KeyValue {
+	redacted := make([]attribute.KeyValue, 0, len(attributes))
+	for _, attr := range attributes {
+		switch attr.Key {
+		case dbStatementKey:
+			if !dbStatements {
+				continue
+			}
+		case requestHeadersKey, responseHeadersKey:
+			attr = attr.Key.String(redactHeaders(attr.Value.AsString()))
+		}
+		redacted = append(redacted, attr)
+	}
+	return redacted
+}
+
+// redactHeaders removes sensitiveHeaders from a JSON object of headers
+func redactHeaders(encoded string) string {
+	var headers map[string]interface{}
+	if err := json.Unmarshal([]byte(encoded), &headers); err != nil {
+		return "[redacted]"
+	}
+
+	for name := range headers {
+		for _, sensitive := range sensitiveHeaders {
+			if strings.EqualFold(name, sensitive) {
+				headers[name] = "[redacted]"
+			}
+		}
+	}
+
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return "[redacted]"
+	}
+	return string(data)
+}
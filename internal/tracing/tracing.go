@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+var (
+	provider *sdktrace.TracerProvider
+
+	// dbStatements includes SQL with its values and Redis commands in spans
+	dbStatements bool
+)
+
+// Init installs the tracer provider configured in the tracing block of the
+// config. GoFrame then records a span for each incoming request and SQL query;
+// InstrumentRedis adds Redis commands. It must run before the database and
+// Redis are initialized.
+func Init() error {
+	ctx := gctx.New()
+	cfg := g.Cfg()
+
+	exporterName := cfg.MustGet(ctx, "tracing.exporter", "none").String()
+	endpoint := cfg.MustGet(ctx, "tracing.endpoint", "http://localhost:4318").String()
+	serviceName := cfg.MustGet(ctx, "tracing.serviceName", "tzlev").String()
+	sampleRatio := cfg.MustGet(ctx, "tracing.sampleRatio", 1.0).Float64()
+	dbStatements = cfg.MustGet(ctx, "tracing.dbStatements", false).Bool()
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch exporterName {
+	case "none":
+		return nil
+	case "otlp":
+		// OTEL_EXPORTER_OTLP_HEADERS and the other standard variables also apply
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return fmt.Errorf("unknown tracing exporter %q, use otlp, stdout or none", exporterName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s trace exporter: %w", exporterName, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the config
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to describe tracing resource: %w", err)
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(redactingExporter{exporter}),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	g.Log().Infof(ctx, "Tracing enabled, exporting spans to %s", exporterName)
+
+	return nil
+}
+
+// Shutdown exports the spans that are still buffered
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// InstrumentRedis records a span for each command sent by client. Commands
+// carry session data, so they are left out unless dbStatements is on.
+func InstrumentRedis(client *redis.Client) error {
+	if provider == nil {
+		return nil
+	}
+	return redisotel.InstrumentTracing(client, redisotel.WithDBStatement(dbStatements))
+}
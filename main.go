@@ -3,25 +3,48 @@ package main
 import (
 	"context"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/net/goai"
 	"github.com/gogf/gf/v2/os/gcmd"
 	"github.com/gogf/gf/v2/os/gctx"
 
 	_ "github.com/gogf/gf/contrib/drivers/pgsql/v2" // PostgreSQL driver
 
+	"tzlev/internal/apperror"
 	"tzlev/internal/cli"
 	"tzlev/internal/controller"
 	"tzlev/internal/database"
+	"tzlev/internal/jwt"
+	"tzlev/internal/logging"
+	"tzlev/internal/metrics"
 	"tzlev/internal/middleware"
+	"tzlev/internal/model"
 	"tzlev/internal/oauth"
 	"tzlev/internal/redis"
+	"tzlev/internal/tracing"
 )
 
 func main() {
 	ctx := gctx.New()
 
+	if err := logging.Init(); err != nil {
+		g.Log().Fatal(ctx, "Failed to configure logging:", err)
+	}
+
+	// Tracing comes before the services so that Redis commands are traced
+	if err := tracing.Init(); err != nil {
+		g.Log().Fatal(ctx, "Failed to configure tracing:", err)
+	}
+	defer func() {
+		if err := tracing.Shutdown(ctx); err != nil {
+			g.Log().Warning(ctx, "Failed to export remaining spans:", err)
+		}
+	}()
+
 	// Initialize external services
 	if err := initServices(ctx); err != nil {
 		g.Log().Fatal(ctx, "Failed to initialize services:", err)
@@ -48,8 +71,8 @@ func initServices(ctx context.Context) error {
 		return err
 	}
 
-	// Initialize OAuth
-	if err := oauth.InitGoogleOAuth(); err != nil {
+	// Initialize OAuth login providers
+	if err := oauth.Init(); err != nil {
 		return err
 	}
 
@@ -69,6 +92,8 @@ func runCLI(ctx context.Context) {
 		cli.RunMigrate(ctx, parser)
 	case "seed":
 		cli.RunSeed(ctx, parser)
+	case "jwtkey":
+		cli.RunJWTKey(ctx, parser)
 	case "version":
 		cli.ShowVersion(ctx)
 	case "help":
@@ -84,6 +109,20 @@ func runWebServer(ctx context.Context) {
 	s := g.Server()
 	cfg := g.Cfg()
 
+	// Token signing keys are only needed by the server, so CLI commands such as
+	// jwtkey can run before any key exists
+	if err := jwt.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize JWT keys:", err)
+	}
+
+	// Prometheus metrics are served on their own port
+	if err := metrics.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize metrics:", err)
+	}
+
+	// Request IDs, access logs and metrics for every route
+	s.Use(middleware.RequestID(), middleware.AccessLog(), middleware.Metrics())
+
 	// CORS Middleware
 	s.Use(func(r *ghttp.Request) {
 		r.Response.CORSDefault()
@@ -92,6 +131,7 @@ func runWebServer(ctx context.Context) {
 
 	// Setup routes (must be before static file serving)
 	setupRoutes(s)
+	setupOpenAPI(s)
 
 	// Serve static files
 	s.SetServerRoot("public")
@@ -102,11 +142,11 @@ func runWebServer(ctx context.Context) {
 		path := r.URL.Path
 		// Don't intercept API or auth routes
 		if len(path) >= 4 && path[:4] == "/api" {
-			r.Response.WriteStatus(404)
+			middleware.WriteError(r, apperror.NotFound("Not found"))
 			return
 		}
 		if len(path) >= 5 && path[:5] == "/auth" {
-			r.Response.WriteStatus(404)
+			middleware.WriteError(r, apperror.NotFound("Not found"))
 			return
 		}
 		// Serve index.html for all other routes
@@ -123,34 +163,309 @@ func runWebServer(ctx context.Context) {
 func setupRoutes(s *ghttp.Server) {
 	healthCtrl := controller.NewHealthController()
 	authCtrl := controller.NewAuthController()
+	passwordCtrl := controller.NewPasswordController()
 	academicYearCtrl := controller.NewAcademicYearController()
 	appResourceCtrl := controller.NewAppResourceController()
+	classroomCtrl := controller.NewClassroomController()
+	userCtrl := controller.NewUserController()
+	groupCtrl := controller.NewGroupController()
+	permissionCtrl := controller.NewPermissionController()
+	sessionCtrl := controller.NewSessionController()
+	apiKeyCtrl := controller.NewAPIKeyController()
+	twoFactorCtrl := controller.NewTwoFactorController()
+	identityCtrl := controller.NewUserIdentityController()
+	auditCtrl := controller.NewAuditController()
 
 	// Public routes
 	s.Group("/auth", func(group *ghttp.RouterGroup) {
-		group.POST("/login", authCtrl.Login)
-		group.GET("/google/login", authCtrl.GoogleLogin)
-		group.GET("/google/callback", authCtrl.GoogleCallback)
-		group.POST("/logout", authCtrl.Logout)
+		group.Middleware(middleware.HandlerResponse())
+		group.Bind(
+			authCtrl.Login,
+			authCtrl.VerifyTwoFactor,
+			authCtrl.GetProviders,
+			authCtrl.Logout,
+			passwordCtrl.ForgotPassword,
+			passwordCtrl.ResetPassword,
+		)
+
+		// Token endpoints for API clients, whose OAuth libraries read RFC 6749 error codes
+		group.Group("/", func(group *ghttp.RouterGroup) {
+			group.Middleware(middleware.TokenErrors())
+			group.Bind(
+				authCtrl.IssueToken,
+				authCtrl.RevokeToken,
+			)
+		})
+
+		// Provider logins redirect rather than answer in JSON
+		group.GET("/{provider}/login", authCtrl.ProviderLogin)
+		group.GET("/{provider}/callback", authCtrl.ProviderCallback)
 	})
 
+	// Public keys for verifying our access tokens
+	s.BindHandler("GET:/.well-known/jwks.json", authCtrl.JWKS)
+
 	// API routes
 	s.Group("/api", func(group *ghttp.RouterGroup) {
+		group.Middleware(middleware.HandlerResponse())
+
 		// Public API
-		group.GET("/health", healthCtrl.Check)
+		group.Bind(healthCtrl.Check)
 
 		// Protected API
 		group.Group("/", func(protectedGroup *ghttp.RouterGroup) {
 			protectedGroup.Middleware(middleware.Auth())
-			protectedGroup.GET("/me", authCtrl.GetCurrentUser)
-			protectedGroup.GET("/academic-year", academicYearCtrl.GetAcademicYear)
-			protectedGroup.POST("/academic-year", academicYearCtrl.SetAcademicYear)
-			protectedGroup.GET("/academic-years", academicYearCtrl.GetAcademicYearsList)
-			protectedGroup.GET("/app-resources", appResourceCtrl.GetAppResources)
-			protectedGroup.GET("/app-resources/{id}", appResourceCtrl.GetAppResource)
-			protectedGroup.POST("/app-resources", appResourceCtrl.CreateAppResource)
-			protectedGroup.PUT("/app-resources/{id}", appResourceCtrl.UpdateAppResource)
-			protectedGroup.DELETE("/app-resources/{id}", appResourceCtrl.DeleteAppResource)
+			protectedGroup.Bind(
+				authCtrl.GetCurrentUser,
+				academicYearCtrl.GetAcademicYear,
+				academicYearCtrl.SetAcademicYear,
+				academicYearCtrl.GetAcademicYearsList,
+				permissionCtrl.GetMyPermissions,
+				sessionCtrl.GetMySessions,
+				apiKeyCtrl.GetMyAPIKeys,
+				twoFactorCtrl.GetMyTwoFactor,
+				identityCtrl.GetMyIdentities,
+			)
+
+			// Account security - never changed on a user's behalf by impersonating staff
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation())
+				group.Bind(
+					sessionCtrl.RevokeMyOtherSessions,
+					sessionCtrl.RevokeMySession,
+					apiKeyCtrl.CreateMyAPIKey,
+					apiKeyCtrl.RevokeMyAPIKey,
+					twoFactorCtrl.DisableMyTwoFactor,
+					twoFactorCtrl.EnrollMyTwoFactor,
+					twoFactorCtrl.ConfirmMyTwoFactor,
+					twoFactorCtrl.RegenerateMyRecoveryCodes,
+					identityCtrl.UnlinkMyIdentity,
+				)
+				group.GET("/me/identities/{provider}/link", authCtrl.LinkProvider)
+			})
+
+			// Impersonation ("login as") for admins and technical staff
+			protectedGroup.Bind(
+				sessionCtrl.StartImpersonation,
+				sessionCtrl.StopImpersonation,
+			)
+
+			// Classrooms - writes are limited to admins and the owning teacher; API keys
+			// also need the classrooms scope
+			protectedGroup.Bind(
+				classroomCtrl.GetClassrooms,
+				classroomCtrl.GetClassroom,
+				classroomCtrl.CreateClassroom,
+				classroomCtrl.UpdateClassroom,
+				classroomCtrl.DeleteClassroom,
+			)
+
+			// App resources (permission modules)
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.RequirePermission("app_resources", model.ActionView))
+				group.Bind(
+					appResourceCtrl.GetAppResources,
+					appResourceCtrl.GetAppResource,
+				)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("app_resources", model.ActionCreate))
+				group.Bind(appResourceCtrl.CreateAppResource)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("app_resources", model.ActionEdit))
+				group.Bind(appResourceCtrl.UpdateAppResource)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("app_resources", model.ActionDelete))
+				group.Bind(appResourceCtrl.DeleteAppResource)
+			})
+
+			// User administration
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.RequirePermission("users", model.ActionView))
+				group.Bind(
+					userCtrl.GetUsers,
+					userCtrl.GetUser,
+					userCtrl.GetLoginLockout,
+					sessionCtrl.GetUserSessions,
+					identityCtrl.GetUserIdentities,
+				)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("users", model.ActionCreate))
+				group.Bind(userCtrl.CreateUser)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("users", model.ActionEdit))
+				group.Bind(
+					userCtrl.UpdateUser,
+					userCtrl.SetFreezed,
+					userCtrl.SetAdmin,
+					userCtrl.ClearLoginLockout,
+					sessionCtrl.ForceLogout,
+					twoFactorCtrl.ResetUserTwoFactor,
+					identityCtrl.UnlinkUserIdentity,
+					userCtrl.ClearIPLockout,
+				)
+			})
+
+			// API keys of all users and service accounts
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.RequirePermission("api_keys", model.ActionView))
+				group.Bind(apiKeyCtrl.GetAPIKeys)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("api_keys", model.ActionCreate))
+				group.Bind(apiKeyCtrl.CreateAPIKey)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("api_keys", model.ActionDelete))
+				group.Bind(apiKeyCtrl.RevokeAPIKey)
+			})
+
+			// Groups, memberships and permission grants
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.RequirePermission("permissions", model.ActionView))
+				group.Bind(
+					groupCtrl.GetGroups,
+					groupCtrl.GetGroup,
+					groupCtrl.GetGroupMembers,
+					groupCtrl.GetGroupPermissions,
+					permissionCtrl.GetUserPermissions,
+				)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("permissions", model.ActionCreate))
+				group.Bind(
+					groupCtrl.CreateGroup,
+					groupCtrl.AddGroupMember,
+				)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("permissions", model.ActionEdit))
+				group.Bind(
+					groupCtrl.UpdateGroup,
+					groupCtrl.SetGroupPermissions,
+					permissionCtrl.SetUserPermissions,
+				)
+			})
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("permissions", model.ActionDelete))
+				group.Bind(
+					groupCtrl.DeleteGroup,
+					groupCtrl.RemoveGroupMember,
+				)
+			})
+
+			// Audit log for compliance reviews
+			protectedGroup.Group("/", func(group *ghttp.RouterGroup) {
+				group.Middleware(middleware.DenyImpersonation(), middleware.RequirePermission("audit", model.ActionView))
+				group.Bind(
+					auditCtrl.GetAuditLog,
+					auditCtrl.ExportAuditLog,
+				)
+			})
+		})
+	})
+}
+
+const (
+	openAPIPath = "/api/openapi.json"
+	swaggerPath = "/api/docs"
+)
+
+// publicPathPrefixes are the API paths that can be called without logging in
+var publicPathPrefixes = []string{"/auth/", "/api/health"}
+
+// swaggerUITemplate replaces the default Redoc page with Swagger UI, so the
+// endpoints can be tried out from the browser with the current session
+const swaggerUITemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8"/>
+	<meta name="viewport" content="width=device-width, initial-scale=1"/>
+	<title>Tzlev API</title>
+	<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css"/>
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+	<script>
+		window.onload = () => {
+			window.ui = SwaggerUIBundle({
+				url: '{SwaggerUIDocUrl}',
+				dom_id: '#swagger-ui',
+				withCredentials: true,
+			});
+		};
+	</script>
+</body>
+</html>
+`
+
+// setupOpenAPI serves the OpenAPI document generated from the typed routes in
+// api/v1 and a Swagger UI for it at /api/docs
+func setupOpenAPI(s *ghttp.Server) {
+	oai := s.GetOpenApi()
+	oai.Info.Title = "Tzlev API"
+	oai.Info.Version = cli.Version
+	oai.Config.CommonResponse = middleware.Response{}
+
+	oai.Components.SecuritySchemes = goai.SecuritySchemes{
+		"session": goai.SecuritySchemeRef{Value: &goai.SecurityScheme{
+			Type:        "apiKey",
+			In:          "cookie",
+			Name:        s.GetSessionIdName(),
+			Description: "Browser session from /auth/login",
+		}},
+		"bearer": goai.SecuritySchemeRef{Value: &goai.SecurityScheme{
+			Type:         "http",
+			Scheme:       "bearer",
+			BearerFormat: "JWT",
+			Description:  "Access token from /auth/token",
+		}},
+		"apiKey": goai.SecuritySchemeRef{Value: &goai.SecurityScheme{
+			Type: "apiKey",
+			In:   "header",
+			Name: "X-API-Key",
+		}},
+	}
+	// Any one of the schemes is enough
+	oai.Security = &goai.SecurityRequirements{
+		{"session": {}},
+		{"bearer": {}},
+		{"apiKey": {}},
+	}
+
+	s.SetOpenApiPath(openAPIPath)
+	s.SetSwaggerPath(swaggerPath)
+	s.SetSwaggerUITemplate(swaggerUITemplate)
+
+	// Operations are only added to the document when the server starts, so
+	// the public ones are marked on the first request for it
+	var markPublic sync.Once
+	s.BindHookHandler(openAPIPath, ghttp.HookBeforeServe, func(r *ghttp.Request) {
+		markPublic.Do(func() {
+			for path, item := range oai.Paths {
+				if !isPublicPath(path) {
+					continue
+				}
+				for _, operation := range []*goai.Operation{item.Get, item.Post, item.Put, item.Delete, item.Patch} {
+					if operation != nil {
+						operation.Security = &goai.SecurityRequirements{}
+					}
+				}
+			}
 		})
 	})
 }
+
+func isPublicPath(path string) bool {
+	for _, prefix := range publicPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
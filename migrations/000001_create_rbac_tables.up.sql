@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS groups (
+    id          BIGSERIAL PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    inserted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id    BIGINT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    zehut       VARCHAR(255) NOT NULL REFERENCES users(zehut) ON DELETE CASCADE,
+    inserted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, zehut)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_zehut ON group_members(zehut);
+
+CREATE TABLE IF NOT EXISTS group_permissions (
+    group_id    BIGINT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    resource_id VARCHAR(255) NOT NULL REFERENCES app_resources(id) ON DELETE CASCADE,
+    can_view    BOOLEAN NOT NULL DEFAULT FALSE,
+    can_create  BOOLEAN NOT NULL DEFAULT FALSE,
+    can_edit    BOOLEAN NOT NULL DEFAULT FALSE,
+    can_delete  BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (group_id, resource_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_permissions (
+    zehut       VARCHAR(255) NOT NULL REFERENCES users(zehut) ON DELETE CASCADE,
+    resource_id VARCHAR(255) NOT NULL REFERENCES app_resources(id) ON DELETE CASCADE,
+    can_view    BOOLEAN NOT NULL DEFAULT FALSE,
+    can_create  BOOLEAN NOT NULL DEFAULT FALSE,
+    can_edit    BOOLEAN NOT NULL DEFAULT FALSE,
+    can_delete  BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (zehut, resource_id)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_email_token;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_token_sent_at;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_token_sent_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_email_token ON users(email_token) WHERE email_token IS NOT NULL;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id              BIGSERIAL PRIMARY KEY,
+    name            VARCHAR(255) NOT NULL,
+    prefix          VARCHAR(32) NOT NULL,
+    key_hash        CHAR(64) NOT NULL UNIQUE,
+    zehut           VARCHAR(255) REFERENCES users(zehut) ON DELETE CASCADE,
+    service_account VARCHAR(255),
+    created_by      VARCHAR(255) NOT NULL,
+    expires_at      TIMESTAMP NOT NULL,
+    last_used_at    TIMESTAMP,
+    revoked_at      TIMESTAMP,
+    inserted_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- A key belongs either to a user or to a named service account
+    CHECK ((zehut IS NULL) <> (service_account IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_zehut ON api_keys(zehut);
+
+CREATE TABLE IF NOT EXISTS api_key_scopes (
+    api_key_id  BIGINT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    resource_id VARCHAR(255) NOT NULL REFERENCES app_resources(id) ON DELETE CASCADE,
+    can_view    BOOLEAN NOT NULL DEFAULT FALSE,
+    can_create  BOOLEAN NOT NULL DEFAULT FALSE,
+    can_edit    BOOLEAN NOT NULL DEFAULT FALSE,
+    can_delete  BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (api_key_id, resource_id)
+);
@@ -0,0 +1,19 @@
+-- TOTP secrets. confirmed_at is NULL while enrollment is pending.
+CREATE TABLE IF NOT EXISTS user_totp (
+    zehut          VARCHAR(255) PRIMARY KEY REFERENCES users(zehut) ON DELETE CASCADE,
+    secret         VARCHAR(64) NOT NULL,
+    confirmed_at   TIMESTAMP,
+    -- Last accepted time step, so a code cannot be replayed
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    inserted_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    zehut       VARCHAR(255) NOT NULL REFERENCES users(zehut) ON DELETE CASCADE,
+    code_hash   CHAR(64) NOT NULL,
+    used_at     TIMESTAMP,
+    inserted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_zehut ON user_recovery_codes(zehut);
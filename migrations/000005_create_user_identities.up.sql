@@ -0,0 +1,14 @@
+-- External identities (OpenID Connect subjects) linked to local users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id            BIGSERIAL PRIMARY KEY,
+    zehut         VARCHAR(255) NOT NULL REFERENCES users(zehut) ON DELETE CASCADE,
+    provider      VARCHAR(64) NOT NULL,
+    subject       VARCHAR(255) NOT NULL,
+    -- Address reported by the provider when the identity was linked, for display only
+    email         VARCHAR(255),
+    last_login_at TIMESTAMP,
+    inserted_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_zehut ON user_identities(zehut);
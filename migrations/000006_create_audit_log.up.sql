@@ -0,0 +1,20 @@
+-- Who did what to which record, from where. Rows are only ever inserted.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          BIGSERIAL PRIMARY KEY,
+    -- Zehut of the user, "api_key:<id>" for service accounts, "anonymous" or "system"
+    actor       VARCHAR(255) NOT NULL,
+    action      VARCHAR(100) NOT NULL,
+    entity      VARCHAR(100),
+    entity_id   VARCHAR(255),
+    -- Changed fields as {"field": {"before": ..., "after": ...}}
+    changes     JSONB,
+    details     JSONB,
+    ip          VARCHAR(64),
+    request_id  VARCHAR(64),
+    inserted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_inserted_at ON audit_log(inserted_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, inserted_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_log_impersonator;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS impersonator;
@@ -0,0 +1,4 @@
+-- Zehut of the staff member impersonating the actor, if any
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS impersonator VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_impersonator ON audit_log(impersonator, inserted_at) WHERE impersonator IS NOT NULL;